@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jasonthorsness/unlurker/hn"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// lastGoodEntries bounds the parameter sets whose last good snapshot is kept.
+	lastGoodEntries = 100
+	// lastGoodTTL is how old a snapshot may be and still be served when the HN API fails.
+	lastGoodTTL = 24 * time.Hour
+	// precomputedIntervals is how many refresh intervals old the precomputed snapshot may be and
+	// still be served; after that refreshes have stopped or keep failing, and requests fall back to
+	// computing their own or to the last good snapshot.
+	precomputedIntervals = 2
+	// recordQueueSize bounds the computed snapshots waiting to be recorded in the store.
+	recordQueueSize = 4
+)
+
+type activeParams struct {
+	Window time.Duration
+	MaxAge time.Duration
+	MinBy  int
+}
+
+func (p activeParams) key() string {
+	return fmt.Sprintf("%v|%v|%d", p.Window, p.MaxAge, p.MinBy)
+}
+
+// activeBounds are the ranges requested activeParams must be in, so that a single request cannot
+// make a snapshot crawl an unbounded part of HN.
+type activeBounds struct {
+	MinWindow time.Duration
+	MaxWindow time.Duration
+	MaxMaxAge time.Duration
+	MinMinBy  int
+	MaxMinBy  int
+}
+
+// paramRangeError is a parameter outside the range its bounds allow.
+type paramRangeError struct {
+	Param string
+	Min   string
+	Max   string
+	Code  errorCode
+}
+
+func (e *paramRangeError) Error() string {
+	return fmt.Sprintf("%s must be between %s and %s", e.Param, e.Min, e.Max)
+}
+
+// check returns the *paramRangeError of each of params outside the bounds joined, or nil.
+func (b activeBounds) check(params activeParams) error {
+	return errors.Join(b.checkWindow(params.Window), b.checkMaxAge(params.MaxAge), b.checkMinBy(params.MinBy))
+}
+
+func (b activeBounds) checkWindow(window time.Duration) error {
+	if window >= b.MinWindow && window <= b.MaxWindow {
+		return nil
+	}
+
+	return &paramRangeError{
+		Param: "window",
+		Min:   b.MinWindow.String(),
+		Max:   b.MaxWindow.String(),
+		Code:  codeWindowOutOfRange,
+	}
+}
+
+func (b activeBounds) checkMaxAge(maxAge time.Duration) error {
+	if maxAge >= time.Second && maxAge <= b.MaxMaxAge {
+		return nil
+	}
+
+	return &paramRangeError{
+		Param: "max-age",
+		Min:   time.Second.String(),
+		Max:   b.MaxMaxAge.String(),
+		Code:  codeMaxAgeOutOfRange,
+	}
+}
+
+func (b activeBounds) checkMinBy(minBy int) error {
+	if minBy >= b.MinMinBy && minBy <= b.MaxMinBy {
+		return nil
+	}
+
+	return &paramRangeError{
+		Param: "min-by",
+		Min:   strconv.Itoa(b.MinMinBy),
+		Max:   strconv.Itoa(b.MaxMinBy),
+		Code:  codeMinByOutOfRange,
+	}
+}
+
+type activeSnapshot struct {
+	Time               time.Time
+	Tree               map[int]hn.ItemSet
+	Roots              []handleActiveRoot
+	Params             activeParams
+	SecondChanceFailed bool
+	Historical         bool
+	Stale              bool
+}
+
+// stale returns a copy of the snapshot marked as served in place of one that failed to compute.
+func (s *activeSnapshot) stale() *activeSnapshot {
+	stale := *s
+	stale.Stale = true
+
+	return &stale
+}
+
+// now is the time the snapshot describes: the current time for live snapshots, so ages stay
+// accurate while a precomputed snapshot is reused, or the requested time for historical ones.
+func (s *activeSnapshot) now() time.Time {
+	if s.Historical {
+		return s.Time
+	}
+
+	return time.Now()
+}
+
+// activeSource provides active roots to handlers. Concurrent requests for the same parameters
+// share a single computation, and the roots for the default parameters can be precomputed on an
+// interval so the most common /active request is served without walking the HN tree. Snapshots for
+// other parameters are reused while fresh, and for a while after that are served marked as stale
+// while a new one is computed in the background. When the HN API fails, the last good snapshot for
+// the same parameters is served marked as stale instead.
+type activeSource struct {
+	client    *itemClient
+	store     *store
+	breaker   *circuitBreaker
+	lastGood  *lruCache[string, *activeSnapshot]
+	snapshot  *activeSnapshot
+	wake      chan struct{}
+	records   chan *activeSnapshot
+	group     singleflight.Group
+	refreshed time.Time
+	params    activeParams
+	bounds    activeBounds
+	interval  time.Duration
+	freshFor  time.Duration
+	staleFor  time.Duration
+	mu        sync.RWMutex
+}
+
+// newActiveSource returns a source for the client. Each snapshot it computes is recorded in st
+// unless st is nil, and computation stops while breaker is open.
+func newActiveSource(client *itemClient, params activeParams, st *store, breaker *circuitBreaker) *activeSource {
+	return &activeSource{
+		client:    client,
+		store:     st,
+		breaker:   breaker,
+		lastGood:  newLRUCache[string, *activeSnapshot](lastGoodEntries, 0, nil),
+		snapshot:  nil,
+		wake:      make(chan struct{}, 1),
+		records:   make(chan *activeSnapshot, recordQueueSize),
+		group:     singleflight.Group{},
+		refreshed: time.Now(),
+		params:    params,
+		bounds:    activeBounds{MinWindow: 0, MaxWindow: 0, MaxMaxAge: 0, MinMinBy: 0, MaxMinBy: 0},
+		interval:  0,
+		freshFor:  0,
+		staleFor:  0,
+		mu:        sync.RWMutex{},
+	}
+}
+
+// Run refreshes the precomputed snapshot every interval set with SetInterval until ctx is done.
+// Nothing is precomputed while the interval is 0.
+func (s *activeSource) Run(ctx context.Context) {
+	// the first refresh already uses an interval set before Run started
+	select {
+	case <-s.wake:
+	default:
+	}
+
+	for {
+		var next <-chan time.Time
+
+		if interval := s.Interval(); interval > 0 {
+			s.refresh(ctx)
+
+			next = time.After(interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-next:
+		case <-s.wake:
+		}
+	}
+}
+
+// RecordSnapshots records the snapshots computed for requests in the store until ctx is done, so
+// indexing and archiving their items does not hold up the requests. It returns immediately if the
+// source has no store.
+func (s *activeSource) RecordSnapshots(ctx context.Context) {
+	if s.store == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case snapshot := <-s.records:
+			s.store.recordSnapshot(ctx, snapshot)
+		}
+	}
+}
+
+// Current returns the precomputed snapshot, or nil if there is none yet.
+func (s *activeSource) Current() *activeSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.snapshot
+}
+
+// Refresh refreshes the precomputed snapshot right away instead of waiting for the interval, unless
+// precomputing is disabled.
+func (s *activeSource) Refresh() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Interval returns how often the precomputed snapshot is refreshed, or 0 if it is not.
+func (s *activeSource) Interval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.interval
+}
+
+// SetInterval changes how often the precomputed snapshot is refreshed. A changed interval takes
+// effect right away with a refresh. An interval of 0 drops the precomputed snapshot, since it would
+// never be refreshed again.
+func (s *activeSource) SetInterval(interval time.Duration) {
+	s.mu.Lock()
+	changed := interval != s.interval
+	s.interval = interval
+
+	if interval == 0 {
+		s.snapshot = nil
+	}
+
+	s.mu.Unlock()
+
+	if changed {
+		s.Refresh()
+	}
+}
+
+// SetFreshness changes how long a computed snapshot is reused as is, and how long after that it is
+// served marked as stale while a new one is computed. A freshFor of 0 computes every snapshot.
+func (s *activeSource) SetFreshness(freshFor time.Duration, staleFor time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.freshFor = freshFor
+	s.staleFor = staleFor
+}
+
+// Get returns the precomputed snapshot when it matches params and is no more than
+// precomputedIntervals refresh intervals old, then a recently computed snapshot for params, and
+// otherwise computes a new one, sharing the work with any identical computation already in flight.
+// If ctx is done before the computation is, the last good snapshot is returned marked as stale, or
+// the error of ctx if there is none; the computation carries on for the other requests waiting.
+func (s *activeSource) Get(ctx context.Context, params activeParams) (*activeSnapshot, error) {
+	s.mu.RLock()
+	snapshot := s.snapshot
+	freshFor, staleFor := s.freshFor, s.staleFor
+	maxAge := precomputedIntervals * s.interval
+	s.mu.RUnlock()
+
+	if snapshot != nil && snapshot.Params == params && time.Since(snapshot.Time) <= maxAge {
+		return snapshot, nil
+	}
+
+	if !s.breaker.Allow() {
+		return s.stale(params, errCircuitOpen)
+	}
+
+	// the computation must not be canceled when the request that started it goes away because
+	// other requests may be waiting for it
+	detached := context.WithoutCancel(ctx)
+	compute := func() (any, error) { return s.compute(detached, params) }
+
+	if last, ok := s.lastGood.Get(params.key()); ok && freshFor > 0 {
+		age := time.Since(last.Time)
+
+		if age < freshFor {
+			return last, nil
+		}
+
+		if age < freshFor+staleFor {
+			// the caller does not wait for the result, which replaces last in lastGood
+			s.group.DoChan(params.key(), compute)
+
+			return last.stale(), nil
+		}
+	}
+
+	return s.wait(ctx, params, s.group.DoChan(params.key(), compute))
+}
+
+// wait returns the snapshot computed for params once results has it, or the last good snapshot if
+// ctx is done first or the computation failed.
+func (s *activeSource) wait(
+	ctx context.Context,
+	params activeParams,
+	results <-chan singleflight.Result,
+) (*activeSnapshot, error) {
+	select {
+	case <-ctx.Done():
+		return s.stale(params, fmt.Errorf("failed waiting for active roots: %w", ctx.Err()))
+	case result := <-results:
+		if result.Err != nil {
+			return s.stale(params, fmt.Errorf("failed to compute active roots: %w", result.Err))
+		}
+
+		snapshot, _ := result.Val.(*activeSnapshot)
+
+		return snapshot, nil
+	}
+}
+
+// Params returns the parameters of the precomputed snapshot, which are the defaults of /active.
+func (s *activeSource) Params() activeParams {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.params
+}
+
+// Bounds returns the ranges requested parameters must be in.
+func (s *activeSource) Bounds() activeBounds {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.bounds
+}
+
+// SetBounds changes the ranges requested parameters must be in.
+func (s *activeSource) SetBounds(bounds activeBounds) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bounds = bounds
+}
+
+// SetParams changes the parameters of the precomputed snapshot from the next refresh on. Until
+// then requests with the new parameters compute their own snapshot.
+func (s *activeSource) SetParams(params activeParams) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.params = params
+}
+
+// stale returns the last good snapshot for params marked as stale, or err if there is none.
+func (s *activeSource) stale(params activeParams, err error) (*activeSnapshot, error) {
+	snapshot, ok := s.lastGood.Get(params.key())
+	if !ok {
+		return nil, err
+	}
+
+	return snapshot.stale(), nil
+}
+
+// At returns the snapshot for params as of at, recomputed from the store, or the current snapshot
+// if at is the zero time.
+func (s *activeSource) At(ctx context.Context, at time.Time, params activeParams) (*activeSnapshot, error) {
+	if at.IsZero() {
+		return s.Get(ctx, params)
+	}
+
+	if s.store == nil {
+		return nil, errNoStore
+	}
+
+	return s.store.activeSnapshotAt(ctx, at, params)
+}
+
+// Refreshed returns when the precomputed snapshot was last refreshed, or when the source was
+// created if it has not been yet.
+func (s *activeSource) Refreshed() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.refreshed
+}
+
+func (s *activeSource) refresh(ctx context.Context) {
+	if !s.breaker.Allow() {
+		s.markStale()
+		return
+	}
+
+	snapshot, err := s.compute(ctx, s.Params())
+	if err != nil {
+		log.Printf("failed to precompute active roots: %v", err)
+		s.markStale()
+
+		return
+	}
+
+	s.mu.Lock()
+	s.snapshot = snapshot
+	s.refreshed = snapshot.Time
+	s.mu.Unlock()
+
+	if s.store != nil {
+		err = s.store.archiveSnapshot(ctx, snapshot)
+		if err != nil {
+			log.Printf("failed to archive active snapshot: %v", err)
+		}
+	}
+}
+
+// markStale keeps serving the precomputed snapshot after a refresh failed, but marked as stale.
+func (s *activeSource) markStale() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.snapshot != nil && !s.snapshot.Stale {
+		s.snapshot = s.snapshot.stale()
+	}
+}
+
+func (s *activeSource) compute(ctx context.Context, params activeParams) (*activeSnapshot, error) {
+	now := time.Now()
+
+	roots, tree, secondChanceFailed, err := getActiveRoots(
+		ctx, s.client, now, now.Add(-params.Window), params.MaxAge, params.MinBy)
+
+	s.breaker.Record(err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &activeSnapshot{
+		Time:               now,
+		Tree:               tree,
+		Roots:              roots,
+		Params:             params,
+		SecondChanceFailed: secondChanceFailed,
+		Historical:         false,
+		Stale:              false,
+	}
+
+	s.lastGood.Put(params.key(), snapshot, lastGoodTTL)
+
+	if s.store != nil {
+		select {
+		case s.records <- snapshot:
+		default:
+			log.Printf("failed to record active snapshot: %d waiting", recordQueueSize)
+		}
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var errCompute = errors.New("compute failed")
+
+func TestActiveSourceWait(t *testing.T) {
+	t.Parallel()
+
+	params := activeParams{Window: time.Hour, MaxAge: time.Hour, MinBy: 1}
+	last := &activeSnapshot{
+		Time:               time.Now(),
+		Tree:               nil,
+		Roots:              nil,
+		Params:             params,
+		SecondChanceFailed: false,
+		Historical:         false,
+		Stale:              false,
+	}
+	computed := *last
+
+	tests := []struct {
+		result    *singleflight.Result
+		err       error
+		name      string
+		lastGood  bool
+		canceled  bool
+		wantStale bool
+	}{
+		{
+			result:    &singleflight.Result{Val: &computed, Err: nil, Shared: false},
+			err:       nil,
+			name:      "computed",
+			lastGood:  true,
+			canceled:  false,
+			wantStale: false,
+		},
+		{
+			result:    nil,
+			err:       nil,
+			name:      "canceled with last good",
+			lastGood:  true,
+			canceled:  true,
+			wantStale: true,
+		},
+		{
+			result:    nil,
+			err:       context.Canceled,
+			name:      "canceled without last good",
+			lastGood:  false,
+			canceled:  true,
+			wantStale: false,
+		},
+		{
+			result:    &singleflight.Result{Val: nil, Err: errCompute, Shared: false},
+			err:       nil,
+			name:      "failed with last good",
+			lastGood:  true,
+			canceled:  false,
+			wantStale: true,
+		},
+		{
+			result:    &singleflight.Result{Val: nil, Err: errCompute, Shared: false},
+			err:       errCompute,
+			name:      "failed without last good",
+			lastGood:  false,
+			canceled:  false,
+			wantStale: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newActiveSource(nil, params, nil, newCircuitBreaker(0, 0))
+			if tt.lastGood {
+				s.lastGood.Put(params.key(), last, time.Hour)
+			}
+
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			// a computation that never finishes unless the test has a result for it
+			results := make(chan singleflight.Result, 1)
+			if tt.result != nil {
+				results <- *tt.result
+			}
+
+			if tt.canceled {
+				cancel()
+			}
+
+			snapshot, err := s.wait(ctx, params, results)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error %v, want %v", err, tt.err)
+			}
+
+			if tt.err != nil {
+				return
+			}
+
+			if snapshot.Stale != tt.wantStale {
+				t.Errorf("stale %v, want %v", snapshot.Stale, tt.wantStale)
+			}
+		})
+	}
+}
+
+func TestActiveBoundsCheck(t *testing.T) {
+	t.Parallel()
+
+	bounds := activeBounds{
+		MinWindow: time.Minute,
+		MaxWindow: 24 * time.Hour,
+		MaxMaxAge: 48 * time.Hour,
+		MinMinBy:  1,
+		MaxMinBy:  10,
+	}
+
+	tests := []struct {
+		name   string
+		want   []string
+		params activeParams
+	}{
+		{name: "in range", want: nil, params: activeParams{Window: time.Hour, MaxAge: time.Hour, MinBy: 2}},
+		{
+			name:   "lower bounds",
+			want:   nil,
+			params: activeParams{Window: time.Minute, MaxAge: time.Second, MinBy: 1},
+		},
+		{
+			name:   "upper bounds",
+			want:   nil,
+			params: activeParams{Window: 24 * time.Hour, MaxAge: 48 * time.Hour, MinBy: 10},
+		},
+		{
+			name:   "window too short",
+			want:   []string{"window"},
+			params: activeParams{Window: time.Second, MaxAge: time.Hour, MinBy: 2},
+		},
+		{
+			name:   "window too long",
+			want:   []string{"window"},
+			params: activeParams{Window: 25 * time.Hour, MaxAge: time.Hour, MinBy: 2},
+		},
+		{
+			name:   "max-age below a second",
+			want:   []string{"max-age"},
+			params: activeParams{Window: time.Hour, MaxAge: time.Millisecond, MinBy: 2},
+		},
+		{
+			name:   "max-age too long",
+			want:   []string{"max-age"},
+			params: activeParams{Window: time.Hour, MaxAge: 49 * time.Hour, MinBy: 2},
+		},
+		{
+			name:   "min-by too small",
+			want:   []string{"min-by"},
+			params: activeParams{Window: time.Hour, MaxAge: time.Hour, MinBy: 0},
+		},
+		{
+			name:   "min-by too large",
+			want:   []string{"min-by"},
+			params: activeParams{Window: time.Hour, MaxAge: time.Hour, MinBy: 11},
+		},
+		{
+			name:   "all out of range",
+			want:   []string{"window", "max-age", "min-by"},
+			params: activeParams{Window: 0, MaxAge: 0, MinBy: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := bounds.check(tt.params)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("error %v, want nil", err)
+				}
+
+				return
+			}
+
+			joined, ok := err.(interface{ Unwrap() []error })
+			if !ok {
+				t.Fatalf("error %v, want joined errors", err)
+			}
+
+			var got []string
+
+			for _, e := range joined.Unwrap() {
+				var rangeErr *paramRangeError
+				if !errors.As(e, &rangeErr) {
+					t.Fatalf("error %v, want a *paramRangeError", e)
+				}
+
+				got = append(got, rangeErr.Param)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("params out of range %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
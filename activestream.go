@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamActiveResponse writes the flat JSON /active response with the items of each root as roots
+// yields them instead of building every item first, so clients get the first roots sooner and the
+// whole item list is never held in memory at once. The truncation is read once roots is done, and
+// written after the items. The output matches PureJSON of the equivalent handleActiveResponse.
+func streamActiveResponse(
+	c *gin.Context,
+	snapshot *activeSnapshot,
+	roots iter.Seq[[]handleActiveResponseItem],
+	truncation func() *responseTruncation,
+) {
+	envelope, split, err := activeEnvelope(snapshot, nil)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to encode response")
+		return
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+
+	err = writeActiveStream(w, envelope[:split], roots, func() ([]byte, error) {
+		envelope, split, err := activeEnvelope(snapshot, truncation())
+		if err != nil {
+			return nil, err
+		}
+
+		return append(envelope[split:], '\n'), nil
+	})
+	if err != nil {
+		// the status and part of the body are already sent, so the incomplete JSON is the only signal
+		// to the client; the error keeps the response out of the response cache
+		log.Printf("failed to stream /active response: %v", err)
+
+		_ = c.Error(err)
+	}
+}
+
+// writeActiveStream writes head, the items of roots, and the tail, which is only built once every
+// item is written.
+func writeActiveStream(
+	w io.Writer,
+	head []byte,
+	roots iter.Seq[[]handleActiveResponseItem],
+	tail func() ([]byte, error),
+) error {
+	_, err := w.Write(head)
+	if err != nil {
+		return fmt.Errorf("failed to write response: %w", err)
+	}
+
+	err = writeActiveItems(w, roots)
+	if err != nil {
+		return err
+	}
+
+	end, err := tail()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(end)
+	if err != nil {
+		return fmt.Errorf("failed to write response: %w", err)
+	}
+
+	return nil
+}
+
+// writeActiveItems writes the items of each root as roots yields them, separated by commas.
+func writeActiveItems(w io.Writer, roots iter.Seq[[]handleActiveResponseItem]) error {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	first := true
+
+	for items := range roots {
+		buf.Reset()
+
+		for _, item := range items {
+			if !first {
+				buf.WriteByte(',')
+			}
+
+			first = false
+
+			err := enc.Encode(item)
+			if err != nil {
+				return fmt.Errorf("failed to encode item: %w", err)
+			}
+
+			// Encode ends each value with a newline that PureJSON does not have inside arrays
+			buf.Truncate(buf.Len() - 1)
+		}
+
+		_, err := w.Write(buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to write items: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// activeEnvelope encodes the /active response with no items and returns the index of the end of
+// the opening of the empty item array, so the field names and omitted fields follow the struct tags.
+func activeEnvelope(snapshot *activeSnapshot, truncation *responseTruncation) ([]byte, int, error) {
+	envelope, err := json.Marshal(handleActiveResponse{
+		Items:              []handleActiveResponseItem{},
+		responseTruncation: truncation,
+		SecondChanceFailed: snapshot.SecondChanceFailed,
+		Stale:              snapshot.Stale,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	return envelope, bytes.Index(envelope, []byte("[]")) + 1, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+const (
+	activityWindow      = "window"
+	activityVelocity    = "velocity"
+	activityRepliesToMe = "replies-to-me"
+	activityOPEngaged   = "op-engaged"
+)
+
+// velocityShare is the fraction of the window within which a reply must follow its parent to
+// count as active under the velocity rule.
+const velocityShare = 4
+
+// activityThread is what an activity rule knows about the thread of a comment.
+type activityThread struct {
+	Items       map[int]*hn.Item
+	Root        *hn.Item
+	Me          string
+	ActiveAfter int64
+	Window      time.Duration
+}
+
+func (t *activityThread) parent(item *hn.Item) *hn.Item {
+	return t.Items[item.Parent]
+}
+
+// activityRule reports whether a comment counts as active in its thread.
+type activityRule func(item *hn.Item, thread *activityThread) bool
+
+// activityRules are the definitions of an active comment selected by the activity query parameter.
+//
+//nolint:gochecknoglobals // constant table
+var activityRules = map[string]activityRule{
+	activityWindow:      inWindow,
+	activityVelocity:    fastReply,
+	activityRepliesToMe: replyToMe,
+	activityOPEngaged:   opEngaged,
+}
+
+// inWindow is the default rule: a comment is active if it was posted within the window.
+func inWindow(item *hn.Item, thread *activityThread) bool {
+	return item.Time > thread.ActiveAfter
+}
+
+// fastReply counts a comment within the window if it followed its parent quickly, within a share
+// of the window, so only fast back-and-forth stands out.
+func fastReply(item *hn.Item, thread *activityThread) bool {
+	parent := thread.parent(item)
+	gap := int64(thread.Window / velocityShare / time.Second)
+
+	return inWindow(item, thread) && parent != nil && item.Time-parent.Time <= gap
+}
+
+// replyToMe counts a comment within the window if it replies to the user named by the me
+// parameter.
+func replyToMe(item *hn.Item, thread *activityThread) bool {
+	parent := thread.parent(item)
+	return inWindow(item, thread) && parent != nil && parent.By == thread.Me && item.By != thread.Me
+}
+
+// opEngaged counts a comment within the window if the author of the story wrote it or it replies
+// to one of their comments.
+func opEngaged(item *hn.Item, thread *activityThread) bool {
+	op := thread.Root.By
+	if op == "" || !inWindow(item, thread) {
+		return false
+	}
+
+	parent := thread.parent(item)
+
+	return item.By == op || (parent != nil && parent != thread.Root && parent.By == op)
+}
+
+// parseActivity reads the activity query parameter naming one of activityRules, window by default,
+// and the me parameter naming the user replies-to-me looks for, adding an error to errs if either
+// is invalid.
+func parseActivity(c *gin.Context, errs *paramErrors) (string, string) {
+	activity := c.DefaultQuery("activity", activityWindow)
+	if _, ok := activityRules[activity]; !ok {
+		errs.add(codeInvalidActivity, "activity", "invalid activity")
+	}
+
+	me := c.Query("me")
+	if activity == activityRepliesToMe && me == "" {
+		errs.add(codeInvalidActivity, "me", "activity=replies-to-me requires me")
+	}
+
+	return activity, me
+}
+
+// threadActivity returns a function reporting whether a comment of the flattened thread under root
+// is active under the activity rule of opts. The root itself never is.
+func threadActivity(
+	flat []unl.FlatItem,
+	root *hn.Item,
+	window time.Duration,
+	activeAfter time.Time,
+	opts activeItemOptions,
+) func(*hn.Item) bool {
+	thread := &activityThread{
+		Items:       make(map[int]*hn.Item, len(flat)),
+		Root:        root,
+		Me:          opts.Me,
+		ActiveAfter: activeAfter.Unix(),
+		Window:      window,
+	}
+
+	for _, f := range flat {
+		thread.Items[f.ID] = f.Item
+	}
+
+	rule := activityRules[opts.Activity]
+
+	return func(item *hn.Item) bool {
+		return item.ID != root.ID && rule(item, thread)
+	}
+}
+
+// buildActiveMap marks the items of a flattened thread like unl.BuildActiveMap, which implements
+// the default window rule, with subtrees from activeSubtrees standing in for it under the others.
+func buildActiveMap(
+	flat []unl.FlatItem,
+	activeAfter time.Time,
+	activity string,
+	isActive func(*hn.Item) bool,
+	subtrees map[int]bool,
+) map[int]unl.ActiveMapEntry {
+	if activity == activityWindow {
+		return unl.BuildActiveMap(flat, activeAfter)
+	}
+
+	activeMap := make(map[int]unl.ActiveMapEntry, len(subtrees))
+
+	for _, f := range flat {
+		switch {
+		case isActive(f.Item):
+			activeMap[f.ID] = unl.ActiveMapSelf
+		case subtrees[f.ID]:
+			activeMap[f.ID] = unl.ActiveMapChild
+		}
+	}
+
+	return activeMap
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdminToken rejects requests without an Authorization header carrying the bearer token.
+func requireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasAdminToken(c, token) {
+			respondUnauthorized(c)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// hasAdminToken reports whether the request carries the bearer token, which must not be empty.
+func hasAdminToken(c *gin.Context, token string) bool {
+	got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+func respondUnauthorized(c *gin.Context) {
+	c.Header("WWW-Authenticate", "Bearer")
+	respondError(c, http.StatusUnauthorized, codeUnauthorized, "missing or invalid admin token")
+}
+
+// registerAdmin adds the cache and configuration administration endpoints under /admin, or
+// nothing if there is no admin token.
+func registerAdmin(
+	r *gin.Engine, live *liveConfig, responses *lruCache[string, cachedResponse], formatter *textFormatter,
+) {
+	cfg := live.Load()
+	if cfg.AdminToken == "" {
+		return
+	}
+
+	admin := r.Group("/admin", requireAdminToken(cfg.AdminToken))
+	admin.GET("/cache/stats", func(c *gin.Context) { handleCacheStats(c, cfg, responses.Stats(), formatter.Stats()) })
+	admin.POST("/cache/purge", func(c *gin.Context) { handleCachePurge(c, cfg, responses) })
+	admin.POST("/cache/compact", func(c *gin.Context) { handleCacheCompact(c, cfg) })
+	admin.GET("/config", func(c *gin.Context) { handleGetAdminConfig(c, live) })
+	admin.PATCH("/config", func(c *gin.Context) { handlePatchAdminConfig(c, live) })
+}
+
+type memoryCacheStats struct {
+	Oldest     string  `json:"oldest,omitempty"`
+	Entries    int     `json:"entries"`
+	MaxEntries int     `json:"maxEntries"`
+	Bytes      int64   `json:"bytes"`
+	MaxBytes   int64   `json:"maxBytes"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hitRate"`
+}
+
+func newMemoryCacheStats(stats lruStats) memoryCacheStats {
+	hitRate := 0.0
+	if stats.Hits+stats.Misses > 0 {
+		hitRate = float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+	}
+
+	oldest := ""
+	if !stats.Oldest.IsZero() {
+		oldest = stats.Oldest.UTC().Format(time.RFC3339)
+	}
+
+	return memoryCacheStats{
+		Oldest:     oldest,
+		Entries:    stats.Entries,
+		MaxEntries: stats.MaxEntries,
+		Bytes:      stats.Bytes,
+		MaxBytes:   stats.MaxBytes,
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		HitRate:    hitRate,
+	}
+}
+
+type itemCacheStats struct {
+	Tables    map[string]int64 `json:"tables"`
+	Path      string           `json:"path"`
+	SizeBytes int64            `json:"sizeBytes"`
+}
+
+type handleCacheStatsResponse struct {
+	Items     itemCacheStats   `json:"items"`
+	Responses memoryCacheStats `json:"responses"`
+	Text      memoryCacheStats `json:"text"`
+}
+
+// handleCacheStats reports the size of the item cache of the HN client and the size against the
+// limits and hit rate of the in-memory response and formatted text caches. Hits are only counted
+// for the in-memory caches since the client keeps its own.
+func handleCacheStats(c *gin.Context, cfg config, responses lruStats, text lruStats) {
+	tables, err := withItemCache(c.Request.Context(), cfg, tableCounts)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read item cache")
+		return
+	}
+
+	respond(c, http.StatusOK, handleCacheStatsResponse{
+		Items: itemCacheStats{
+			Tables:    tables,
+			Path:      cfg.HNCache,
+			SizeBytes: sizeOnDisk(cfg.HNCache),
+		},
+		Responses: newMemoryCacheStats(responses),
+		Text:      newMemoryCacheStats(text),
+	})
+}
+
+type handleCachePurgeResponse struct {
+	Items     int64 `json:"items"`
+	Responses int   `json:"responses"`
+}
+
+// handleCachePurge empties the response cache, the item cache, or both, as selected by the cache
+// query parameter.
+func handleCachePurge(c *gin.Context, cfg config, responses *lruCache[string, cachedResponse]) {
+	which := c.DefaultQuery("cache", "all")
+	if which != "all" && which != "items" && which != "responses" {
+		respondParamError(c, codeInvalidCache, "cache", "invalid cache")
+		return
+	}
+
+	var result handleCachePurgeResponse
+
+	if which != "items" {
+		result.Responses = responses.Stats().Entries
+		responses.Purge()
+	}
+
+	if which != "responses" {
+		var err error
+
+		result.Items, err = withItemCache(c.Request.Context(), cfg, deleteAllRows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, codeInternalError, "failed to purge item cache")
+			return
+		}
+	}
+
+	respond(c, http.StatusOK, result)
+}
+
+type handleCacheCompactResponse struct {
+	SizeBefore int64 `json:"sizeBefore"`
+	SizeAfter  int64 `json:"sizeAfter"`
+}
+
+// handleCacheCompact runs VACUUM on the item cache to return the space of purged and expired
+// items to the file system.
+func handleCacheCompact(c *gin.Context, cfg config) {
+	before := sizeOnDisk(cfg.HNCache)
+
+	_, err := withItemCache(c.Request.Context(), cfg, func(ctx context.Context, db *sql.DB) (struct{}, error) {
+		_, err := db.ExecContext(ctx, `VACUUM`)
+		return struct{}{}, err //nolint:wrapcheck // wrapped by withItemCache
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to compact item cache")
+		return
+	}
+
+	respond(c, http.StatusOK, handleCacheCompactResponse{SizeBefore: before, SizeAfter: sizeOnDisk(cfg.HNCache)})
+}
+
+// withItemCache opens a second connection to the SQLite database of the HN client for fn. SQLite
+// locking keeps this safe while the client is using it.
+func withItemCache[T any](ctx context.Context, cfg config, fn func(context.Context, *sql.DB) (T, error)) (T, error) {
+	var invalid T
+
+	db, err := sql.Open("sqlite3", cfg.HNCache)
+	if err != nil {
+		return invalid, fmt.Errorf("failed to open item cache: %w", err)
+	}
+
+	defer func() { _ = db.Close() }()
+
+	result, err := fn(ctx, db)
+	if err != nil {
+		return invalid, fmt.Errorf("failed to access item cache: %w", err)
+	}
+
+	return result, nil
+}
+
+// cacheTables returns the names of the tables the HN client created, whatever its schema is.
+func cacheTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	var tables []string
+
+	for rows.Next() {
+		var name string
+
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read table name: %w", err)
+		}
+
+		tables = append(tables, name)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	return tables, nil
+}
+
+// tableCounts returns the number of rows in each table of the item cache.
+func tableCounts(ctx context.Context, db *sql.DB) (map[string]int64, error) {
+	tables, err := cacheTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(tables))
+
+	for _, table := range tables {
+		var count int64
+
+		err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+quoteIdentifier(table)).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+
+		counts[table] = count
+	}
+
+	return counts, nil
+}
+
+// deleteAllRows empties every table of the item cache and returns the number of rows deleted.
+func deleteAllRows(ctx context.Context, db *sql.DB) (int64, error) {
+	tables, err := cacheTables(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+
+	for _, table := range tables {
+		result, err := db.ExecContext(ctx, `DELETE FROM `+quoteIdentifier(table))
+		if err != nil {
+			return deleted, fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+
+		n, _ := result.RowsAffected()
+		deleted += n
+	}
+
+	return deleted, nil
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sizeOnDisk returns the combined size of a SQLite database and its journal files, or 0 for an
+// in-memory database.
+func sizeOnDisk(path string) int64 {
+	var size int64
+
+	if path == "memory" {
+		return 0
+	}
+
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		info, err := os.Stat(path + suffix)
+		if err == nil {
+			size += info.Size()
+		}
+	}
+
+	return size
+}
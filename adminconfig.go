@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errNotAdjustable = errors.New("only precompute-interval, window, max-age, min-by, log-level, hn-rate, and " +
+	"hn-burst can be changed while running")
+
+type handleAdminConfigResponse struct {
+	Settings map[string]string `json:"settings"`
+}
+
+// handleGetAdminConfig responds with the current value of every setting, by flag name, with
+// secrets redacted.
+func handleGetAdminConfig(c *gin.Context, live *liveConfig) {
+	c.Header("Cache-Control", "no-store")
+	respond(c, http.StatusOK, handleAdminConfigResponse{Settings: live.Load().Effective})
+}
+
+// handlePatchAdminConfig changes the settings in a JSON body like {"window": "2h", "hn-rate": 50}
+// without a restart. Either every setting is valid and all are applied, or none are. The changes
+// last until the configuration is reloaded or the server restarts.
+func handlePatchAdminConfig(c *gin.Context, live *liveConfig) {
+	var req map[string]any
+
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+
+	err := decoder.Decode(&req)
+	if err != nil || len(req) == 0 {
+		respondError(c, http.StatusBadRequest, codeInvalidBody, "invalid request body")
+		return
+	}
+
+	names := slices.Sorted(maps.Keys(req))
+
+	cfg, err := live.Update(func(cfg *config) error {
+		for _, name := range names {
+			err := adjustSetting(cfg, name, req[name])
+			if err != nil {
+				return err
+			}
+		}
+
+		return cfg.validate()
+	})
+	if err != nil {
+		respondError(c, http.StatusBadRequest, codeInvalidSetting, err.Error())
+		return
+	}
+
+	log.Printf("changed %s through /admin/config", strings.Join(names, ", "))
+
+	c.Header("Cache-Control", "no-store")
+	respond(c, http.StatusOK, handleAdminConfigResponse{Settings: cfg.Effective})
+}
+
+// adjustSetting changes the setting with the flag name name in cfg, and its effective value.
+//
+//nolint:cyclop // one case per setting
+func adjustSetting(cfg *config, name string, v any) error {
+	value, err := configValue(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	switch name {
+	case "precompute-interval":
+		cfg.PrecomputeInterval, err = time.ParseDuration(value)
+	case "window":
+		cfg.Window, err = parsePositiveDuration(value, time.Second)
+	case "max-age":
+		cfg.MaxAge, err = parsePositiveDuration(value, time.Second)
+	case "min-by":
+		cfg.MinBy, err = strconv.Atoi(value)
+	case "hn-burst":
+		cfg.HNBurst, err = strconv.Atoi(value)
+	case "hn-rate":
+		cfg.HNRate, err = strconv.ParseFloat(value, 64)
+	case "log-level":
+		err = cfg.LogLevel.UnmarshalText([]byte(value))
+	default:
+		return fmt.Errorf("%w: %s", errNotAdjustable, name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	cfg.Effective[name] = value
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// maxAncestors bounds the walk up the parent chain in case of a cycle in upstream data.
+const maxAncestors = 1000
+
+// getAncestors returns the chain of items from the root story down to and including the item,
+// with errors as from getItem.
+func getAncestors(ctx context.Context, client *itemClient, itemID int) ([]*hn.Item, error) {
+	_, item, err := getItem(ctx, client, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []*hn.Item{item}
+
+	for item.Parent != 0 && len(chain) < maxAncestors {
+		_, item, err = getItem(ctx, client, item.Parent)
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append(chain, item)
+	}
+
+	slices.Reverse(chain)
+
+	return chain, nil
+}
+
+// getStoryID returns the ID of the story at the top of the thread that item is in, with errors as
+// from getItem.
+func getStoryID(ctx context.Context, client *itemClient, item *hn.Item) (int, error) {
+	if item.Parent == 0 {
+		return item.ID, nil
+	}
+
+	chain, err := getAncestors(ctx, client, item.Parent)
+	if err != nil {
+		return 0, err
+	}
+
+	return chain[0].ID, nil
+}
+
+// handleItemAncestors returns the chain of items from the root story down to and including the
+// requested item, formatted like /item/:id/tree.
+func handleItemAncestors(c *gin.Context, client *itemClient, formatter *textFormatter) {
+	ctx := c.Request.Context()
+
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondParamError(c, codeInvalidID, "id", "invalid id")
+		return
+	}
+
+	showUser, ok := parseShowUser(c)
+	if !ok {
+		respondParamError(c, codeInvalidUser, "show-user", "invalid show-user")
+		return
+	}
+
+	textMode, ok := parseTextMode(c)
+	if !ok {
+		respondParamError(c, codeInvalidText, "text", "invalid text")
+		return
+	}
+
+	chain, err := getAncestors(ctx, client, itemID)
+	if err != nil {
+		respondItemError(c, err)
+		return
+	}
+
+	response := make([]handleItemDescendantsResponse, 0, len(chain))
+
+	for depth, item := range chain {
+		by := item.By
+		if !showUser {
+			by = ""
+		}
+
+		var story *storyMetadata
+		if depth == 0 {
+			story = newStoryMetadata(item)
+		}
+
+		response = append(response, handleItemDescendantsResponse{
+			storyMetadata:     story,
+			By:                by,
+			Text:              formatter.formatAs(item, textMode),
+			Children:          nil,
+			AccountAgeDays:    nil,
+			Karma:             nil,
+			Time:              item.Time,
+			ID:                item.ID,
+			Parent:            item.Parent,
+			Root:              chain[0].ID,
+			Depth:             depth,
+			TruncatedChildren: 0,
+			OP:                isOP(item, chain[0]),
+			Dead:              item.Dead,
+			Deleted:           item.Deleted,
+			IsNew:             false,
+			NewUser:           false,
+		})
+	}
+
+	respond(c, http.StatusOK, response)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+// archiveItems stores the latest version of each item so active sets can later be recomputed as
+// of a past time.
+func (s *store) archiveItems(ctx context.Context, items []*hn.Item) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin archiving: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	for _, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to encode item %d: %w", item.ID, err)
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO items (id, parent, time, data) VALUES (?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET parent = excluded.parent, time = excluded.time, data = excluded.data`,
+			item.ID, item.Parent, item.Time, string(data))
+		if err != nil {
+			return fmt.Errorf("failed to archive item %d: %w", item.ID, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit archive: %w", err)
+	}
+
+	return nil
+}
+
+// archivedItems returns the archived items posted after after and no later than until.
+func (s *store) archivedItems(ctx context.Context, after time.Time, until time.Time) (hn.ItemSet, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT data FROM items WHERE time > ? AND time <= ?`, after.Unix(), until.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	items := make(hn.ItemSet)
+
+	for rows.Next() {
+		var data string
+
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archived item: %w", err)
+		}
+
+		var item hn.Item
+
+		err = json.Unmarshal([]byte(data), &item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode archived item: %w", err)
+		}
+
+		items[item.ID] = &item
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	return items, nil
+}
+
+// activeSnapshotAt recomputes the active set as it would have been at a past time from the
+// archived items, leaving out the comments posted after it. Scores and text are the last ones
+// archived, and second-chance times are not known, so roots keep their original times.
+func (s *store) activeSnapshotAt(ctx context.Context, at time.Time, params activeParams) (*activeSnapshot, error) {
+	items, err := s.archivedItems(ctx, at.Add(-params.MaxAge), at)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, _, err := items.GroupByParent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to group archived items: %w", err)
+	}
+
+	activeAfter := at.Add(-params.Window).Unix()
+	roots := make([]handleActiveRoot, 0)
+
+	for _, item := range items {
+		if item.Parent != 0 || item.Dead || item.Deleted {
+			continue
+		}
+
+		if activeCommenters(item, tree, activeAfter) >= params.MinBy {
+			roots = append(roots, handleActiveRoot{item, item.Time})
+		}
+	}
+
+	slices.SortFunc(roots, func(a, b handleActiveRoot) int { return cmp.Compare(b.Time, a.Time) })
+
+	return &activeSnapshot{
+		Time:               at,
+		Tree:               tree,
+		Roots:              roots,
+		Params:             params,
+		SecondChanceFailed: false,
+		Historical:         true,
+		Stale:              false,
+	}, nil
+}
+
+// activeCommenters counts the distinct authors of the live comments under root posted after
+// activeAfter.
+func activeCommenters(root *hn.Item, tree map[int]hn.ItemSet, activeAfter int64) int {
+	commenters := make(map[string]bool)
+
+	for _, f := range unl.FlattenTree(root, tree) {
+		if f.Depth > 0 && f.Time > activeAfter && !f.Dead && !f.Deleted {
+			commenters[f.By] = true
+		}
+	}
+
+	return len(commenters)
+}
+
+// parseAt reads the at query parameter, an RFC 3339 time in the past. It returns the zero time if
+// at is not set.
+func parseAt(c *gin.Context) (time.Time, bool) {
+	param := c.Query("at")
+	if param == "" {
+		return time.Time{}, true
+	}
+
+	at, err := time.Parse(time.RFC3339, param)
+	if err != nil || at.After(time.Now()) {
+		return time.Time{}, false
+	}
+
+	return at, true
+}
+
+// respondStoreDisabled responds that a feature needs the store, which the server does not have.
+func respondStoreDisabled(c *gin.Context) {
+	respondError(c, http.StatusNotImplemented, codeStoreDisabled, "the server has no store")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var errCircuitOpen = errors.New("HN API circuit is open")
+
+// circuitBreaker stops calls to a failing dependency for a cooldown once threshold calls in a row
+// have failed. After the cooldown calls are allowed again, and the first failure reopens it.
+type circuitBreaker struct {
+	openUntil time.Time
+	cooldown  time.Duration
+	threshold int
+	failures  int
+	mu        sync.Mutex
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		openUntil: time.Time{},
+		cooldown:  cooldown,
+		threshold: threshold,
+		failures:  0,
+		mu:        sync.Mutex{},
+	}
+}
+
+// Allow reports whether a call may be made, which is whenever the breaker is not open.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !time.Now().Before(b.openUntil)
+}
+
+// Record counts the outcome of a call, opening the breaker when failures reach the threshold and
+// closing it on success.
+func (b *circuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		b.openUntil = time.Time{}
+
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
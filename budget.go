@@ -0,0 +1,229 @@
+package main
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// responseBudget limits the size of an /active response. Zero limits are unlimited.
+type responseBudget struct {
+	MaxBytes int64
+	MaxItems int
+}
+
+// responseTruncation reports that items were left out of a response to keep it within its budget.
+type responseTruncation struct {
+	Truncated     bool `json:"truncated"`
+	TotalItems    int  `json:"totalItems"`
+	ReturnedItems int  `json:"returnedItems"`
+	OmittedItems  int  `json:"omittedItems"`
+}
+
+func (b responseBudget) unlimited() bool {
+	return b.MaxBytes <= 0 && b.MaxItems <= 0
+}
+
+func (b responseBudget) fits(items int, size int64) bool {
+	return (b.MaxItems <= 0 || items <= b.MaxItems) && (b.MaxBytes <= 0 || size <= b.MaxBytes)
+}
+
+// estimatedSize approximates the size of the JSON encoding of the item without its children,
+// which is cheaper than encoding it and close enough for a budget.
+func (item *handleActiveResponseItem) estimatedSize() int64 {
+	const (
+		itemOverhead    = 96
+		storyOverhead   = 64
+		metricsOverhead = 160
+	)
+
+	size := itemOverhead + len(item.By) + len(item.Text) + len(item.Age) + len(item.TimeISO)
+
+	if item.storyMetadata != nil {
+		size += storyOverhead + len(item.Type) + len(item.URL) + len(item.Domain)
+	}
+
+	if item.Metrics != nil {
+		size += metricsOverhead
+	}
+
+	for _, w := range item.ActiveWindows {
+		size += len(w) + len(`"",`)
+	}
+
+	return int64(size)
+}
+
+// measureItems returns the estimated size of items.
+func measureItems(items []handleActiveResponseItem) int64 {
+	var size int64
+
+	for i := range items {
+		size += items[i].estimatedSize()
+	}
+
+	return size
+}
+
+// truncationCandidate is a comment that can be left out of a response, with the number of active
+// items in the subtree it heads.
+type truncationCandidate struct {
+	index    int
+	parent   int
+	depth    int
+	id       int
+	activity int
+}
+
+// apply leaves comments out of the items of one root until they fit in what is left of the budget
+// after used items of usedSize bytes, lowest-activity subtrees first, returning the items kept and
+// their estimated size. The items must be in depth-first order. Comments are removed one at a time
+// ordered by the number of active items in their subtree, then deepest and oldest first, so a comment
+// is only removed after all of its replies and each parent counts its removed replies in
+// truncatedChildren. The root is never removed.
+func (b responseBudget) apply(
+	items []handleActiveResponseItem,
+	used int,
+	usedSize int64,
+) ([]handleActiveResponseItem, int64) {
+	if b.unlimited() {
+		return items, 0
+	}
+
+	size := measureItems(items)
+	if b.fits(used+len(items), usedSize+size) {
+		return items, size
+	}
+
+	candidates := truncationCandidates(items)
+
+	slices.SortFunc(candidates, func(a, b truncationCandidate) int {
+		return cmp.Or(cmp.Compare(a.activity, b.activity), cmp.Compare(b.depth, a.depth), cmp.Compare(a.id, b.id))
+	})
+
+	removed := make(map[int]bool)
+	returned := len(items)
+
+	for _, candidate := range candidates {
+		if b.fits(used+returned, usedSize+size) {
+			break
+		}
+
+		removed[items[candidate.index].ID] = true
+		items[candidate.parent].TruncatedChildren++
+		size -= items[candidate.index].estimatedSize()
+		returned--
+	}
+
+	return slices.DeleteFunc(items, func(item handleActiveResponseItem) bool { return removed[item.ID] }), size
+}
+
+// truncationCandidates returns a candidate for each comment in the depth-first items of a root,
+// counting the active items in the subtree under each.
+func truncationCandidates(items []handleActiveResponseItem) []truncationCandidate {
+	activity := make([]int, len(items))
+	parents := make([]int, len(items))
+
+	var ancestors []int
+
+	// pop removes the innermost ancestor and adds its activity to that of its parent
+	pop := func() {
+		last := ancestors[len(ancestors)-1]
+		ancestors = ancestors[:len(ancestors)-1]
+
+		if len(ancestors) > 0 {
+			activity[ancestors[len(ancestors)-1]] += activity[last]
+		}
+	}
+
+	for i := range items {
+		for len(ancestors) > 0 && items[ancestors[len(ancestors)-1]].Depth >= items[i].Depth {
+			pop()
+		}
+
+		parents[i] = -1
+		if len(ancestors) > 0 {
+			parents[i] = ancestors[len(ancestors)-1]
+		}
+
+		if items[i].Active {
+			activity[i] = 1
+		}
+
+		ancestors = append(ancestors, i)
+	}
+
+	for len(ancestors) > 0 {
+		pop()
+	}
+
+	var candidates []truncationCandidate
+
+	for i := range items {
+		if parents[i] < 0 {
+			continue
+		}
+
+		candidates = append(candidates, truncationCandidate{
+			index:    i,
+			parent:   parents[i],
+			depth:    items[i].Depth,
+			id:       items[i].ID,
+			activity: activity[i],
+		})
+	}
+
+	return candidates
+}
+
+// budgetedRoots applies a budget to the roots of a response as they are flattened, in order, so
+// the items are counted while they stream and never need to be collected. Earlier roots get the
+// first claim on the budget; once it is used up later roots are returned without their comments.
+type budgetedRoots struct {
+	budget   responseBudget
+	size     int64
+	total    int
+	returned int
+}
+
+func newBudgetedRoots(budget responseBudget) *budgetedRoots {
+	return &budgetedRoots{budget: budget, size: 0, total: 0, returned: 0}
+}
+
+// all returns roots with the budget applied to the items of each.
+func (r *budgetedRoots) all(
+	roots iter.Seq[[]handleActiveResponseItem],
+) iter.Seq[[]handleActiveResponseItem] {
+	return func(yield func([]handleActiveResponseItem) bool) {
+		for items := range roots {
+			if !yield(r.take(items)) {
+				return
+			}
+		}
+	}
+}
+
+// take applies what is left of the budget to the items of the next root.
+func (r *budgetedRoots) take(items []handleActiveResponseItem) []handleActiveResponseItem {
+	r.total += len(items)
+
+	kept, size := r.budget.apply(items, r.returned, r.size)
+	r.returned += len(kept)
+	r.size += size
+
+	return kept
+}
+
+// truncation reports what was left out of the roots taken so far, or nil if nothing was.
+func (r *budgetedRoots) truncation() *responseTruncation {
+	if r.returned == r.total {
+		return nil
+	}
+
+	return &responseTruncation{
+		Truncated:     true,
+		TotalItems:    r.total,
+		ReturnedItems: r.returned,
+		OmittedItems:  r.total - r.returned,
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// budgetItem returns an item with no text, which estimatedSize puts at budgetItemSize bytes.
+func budgetItem(id, depth int, active bool) handleActiveResponseItem {
+	return handleActiveResponseItem{
+		storyMetadata:     nil,
+		Metrics:           nil,
+		By:                "",
+		Text:              "",
+		Age:               "",
+		TimeISO:           "",
+		Children:          nil,
+		AccountAgeDays:    nil,
+		Karma:             nil,
+		ActiveWindows:     nil,
+		ActivityScore:     0,
+		Time:              0,
+		ID:                id,
+		Parent:            0,
+		Root:              0,
+		Depth:             depth,
+		TruncatedChildren: 0,
+		Conversation:      0,
+		Active:            active,
+		Collapsed:         false,
+		SecondChance:      false,
+		OP:                false,
+		Dead:              false,
+		Deleted:           false,
+		IsNew:             false,
+		NewUser:           false,
+		Followed:          false,
+	}
+}
+
+const budgetItemSize = 96
+
+// budgetTree is a story with an active reply that has an inactive reply of its own, and an inactive
+// reply.
+func budgetTree() []handleActiveResponseItem {
+	return []handleActiveResponseItem{
+		budgetItem(1, 0, false),
+		budgetItem(2, 1, true),
+		budgetItem(3, 2, false),
+		budgetItem(4, 1, false),
+	}
+}
+
+func TestResponseBudgetApply(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		truncated map[int]int
+		name      string
+		items     []handleActiveResponseItem
+		kept      []int
+		budget    responseBudget
+		used      int
+		usedSize  int64
+		size      int64
+	}{
+		{
+			name:      "unlimited",
+			budget:    responseBudget{MaxBytes: 0, MaxItems: 0},
+			items:     budgetTree(),
+			used:      100,
+			usedSize:  0,
+			kept:      []int{1, 2, 3, 4},
+			truncated: map[int]int{},
+			size:      0,
+		},
+		{
+			name:      "fits",
+			budget:    responseBudget{MaxBytes: 0, MaxItems: 4},
+			items:     budgetTree(),
+			used:      0,
+			usedSize:  0,
+			kept:      []int{1, 2, 3, 4},
+			truncated: map[int]int{},
+			size:      4 * budgetItemSize,
+		},
+		{
+			name:      "inactive subtrees first, deepest first",
+			budget:    responseBudget{MaxBytes: 0, MaxItems: 2},
+			items:     budgetTree(),
+			used:      0,
+			usedSize:  0,
+			kept:      []int{1, 2},
+			truncated: map[int]int{1: 1, 2: 1},
+			size:      2 * budgetItemSize,
+		},
+		{
+			name:      "replies before their parent",
+			budget:    responseBudget{MaxBytes: 0, MaxItems: 3},
+			items:     budgetTree(),
+			used:      2,
+			usedSize:  0,
+			kept:      []int{1},
+			truncated: map[int]int{1: 2},
+			size:      budgetItemSize,
+		},
+		{
+			name:      "root is never removed",
+			budget:    responseBudget{MaxBytes: 0, MaxItems: 1},
+			items:     budgetTree(),
+			used:      5,
+			usedSize:  0,
+			kept:      []int{1},
+			truncated: map[int]int{1: 2},
+			size:      budgetItemSize,
+		},
+		{
+			name:      "bytes",
+			budget:    responseBudget{MaxBytes: 3 * budgetItemSize, MaxItems: 0},
+			items:     budgetTree(),
+			used:      0,
+			usedSize:  budgetItemSize,
+			kept:      []int{1, 2},
+			truncated: map[int]int{1: 1, 2: 1},
+			size:      2 * budgetItemSize,
+		},
+		{
+			name:   "oldest first among equals",
+			budget: responseBudget{MaxBytes: 0, MaxItems: 2},
+			items: []handleActiveResponseItem{
+				budgetItem(1, 0, false),
+				budgetItem(5, 1, false),
+				budgetItem(4, 1, false),
+			},
+			used:      0,
+			usedSize:  0,
+			kept:      []int{1, 5},
+			truncated: map[int]int{1: 1},
+			size:      2 * budgetItemSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			kept, size := tt.budget.apply(tt.items, tt.used, tt.usedSize)
+
+			ids := make([]int, 0, len(kept))
+			truncated := make(map[int]int)
+
+			for _, item := range kept {
+				ids = append(ids, item.ID)
+
+				if item.TruncatedChildren > 0 {
+					truncated[item.ID] = item.TruncatedChildren
+				}
+			}
+
+			if !slices.Equal(ids, tt.kept) {
+				t.Errorf("kept %v, want %v", ids, tt.kept)
+			}
+
+			for id, n := range tt.truncated {
+				if truncated[id] != n {
+					t.Errorf("item %d truncatedChildren %d, want %d", id, truncated[id], n)
+				}
+			}
+
+			if len(truncated) != len(tt.truncated) {
+				t.Errorf("truncatedChildren %v, want %v", truncated, tt.truncated)
+			}
+
+			if size != tt.size {
+				t.Errorf("size %d, want %d", size, tt.size)
+			}
+		})
+	}
+}
+
+func TestBudgetedRoots(t *testing.T) {
+	t.Parallel()
+
+	roots := newBudgetedRoots(responseBudget{MaxBytes: 0, MaxItems: 5})
+
+	var ids []int
+
+	for items := range roots.all(slices.Values([][]handleActiveResponseItem{budgetTree(), budgetTree()})) {
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+	}
+
+	if want := []int{1, 2, 3, 4, 1}; !slices.Equal(ids, want) {
+		t.Errorf("items %v, want %v", ids, want)
+	}
+
+	want := responseTruncation{Truncated: true, TotalItems: 8, ReturnedItems: 5, OmittedItems: 3}
+	if got := roots.truncation(); got == nil || *got != want {
+		t.Errorf("truncation %v, want %v", got, want)
+	}
+
+	if got := newBudgetedRoots(responseBudget{MaxBytes: 0, MaxItems: 0}).truncation(); got != nil {
+		t.Errorf("truncation %v, want nil", got)
+	}
+}
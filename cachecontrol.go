@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultCacheControl lets a CDN cache the busiest responses for about as long as they are cached
+// in memory and keep serving them while it fetches a fresh copy. Single items change rarely and can
+// be revalidated with their ETag, so they are cached for longer.
+const defaultCacheControl = "/active=public, max-age=30, stale-while-revalidate=60;" +
+	"/item/:id/tree=public, max-age=60, stale-while-revalidate=300;" +
+	"/item/:id=public, max-age=300, stale-while-revalidate=86400"
+
+var errInvalidCacheControl = errors.New("--cache-control entries must be route=directives pairs")
+
+// parseCacheControl reads semicolon-separated route=directives pairs, such as
+// /active=public, max-age=30, into the Cache-Control value for each route.
+func parseCacheControl(s string) (map[string]string, error) {
+	policies := make(map[string]string)
+
+	for entry := range strings.SplitSeq(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, directives, ok := strings.Cut(entry, "=")
+		route = strings.TrimSpace(route)
+		directives = strings.TrimSpace(directives)
+
+		if !ok || !strings.HasPrefix(route, "/") || directives == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidCacheControl, entry)
+		}
+
+		policies[route] = directives
+	}
+
+	return policies, nil
+}
+
+// setCacheControl sets the configured Cache-Control header on GET and HEAD requests to each route,
+// matched by its pattern such as /item/:id/tree. Handlers can still replace it, as /active does
+// when it serves a stale snapshot, and respondError replaces it for server errors.
+func setCacheControl(live *liveConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		if policy, ok := live.Load().CacheControl[c.FullPath()]; ok {
+			c.Header("Cache-Control", policy)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		want map[string]string
+		err  error
+		name string
+		s    string
+	}{
+		{want: map[string]string{}, err: nil, name: "empty", s: ""},
+		{
+			want: map[string]string{
+				"/active":        "public, max-age=30, stale-while-revalidate=60",
+				"/item/:id/tree": "public, max-age=60, stale-while-revalidate=300",
+				"/item/:id":      "public, max-age=300, stale-while-revalidate=86400",
+			},
+			err:  nil,
+			name: "default",
+			s:    defaultCacheControl,
+		},
+		{want: map[string]string{"/active": "no-store"}, err: nil, name: "single", s: "/active=no-store"},
+		{
+			want: map[string]string{"/active": "public, max-age=30"},
+			err:  nil,
+			name: "spaces and empty entries",
+			s:    " ; /active = public, max-age=30 ;;",
+		},
+		{want: map[string]string{"/active": "no-cache"}, err: nil, name: "last wins", s: "/active=no-store;/active=no-cache"},
+		{want: nil, err: errInvalidCacheControl, name: "no directives", s: "/active"},
+		{want: nil, err: errInvalidCacheControl, name: "empty directives", s: "/active= "},
+		{want: nil, err: errInvalidCacheControl, name: "relative route", s: "active=no-store"},
+		{want: nil, err: errInvalidCacheControl, name: "one invalid entry", s: "/active=no-store;max-age=30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseCacheControl(tt.s)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error %v, want %v", err, tt.err)
+			}
+
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("policies %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// indexItems adds the titles and text of items to the full-text index, replacing what was indexed
+// for them before. Dead and deleted items are removed from the index.
+func (s *store) indexItems(ctx context.Context, items []*hn.Item) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin indexing: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	for _, item := range items {
+		_, err = tx.ExecContext(ctx, `DELETE FROM item_text WHERE rowid = ?`, item.ID)
+		if err != nil {
+			return fmt.Errorf("failed to remove item %d from index: %w", item.ID, err)
+		}
+
+		if item.Dead || item.Deleted || (item.Title == "" && item.Text == "") {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO item_text (rowid, title, text) VALUES (?, ?, ?)`,
+			item.ID, item.Title, convertText(item.Text, textModePlain))
+		if err != nil {
+			return fmt.Errorf("failed to index item %d: %w", item.ID, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit index: %w", err)
+	}
+
+	return nil
+}
+
+// searchItems returns the IDs of the indexed items matching an FTS5 query, best match first.
+func (s *store) searchItems(ctx context.Context, query string, limit int) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT rowid FROM item_text WHERE item_text MATCH ? ORDER BY rank LIMIT ?`, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	var ids []int
+
+	for rows.Next() {
+		var id int
+
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read search result: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search results: %w", err)
+	}
+
+	return ids, nil
+}
+
+// ftsQuery quotes each word of q so the index matches items containing all of them and FTS5
+// operators in user input are treated as text.
+func ftsQuery(q string) string {
+	words := strings.Fields(q)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+
+	return strings.Join(words, " ")
+}
+
+type handleCacheSearchResponse struct {
+	Items []handleItemDescendantsResponse `json:"items"`
+}
+
+// handleCacheSearch searches the titles and text of the items indexed from active snapshots and
+// returns them in the same shape as /search, best match first.
+//
+//nolint:cyclop // need parsing helper
+func handleCacheSearch(c *gin.Context, client *itemClient, st *store, formatter *textFormatter) {
+	ctx := c.Request.Context()
+
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	query := ftsQuery(c.Query("q"))
+	if query == "" {
+		respondParamError(c, codeInvalidQuery, "q", "missing q")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSearchLimit)))
+	if err != nil || limit < 1 || limit > maxSearchLimit {
+		respondParamError(c, codeInvalidLimit, "limit", "invalid limit")
+		return
+	}
+
+	var errs paramErrors
+
+	opts := parseStoryListOptions(c, &errs)
+
+	if errs.respond(c) {
+		return
+	}
+
+	ids, err := st.searchItems(ctx, query, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to search cached items")
+		return
+	}
+
+	found, err := fetchItems(ctx, client, ids)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve items")
+		return
+	}
+
+	items := make([]handleItemDescendantsResponse, 0, len(ids))
+
+	for _, id := range ids {
+		item, ok := found[id]
+		if ok && item != nil && !item.Dead && !item.Deleted {
+			items = append(items, searchItemResponse(item, formatter, opts))
+		}
+	}
+
+	respond(c, http.StatusOK, handleCacheSearchResponse{Items: items})
+}
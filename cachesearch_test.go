@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestFTSQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		q    string
+		want string
+	}{
+		{name: "empty", q: "", want: ""},
+		{name: "only spaces", q: " \t ", want: ""},
+		{name: "word", q: "rust", want: `"rust"`},
+		{name: "words", q: "rust  compiler\tspeed", want: `"rust" "compiler" "speed"`},
+		{name: "quoted phrase", q: `"rust compiler"`, want: `"""rust" "compiler"""`},
+		{name: "embedded quote", q: `it"s`, want: `"it""s"`},
+		{name: "lone quote", q: `"`, want: `""""`},
+		{name: "or", q: "rust OR go", want: `"rust" "OR" "go"`},
+		{name: "and not", q: "rust AND NOT go", want: `"rust" "AND" "NOT" "go"`},
+		{name: "near", q: "NEAR(rust go, 5)", want: `"NEAR(rust" "go," "5)"`},
+		{name: "column filter", q: "title:rust", want: `"title:rust"`},
+		{name: "column set", q: "{title text}:rust", want: `"{title" "text}:rust"`},
+		{name: "prefix", q: "rust*", want: `"rust*"`},
+		{name: "initial token", q: "^rust", want: `"^rust"`},
+		{name: "minus and plus", q: "-rust + go", want: `"-rust" "+" "go"`},
+		{name: "parentheses", q: "(rust)", want: `"(rust)"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ftsQuery(tt.q); got != tt.want {
+				t.Errorf("query %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
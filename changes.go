@@ -0,0 +1,101 @@
+package main
+
+import (
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// activeChangesTTL is how long a token from /active/changes can be used as since.
+const activeChangesTTL = 15 * time.Minute
+
+// activeState is whether each item of an /active/changes response was active, by item ID.
+type activeState map[int]bool
+
+type handleActiveChangesResponse struct {
+	Token              string                     `json:"token"`
+	Items              []handleActiveResponseItem `json:"items"`
+	Removed            []int                      `json:"removed"`
+	SecondChanceFailed bool                       `json:"secondChanceFailed"`
+}
+
+// handleActiveChanges responds with the /active items that were added or changed their active
+// state since the response identified by the since token, and the IDs of the items that are no
+// longer returned. Each item has the ID of its parent so it can be placed in the previous trees.
+// Without since every item is returned. The response includes a token for the next request.
+func handleActiveChanges(
+	c *gin.Context,
+	source *activeSource,
+	followed *followList,
+	formatter *textFormatter,
+	defaultBlock []string,
+	history *lruCache[string, activeState],
+) {
+	since, hasSince := c.GetQuery("since")
+
+	var previous activeState
+
+	if hasSince {
+		var ok bool
+
+		previous, ok = history.Get(since)
+		if !ok {
+			respondError(c, http.StatusGone, codeSinceExpired, "since token is unknown or expired")
+			return
+		}
+	}
+
+	snapshot, opts, ok := getActiveSnapshot(c, source, followed, defaultBlock, nil)
+	if !ok {
+		return
+	}
+
+	items := activeItems(snapshot, formatter, snapshot.now(), opts)
+	state := make(activeState, len(items))
+	changes := make([]handleActiveResponseItem, 0)
+
+	for _, item := range items {
+		state[item.ID] = item.Active
+
+		wasActive, seen := previous[item.ID]
+		if !seen || wasActive != item.Active {
+			changes = append(changes, item)
+		}
+	}
+
+	removed := make([]int, 0)
+
+	for id := range previous {
+		if _, ok := state[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+
+	slices.Sort(removed)
+
+	token := activeChangesToken(c, snapshot)
+	history.Put(token, state, activeChangesTTL)
+
+	respond(c, http.StatusOK, handleActiveChangesResponse{
+		Token:              token,
+		Items:              changes,
+		Removed:            removed,
+		SecondChanceFailed: snapshot.SecondChanceFailed,
+	})
+}
+
+// activeChangesToken identifies the items returned for a snapshot and the query parameters other
+// than since, so requests that would return the same items share a token.
+func activeChangesToken(c *gin.Context, snapshot *activeSnapshot) string {
+	query := c.Request.URL.Query()
+	query.Del("since")
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strconv.FormatInt(snapshot.Time.UnixNano(), 10) + "?" + query.Encode()))
+
+	return strconv.FormatUint(h.Sum64(), 36)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// chatNotifier posts to Slack and Discord incoming webhooks when a thread that entered the active
+// set matches a keyword or when an active thread gets busier than the configured comments per hour.
+type chatNotifier struct {
+	client *http.Client
+	live   *liveConfig
+	// busy holds the threads already reported as busy, so each is reported once until it cools
+	// down or leaves the active set
+	busy map[int]bool
+}
+
+// notifyChat posts the configured chat notifications for each new precomputed /active snapshot
+// until ctx is done. Nothing is posted while no Slack or Discord webhook URL is configured.
+func notifyChat(ctx context.Context, source *activeSource, live *liveConfig) {
+	n := &chatNotifier{client: &http.Client{Timeout: webhookTimeout}, live: live, busy: make(map[int]bool)}
+	watchActive(ctx, source, n.notify)
+}
+
+func (n *chatNotifier) notify(ctx context.Context, snapshot *activeSnapshot, entered []handleActiveRoot) {
+	cfg := n.live.Load()
+	if cfg.SlackWebhookURL == "" && cfg.DiscordWebhookURL == "" {
+		return
+	}
+
+	var messages []chatMessage
+
+	for _, root := range entered {
+		thread := newWebhookThread(snapshot, root)
+
+		keyword, ok := matchingKeyword(thread, cfg.NotifyKeywords)
+		if ok {
+			messages = append(messages, chatMessage{Thread: thread, Reason: "matched " + strconv.Quote(keyword)})
+		}
+	}
+
+	busy := make(map[int]bool)
+
+	for _, root := range snapshot.Roots {
+		if cfg.NotifyCommentsPerHour <= 0 {
+			break
+		}
+
+		thread := newWebhookThread(snapshot, root)
+		if thread.CommentsPerHour < cfg.NotifyCommentsPerHour {
+			continue
+		}
+
+		busy[thread.ID] = true
+
+		if !n.busy[thread.ID] {
+			reason := fmt.Sprintf("has %.0f comments in the last hour", thread.CommentsPerHour)
+			messages = append(messages, chatMessage{Thread: thread, Reason: reason})
+		}
+	}
+
+	n.busy = busy
+
+	for _, message := range messages {
+		n.post(ctx, cfg.SlackWebhookURL, slackPayload{Text: message.slack()})
+		n.post(ctx, cfg.DiscordWebhookURL, discordPayload{Content: message.discord()})
+	}
+}
+
+// post sends payload to a chat webhook, logging failures, or does nothing if rawURL is empty.
+func (n *chatNotifier) post(ctx context.Context, rawURL string, payload any) {
+	if rawURL == "" {
+		return
+	}
+
+	err := postJSON(ctx, n.client, rawURL, payload)
+	if err != nil {
+		log.Printf("failed to post chat notification: %v", err)
+	}
+}
+
+// postJSON posts v encoded as JSON and fails unless the response is a 2xx.
+func postJSON(ctx context.Context, client *http.Client, rawURL string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post: %w", err)
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w posting: %d", errUnexpectedStatus, resp.StatusCode)
+	}
+
+	return nil
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+type discordPayload struct {
+	Content string `json:"content"`
+}
+
+type chatMessage struct {
+	Reason string
+	Thread webhookThread
+}
+
+func (m chatMessage) summary() string {
+	return fmt.Sprintf("%s: %d active comments from %d commenters, %d points",
+		m.Reason, m.Thread.ActiveComments, m.Thread.ActiveCommenters, m.Thread.Score)
+}
+
+// slack formats the message as Slack mrkdwn, which needs &, <, and > escaped.
+func (m chatMessage) slack() string {
+	title := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(m.Thread.Title)
+	return fmt.Sprintf("*<%s|%s>* %s", m.Thread.HNURL, title, m.summary())
+}
+
+// discord formats the message as Discord markdown. The angle brackets keep Discord from embedding
+// a preview of the link.
+func (m chatMessage) discord() string {
+	title := strings.NewReplacer("[", "\\[", "]", "\\]", "*", "\\*", "_", "\\_").Replace(m.Thread.Title)
+	return fmt.Sprintf("**[%s](<%s>)** %s", title, m.Thread.HNURL, m.summary())
+}
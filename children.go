@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// maxChildrenDepth bounds the depth of /item/:id/children, which is meant for expanding a collapsed
+// subtree a little at a time; whole subtrees come from /item/:id/tree.
+const maxChildrenDepth = 3
+
+// parseChildrenDepth reads the depth query parameter, which is 1 by default for only the direct
+// children.
+func parseChildrenDepth(c *gin.Context) (int, bool) {
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", "1"))
+	return depth, err == nil && depth >= 1 && depth <= maxChildrenDepth
+}
+
+// getChildren returns the replies to item down to maxDepth levels below it in display order, with
+// depths relative to item. Replies that could not be fetched are left out along with theirs.
+func getChildren(ctx context.Context, client *itemClient, item *hn.Item, maxDepth int) ([]treeEntry, error) {
+	fetched, err := fetchChildLevels(ctx, client, item, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []treeEntry
+
+	var walk func(kids []int, depth int)
+
+	walk = func(kids []int, depth int) {
+		for _, id := range kids {
+			kid, ok := fetched[id]
+			if !ok {
+				continue
+			}
+
+			entries = append(entries, treeEntry{Item: kid, Depth: depth})
+
+			if depth < maxDepth {
+				walk(kid.Kids, depth+1)
+			}
+		}
+	}
+
+	walk(item.Kids, 1)
+
+	return entries, nil
+}
+
+// fetchChildLevels fetches the replies to item down to maxDepth levels below it, each level in one
+// batch.
+func fetchChildLevels(ctx context.Context, client *itemClient, item *hn.Item, maxDepth int) (hn.ItemSet, error) {
+	fetched := make(hn.ItemSet)
+	level := item.Kids
+
+	for depth := 1; depth <= maxDepth && len(level) > 0; depth++ {
+		items, err := fetchItems(ctx, client, level)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errUpstream, err)
+		}
+
+		var next []int
+
+		for _, id := range level {
+			kid, ok := items[id]
+			if !ok || kid == nil {
+				continue
+			}
+
+			fetched[id] = kid
+			next = append(next, kid.Kids...)
+		}
+
+		level = next
+	}
+
+	return fetched, nil
+}
+
+// childrenOptions are the query parameters of /item/:id/children.
+type childrenOptions struct {
+	Blocked  map[string]bool
+	Depth    int
+	Text     textMode
+	ShowUser bool
+	ShowDead bool
+}
+
+func parseChildrenOptions(c *gin.Context, errs *paramErrors, defaultBlock []string) childrenOptions {
+	depth, ok := parseChildrenDepth(c)
+	if !ok {
+		errs.add(codeInvalidDepth, "depth", fmt.Sprintf("depth must be from 1 to %d", maxChildrenDepth))
+	}
+
+	showUser, ok := parseShowUser(c)
+	if !ok {
+		errs.add(codeInvalidUser, "show-user", "invalid show-user")
+	}
+
+	showDead, ok := parseShowDead(c)
+	if !ok {
+		errs.add(codeInvalidShowDead, "show-dead", "invalid show-dead")
+	}
+
+	text, ok := parseTextMode(c)
+	if !ok {
+		errs.add(codeInvalidText, "text", "invalid text")
+	}
+
+	return childrenOptions{
+		Blocked:  parseBlocked(c, defaultBlock),
+		Depth:    depth,
+		Text:     text,
+		ShowUser: showUser,
+		ShowDead: showDead,
+	}
+}
+
+// handleItemChildren returns the replies to an item down to a small depth, formatted like
+// /item/:id/tree but without the item itself, so a client can expand a collapsed subtree without
+// fetching the whole thread again. Replies at the deepest level count their own replies in
+// truncatedChildren. OP is not set since the root story is not fetched.
+func handleItemChildren(c *gin.Context, client *itemClient, formatter *textFormatter, defaultBlock []string) {
+	var errs paramErrors
+
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errs.add(codeInvalidID, "id", "invalid id")
+	}
+
+	opts := parseChildrenOptions(c, &errs, defaultBlock)
+
+	if errs.respond(c) {
+		return
+	}
+
+	_, item, err := getItem(c.Request.Context(), client, itemID)
+	if err != nil {
+		respondItemError(c, err)
+		return
+	}
+
+	entries, err := getChildren(c.Request.Context(), client, item, opts.Depth)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve item children")
+		return
+	}
+
+	respond(c, http.StatusOK, childrenResponse(entries, formatter, opts))
+}
+
+// childrenResponse formats the visible entries of the replies returned by getChildren.
+func childrenResponse(
+	entries []treeEntry,
+	formatter *textFormatter,
+	opts childrenOptions,
+) []handleItemDescendantsResponse {
+	items := make([]*hn.Item, 0, len(entries))
+	depths := make([]int, 0, len(entries))
+
+	for _, e := range entries {
+		items = append(items, e.Item)
+		depths = append(depths, e.Depth)
+	}
+
+	visible, placeholders := visibleItems(items, depths, opts.ShowDead, opts.Blocked)
+	entries = keepOnly(entries, visible)
+
+	response := make([]handleItemDescendantsResponse, 0, len(entries))
+
+	for _, e := range entries {
+		by, text := e.Item.By, formatter.formatAs(e.Item, opts.Text)
+		if placeholders[e.Item.ID] {
+			by, text = "", ""
+		}
+
+		if !opts.ShowUser {
+			by = ""
+		}
+
+		truncated := 0
+		if e.Depth == opts.Depth {
+			truncated = len(e.Item.Kids)
+		}
+
+		response = append(response, handleItemDescendantsResponse{
+			storyMetadata:     nil,
+			By:                by,
+			Text:              text,
+			Children:          nil,
+			AccountAgeDays:    nil,
+			Karma:             nil,
+			Time:              e.Item.Time,
+			ID:                e.Item.ID,
+			Parent:            e.Item.Parent,
+			Root:              0,
+			Depth:             e.Depth,
+			TruncatedChildren: truncated,
+			OP:                false,
+			Dead:              e.Item.Dead,
+			Deleted:           e.Item.Deleted,
+			IsNew:             false,
+			NewUser:           false,
+		})
+	}
+
+	return response
+}
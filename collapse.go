@@ -0,0 +1,37 @@
+package main
+
+import "github.com/jasonthorsness/unlurker/hn"
+
+// activeSubtrees returns the IDs of the items of a flattened tree, in depth-first order with the
+// given depths, that have an active comment in their subtree, counting themselves.
+func activeSubtrees(items []*hn.Item, depths []int, isActive func(*hn.Item) bool) map[int]bool {
+	active := make(map[int]bool)
+
+	var ancestors []int
+
+	for i, item := range items {
+		for len(ancestors) > 0 && depths[ancestors[len(ancestors)-1]] >= depths[i] {
+			ancestors = ancestors[:len(ancestors)-1]
+		}
+
+		ancestors = append(ancestors, i)
+
+		if !isActive(item) {
+			continue
+		}
+
+		// once an ancestor is marked so are all of its own ancestors
+		for j := len(ancestors) - 1; j >= 0 && !active[items[ancestors[j]].ID]; j-- {
+			active[items[ancestors[j]].ID] = true
+		}
+	}
+
+	return active
+}
+
+// isCollapsed reports whether item heads a subtree with no active comments under a subtree that
+// has some, so a client can fold it by default the way HN folds downvoted threads.
+// Only the head is marked; the rest of the subtree is folded with it.
+func isCollapsed(item *hn.Item, root *hn.Item, active map[int]bool) bool {
+	return item.ID != root.ID && !active[item.ID] && (item.Parent == root.ID || active[item.Parent])
+}
@@ -0,0 +1,709 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	envPrefix = "UNLURKER_"
+
+	defaultCacheEntries   = 1000
+	defaultCacheBytes     = 256 << 20
+	defaultTextEntries    = 100_000
+	defaultTextBytes      = 64 << 20
+	defaultActiveCacheTTL = 30 * time.Second
+	defaultTreeCacheTTL   = 60 * time.Second
+	defaultLiveInterval   = 30 * time.Second
+	defaultRankInterval   = 5 * time.Minute
+	defaultMaxDuration    = 20 * time.Second
+	defaultReadyHNWindow  = 5 * time.Minute
+	defaultCORSMaxAge     = 10 * time.Minute
+	defaultHNConcurrency  = 32
+	defaultHNRate         = 100
+	defaultHNBurst        = 50
+	defaultHNRetries      = 2
+	defaultHNRetryBudget  = 20
+	defaultBreakerTrips   = 5
+	defaultBreakerPause   = 30 * time.Second
+	defaultDigestInterval = 24 * time.Hour
+	defaultDigestLimit    = 10
+	defaultFollowTTL      = 7 * 24 * time.Hour
+	defaultMaxFollows     = 1000
+	defaultUpdatesPoll    = 30 * time.Second
+	defaultIngestBatch    = 500
+	defaultActiveFreshFor = 30 * time.Second
+	defaultActiveStaleFor = 5 * time.Minute
+	defaultResponseItems  = 10_000
+	defaultMinWindow      = time.Minute
+	defaultMaxWindow      = 24 * time.Hour
+	defaultMaxMaxAge      = 7 * 24 * time.Hour
+	defaultMinMinBy       = 1
+	defaultMaxMinBy       = 50
+	defaultResponseBytes  = 16 << 20
+	defaultMaxBatchItems  = 100
+	defaultProfileTTL     = 24 * time.Hour
+	defaultProfileRate    = 5
+)
+
+var (
+	errTLSPair             = errors.New("--tls-cert and --tls-key must be set together")
+	errTLSWithAutocert     = errors.New("--autocert-domains cannot be combined with --tls-cert/--tls-key")
+	errInvalidListenArg    = errors.New("invalid listen address")
+	errInvalidCacheEntries = errors.New("--cache-entries and --text-cache-entries must be positive")
+	errInvalidCacheBytes   = errors.New("--cache-bytes and --text-cache-bytes must not be negative")
+	errInvalidLiveInterval = errors.New("--live-interval must be positive")
+	errNegativeDuration    = errors.New("durations must not be negative")
+	errInvalidHNLimits     = errors.New("--hn-concurrency and --hn-burst must be positive and --hn-rate not negative")
+	errInvalidHNRetries    = errors.New("--hn-retries and --hn-retry-budget must not be negative")
+	errInvalidBreaker      = errors.New("--breaker-threshold and --breaker-cooldown must be positive")
+	errInvalidGinMode      = errors.New("--gin-mode must be debug, release, or test")
+	errInvalidActiveParams = errors.New("--window and --max-age must be positive and --min-by not negative")
+	errInvalidDigest       = errors.New("--digest-interval must not be negative, --digest-limit must be positive, " +
+		"and --digest-from must be an email address when --smtp-addr is set")
+	errInvalidBounds = errors.New("--window-min, --window-max, --max-age-max, --min-by-min, and --min-by-max " +
+		"must be ordered ranges containing --window, --max-age, and --min-by")
+	errInvalidFreshness    = errors.New("--active-fresh-for and --active-stale-for must not be negative")
+	errInvalidIngestBatch  = errors.New("--ingest-batch must be positive")
+	errInvalidBudget       = errors.New("--max-response-items and --max-response-bytes must not be negative")
+	errInvalidFollows      = errors.New("--follow-ttl and --max-follows must be positive")
+	errInvalidBatchItems   = errors.New("--max-batch-items must be positive")
+	errInvalidProfiles     = errors.New("--profile-ttl must be positive and --profile-rate not negative")
+	errInvalidSanitizeAttr = errors.New("--sanitize-attrs entries must be element:attribute pairs")
+	errInvalidHNCache      = errors.New("--hn-cache must be a file path")
+)
+
+type config struct {
+	Addr                  string
+	AdminToken            string
+	OTLPEndpoint          string
+	SentryDSN             string
+	SlackWebhookURL       string
+	DiscordWebhookURL     string
+	SMTPAddr              string
+	SMTPUsername          string
+	SMTPPassword          string
+	DigestFrom            string
+	GinMode               string
+	TLSCert               string
+	TLSKey                string
+	AutocertCacheDir      string
+	HNCache               string
+	StorePath             string
+	CacheControl          map[string]string
+	Effective             map[string]string
+	AutocertDomains       []string
+	Block                 []string
+	SanitizeTags          []string
+	SanitizeAttrs         []string
+	SanitizeSchemes       []string
+	CORSOrigins           []string
+	CORSMethods           []string
+	CORSHeaders           []string
+	NotifyKeywords        []string
+	PrecomputeInterval    time.Duration
+	ShutdownTimeout       time.Duration
+	ReadHeaderTimeout     time.Duration
+	ActiveCacheTTL        time.Duration
+	TreeCacheTTL          time.Duration
+	LiveInterval          time.Duration
+	Window                time.Duration
+	MaxAge                time.Duration
+	RankInterval          time.Duration
+	BreakerCooldown       time.Duration
+	MaxRequestDuration    time.Duration
+	ReadyHNWindow         time.Duration
+	CORSMaxAge            time.Duration
+	DigestInterval        time.Duration
+	FollowTTL             time.Duration
+	ProfileTTL            time.Duration
+	UpdatesInterval       time.Duration
+	IngestInterval        time.Duration
+	ActiveFreshFor        time.Duration
+	ActiveStaleFor        time.Duration
+	MinWindow             time.Duration
+	MaxWindow             time.Duration
+	MaxMaxAge             time.Duration
+	Port                  int
+	GRPCPort              int
+	MinBy                 int
+	MinMinBy              int
+	MaxMinBy              int
+	LogLevel              slog.Level
+	CacheEntries          int
+	TextCacheEntries      int
+	HNConcurrency         int
+	BreakerThreshold      int
+	HNBurst               int
+	HNRetries             int
+	HNRetryBudget         int
+	DigestLimit           int
+	MaxFollows            int
+	IngestBatch           int
+	MaxResponseItems      int
+	MaxBatchItems         int
+	CacheBytes            int64
+	MaxResponseBytes      int64
+	TextCacheBytes        int64
+	HNRate                float64
+	NotifyCommentsPerHour float64
+	ProfileRate           float64
+	Docs                  bool
+}
+
+// loadConfig reads the configuration from command-line flags. Every flag can also be set with an
+// environment variable named after it, for example UNLURKER_TLS_CERT for --tls-cert, or in the
+// file named by --config; flags win over the environment, which wins over the file.
+func loadConfig(args []string) (config, error) {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	defaults := defaultActiveParams()
+
+	configPath := fs.String("config", "", "path to a YAML file setting any of these flags by name; "+
+		"reloaded on SIGHUP")
+
+	addr := fs.String("addr", "", "host or IP address to listen on; empty listens on all interfaces")
+	port := fs.Int("port", defaultPort(), "port to listen on")
+	grpcPort := fs.Int("grpc-port", 0, "port to serve the gRPC API on; 0 disables")
+	tlsCert := fs.String("tls-cert", "", "path to a PEM certificate for serving HTTPS")
+	tlsKey := fs.String("tls-key", "", "path to the PEM private key for --tls-cert")
+	autocertDomains := fs.String("autocert-domains", "",
+		"comma-separated domains to obtain Let's Encrypt certificates for; enables HTTPS")
+	autocertCacheDir := fs.String("autocert-cache", filepath.Join(os.TempDir(), "autocert"),
+		"directory for caching Let's Encrypt certificates")
+	precomputeInterval := fs.Duration("precompute-interval", time.Minute,
+		"interval for recomputing the default /active snapshot; 0 disables")
+	shutdownTimeout := fs.Duration("shutdown-timeout", defaultShutdownTimeout,
+		"maximum time to wait for in-flight requests to finish when shutting down")
+	readHeaderTimeout := fs.Duration("read-header-timeout", defaultReadHeaderTimeout,
+		"maximum time to read the headers of a request")
+	logLevel := fs.String("log-level", "info", "minimum level of logged messages: debug, info, warn, or error")
+	ginMode := fs.String("gin-mode", "", "gin mode, one of debug, release, or test; empty uses GIN_MODE")
+	maxRequestDuration := fs.Duration("max-request-duration", defaultMaxDuration,
+		"time after which a request is canceled with a 504; also caps the timeout query parameter; 0 disables")
+	readyHNWindow := fs.Duration("ready-hn-window", defaultReadyHNWindow,
+		"/readyz fails if no request to the HN API has succeeded within this long")
+	window := fs.Duration("window", defaults.Window,
+		"default window query parameter, and the window of the precomputed /active snapshot")
+	maxAge := fs.Duration("max-age", defaults.MaxAge,
+		"default max-age query parameter, and the max-age of the precomputed /active snapshot")
+	minBy := fs.Int("min-by", defaults.MinBy,
+		"default min-by query parameter, and the min-by of the precomputed /active snapshot")
+	minWindow := fs.Duration("window-min", defaultMinWindow, "smallest window query parameter allowed")
+	maxWindow := fs.Duration("window-max", defaultMaxWindow, "largest window query parameter allowed")
+	maxMaxAge := fs.Duration("max-age-max", defaultMaxMaxAge, "largest max-age query parameter allowed")
+	minMinBy := fs.Int("min-by-min", defaultMinMinBy, "smallest min-by query parameter allowed")
+	maxMinBy := fs.Int("min-by-max", defaultMaxMinBy, "largest min-by query parameter allowed")
+	activeFreshFor := fs.Duration("active-fresh-for", defaultActiveFreshFor,
+		"how long an /active snapshot computed for non-default parameters is reused; 0 computes each one")
+	activeStaleFor := fs.Duration("active-stale-for", defaultActiveStaleFor,
+		"how long after --active-fresh-for an /active snapshot is served marked stale while a new one is "+
+			"computed in the background")
+	maxResponseItems := fs.Int("max-response-items", defaultResponseItems,
+		"maximum items in an /active response, also applied to gRPC Active and GraphQL trees; the "+
+			"lowest-activity comments are left out beyond it; 0 is unlimited")
+	maxResponseBytes := fs.Int64("max-response-bytes", defaultResponseBytes,
+		"approximate maximum size of an /active response, also applied to gRPC Active and GraphQL trees; the "+
+			"lowest-activity comments are left out beyond it; 0 is unlimited")
+	liveInterval := fs.Duration("live-interval", defaultLiveInterval,
+		"how often /item/:id/live and follows send what changed in the followed tree")
+	updatesInterval := fs.Duration("updates-interval", defaultUpdatesPoll,
+		"how often the HN updates list is polled to fetch changed items again, drop cached responses and refresh "+
+			"/active early; 0 disables")
+	ingestInterval := fs.Duration("ingest-interval", 0,
+		"how often maxitem of the HN API is polled to fetch new items as they are created; 0 disables")
+	ingestBatch := fs.Int("ingest-batch", defaultIngestBatch,
+		"maximum items fetched per maxitem poll; ingestion skips ahead when it falls further behind")
+	followTTL := fs.Duration("follow-ttl", defaultFollowTTL, "how long a follow created with /item/:id/follow lasts")
+	maxFollows := fs.Int("max-follows", defaultMaxFollows, "maximum number of follows at once")
+	profileTTL := fs.Duration("profile-ttl", defaultProfileTTL,
+		"how long commenter profiles fetched for account ages are cached")
+	profileRate := fs.Float64("profile-rate", defaultProfileRate,
+		"maximum commenter profiles fetched per second in the background; 0 is unlimited")
+	maxBatchItems := fs.Int("max-batch-items", defaultMaxBatchItems, "maximum number of IDs in a POST /items request")
+	rankInterval := fs.Duration("rank-interval", defaultRankInterval,
+		"how often front-page ranks are recorded in the store for /item/:id/rank-history; 0 disables")
+	sanitizeTags := fs.String("sanitize-tags", defaultSanitizeTags,
+		"comma-separated HTML elements allowed in comment text; others are stripped")
+	sanitizeAttrs := fs.String("sanitize-attrs", defaultSanitizeAttrs,
+		"comma-separated element:attribute pairs allowed in comment text; * as the element allows it on all")
+	sanitizeSchemes := fs.String("sanitize-schemes", defaultSanitizeSchemes,
+		"comma-separated URL schemes allowed in comment links")
+	block := fs.String("block", "", "comma-separated authors whose stories and comments are removed unless "+
+		"a request sets block itself")
+	hnCache := fs.String("hn-cache", filepath.Join(os.TempDir(), "hn.db"),
+		"path to the SQLite database the HN client caches items in")
+	hnConcurrency := fs.Int("hn-concurrency", defaultHNConcurrency,
+		"maximum number of simultaneous requests to the HN API")
+	hnRate := fs.Float64("hn-rate", defaultHNRate, "maximum requests per second to the HN API; 0 is unlimited")
+	hnBurst := fs.Int("hn-burst", defaultHNBurst, "number of requests to the HN API allowed at once above --hn-rate")
+	hnRetries := fs.Int("hn-retries", defaultHNRetries,
+		"times a request to the HN API failing with a network error, 429, or 5xx is retried")
+	hnRetryBudget := fs.Int("hn-retry-budget", defaultHNRetryBudget,
+		"maximum retries of requests to the HN API made while serving one request")
+	breakerThreshold := fs.Int("breaker-threshold", defaultBreakerTrips,
+		"consecutive HN API failures after which the last good /active is served without retrying")
+	breakerCooldown := fs.Duration("breaker-cooldown", defaultBreakerPause,
+		"how long to serve the last good /active before retrying the HN API after it failed")
+	storePath := fs.String("store", filepath.Join(os.TempDir(), "unls.db"),
+		"path to the SQLite database for search, history, and stats of active items; empty disables")
+	otlpEndpoint := fs.String("otlp-endpoint", "",
+		"URL of an OTLP/HTTP collector to export traces to, such as http://localhost:4318; empty disables")
+	sentryDSN := fs.String("sentry-dsn", "", "Sentry DSN to report panics to; empty only logs them")
+	slackWebhookURL := fs.String("slack-webhook-url", "", "Slack incoming webhook URL to post notifications to")
+	discordWebhookURL := fs.String("discord-webhook-url", "", "Discord webhook URL to post notifications to")
+	notifyKeywords := fs.String("notify-keywords", "",
+		"comma-separated keywords; a chat notification is posted when a thread entering /active has one in "+
+			"its title or URL")
+	notifyCommentsPerHour := fs.Float64("notify-comments-per-hour", 0,
+		"a chat notification is posted when an active thread gets at least this many comments in an hour; "+
+			"0 disables")
+	smtpAddr := fs.String("smtp-addr", "", "host:port of the SMTP server to send email digests through; empty "+
+		"disables them")
+	smtpUsername := fs.String("smtp-username", "", "username for SMTP authentication; empty sends without it")
+	smtpPassword := fs.String("smtp-password", "", "password for SMTP authentication")
+	digestFrom := fs.String("digest-from", "", "From address of email digests")
+	digestInterval := fs.Duration("digest-interval", defaultDigestInterval,
+		"how often each digest recipient is sent the top active threads of the period; 0 disables")
+	digestLimit := fs.Int("digest-limit", defaultDigestLimit,
+		"maximum threads in an email digest unless the recipient sets a limit")
+	adminToken := fs.String("admin-token", "",
+		"bearer token for the /admin endpoints; empty disables them")
+	corsOrigins := fs.String("cors-origins", "",
+		"comma-separated origins allowed to call the API from browsers, * for any or *.example.com for "+
+			"subdomains; empty disables CORS")
+	corsMethods := fs.String("cors-methods", defaultCORSMethods, "comma-separated methods allowed in CORS requests")
+	corsHeaders := fs.String("cors-headers", defaultCORSHeaders,
+		"comma-separated request headers allowed in CORS requests")
+	corsMaxAge := fs.Duration("cors-max-age", defaultCORSMaxAge, "how long browsers may cache CORS preflight responses")
+	docs := fs.Bool("docs", true, "serve Swagger UI for /openapi.json at /docs")
+	cacheEntries := fs.Int("cache-entries", defaultCacheEntries, "maximum number of cached responses")
+	cacheBytes := fs.Int64("cache-bytes", defaultCacheBytes, "maximum total size of cached responses; 0 is unlimited")
+	textCacheEntries := fs.Int("text-cache-entries", defaultTextEntries,
+		"maximum number of formatted comment texts kept in memory")
+	textCacheBytes := fs.Int64("text-cache-bytes", defaultTextBytes,
+		"maximum total size of formatted comment texts kept in memory; 0 is unlimited")
+	activeCacheTTL := fs.Duration("active-cache-ttl", defaultActiveCacheTTL,
+		"how long /active and other story list responses are cached; 0 disables")
+	treeCacheTTL := fs.Duration("tree-cache-ttl", defaultTreeCacheTTL,
+		"how long /item and /user responses are cached; 0 disables")
+	cacheControl := fs.String("cache-control", defaultCacheControl,
+		"semicolon-separated route=directives pairs setting the Cache-Control header of GET responses, "+
+			"such as /active=public, max-age=30")
+
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return config{}, fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	set := make(map[string]bool)
+
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	err = applyEnv(fs, set)
+	if err != nil {
+		return config{}, err
+	}
+
+	err = applyConfigFile(fs, *configPath, set)
+	if err != nil {
+		return config{}, err
+	}
+
+	cacheControlPolicies, err := parseCacheControl(*cacheControl)
+	if err != nil {
+		return config{}, err
+	}
+
+	var level slog.Level
+
+	err = level.UnmarshalText([]byte(*logLevel))
+	if err != nil {
+		return config{}, fmt.Errorf("invalid --log-level: %w", err)
+	}
+
+	cfg := config{
+		Addr:                  *addr,
+		AdminToken:            *adminToken,
+		OTLPEndpoint:          *otlpEndpoint,
+		SentryDSN:             *sentryDSN,
+		SlackWebhookURL:       *slackWebhookURL,
+		DiscordWebhookURL:     *discordWebhookURL,
+		SMTPAddr:              *smtpAddr,
+		SMTPUsername:          *smtpUsername,
+		SMTPPassword:          *smtpPassword,
+		DigestFrom:            *digestFrom,
+		GinMode:               *ginMode,
+		TLSCert:               *tlsCert,
+		TLSKey:                *tlsKey,
+		AutocertCacheDir:      *autocertCacheDir,
+		HNCache:               *hnCache,
+		StorePath:             *storePath,
+		CacheControl:          cacheControlPolicies,
+		Effective:             effectiveSettings(fs),
+		AutocertDomains:       splitList(*autocertDomains),
+		Block:                 splitList(*block),
+		SanitizeTags:          splitList(*sanitizeTags),
+		SanitizeAttrs:         splitList(*sanitizeAttrs),
+		SanitizeSchemes:       splitList(*sanitizeSchemes),
+		CORSOrigins:           splitList(*corsOrigins),
+		CORSMethods:           splitList(*corsMethods),
+		CORSHeaders:           splitList(*corsHeaders),
+		NotifyKeywords:        normalizeTerms(splitList(*notifyKeywords), ""),
+		PrecomputeInterval:    *precomputeInterval,
+		ShutdownTimeout:       *shutdownTimeout,
+		ReadHeaderTimeout:     *readHeaderTimeout,
+		ActiveCacheTTL:        *activeCacheTTL,
+		TreeCacheTTL:          *treeCacheTTL,
+		LiveInterval:          *liveInterval,
+		Window:                *window,
+		MaxAge:                *maxAge,
+		RankInterval:          *rankInterval,
+		BreakerCooldown:       *breakerCooldown,
+		MaxRequestDuration:    *maxRequestDuration,
+		ReadyHNWindow:         *readyHNWindow,
+		CORSMaxAge:            *corsMaxAge,
+		DigestInterval:        *digestInterval,
+		FollowTTL:             *followTTL,
+		ProfileTTL:            *profileTTL,
+		UpdatesInterval:       *updatesInterval,
+		IngestInterval:        *ingestInterval,
+		ActiveFreshFor:        *activeFreshFor,
+		ActiveStaleFor:        *activeStaleFor,
+		MinWindow:             *minWindow,
+		MaxWindow:             *maxWindow,
+		MaxMaxAge:             *maxMaxAge,
+		Port:                  *port,
+		GRPCPort:              *grpcPort,
+		MinBy:                 *minBy,
+		MinMinBy:              *minMinBy,
+		MaxMinBy:              *maxMinBy,
+		LogLevel:              level,
+		CacheEntries:          *cacheEntries,
+		TextCacheEntries:      *textCacheEntries,
+		HNConcurrency:         *hnConcurrency,
+		BreakerThreshold:      *breakerThreshold,
+		HNBurst:               *hnBurst,
+		HNRetries:             *hnRetries,
+		HNRetryBudget:         *hnRetryBudget,
+		DigestLimit:           *digestLimit,
+		MaxFollows:            *maxFollows,
+		IngestBatch:           *ingestBatch,
+		MaxResponseItems:      *maxResponseItems,
+		MaxBatchItems:         *maxBatchItems,
+		MaxResponseBytes:      *maxResponseBytes,
+		HNRate:                *hnRate,
+		NotifyCommentsPerHour: *notifyCommentsPerHour,
+		ProfileRate:           *profileRate,
+		CacheBytes:            *cacheBytes,
+		TextCacheBytes:        *textCacheBytes,
+		Docs:                  *docs,
+	}
+
+	return cfg, cfg.validate()
+}
+
+func (cfg config) validate() error {
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return errTLSPair
+	}
+
+	if len(cfg.AutocertDomains) > 0 && cfg.TLSCert != "" {
+		return errTLSWithAutocert
+	}
+
+	if cfg.Port < 0 || cfg.Port > 65535 {
+		return fmt.Errorf("%w: port %d", errInvalidListenArg, cfg.Port)
+	}
+
+	if cfg.GRPCPort < 0 || cfg.GRPCPort > 65535 {
+		return fmt.Errorf("%w: grpc-port %d", errInvalidListenArg, cfg.GRPCPort)
+	}
+
+	if cfg.LiveInterval <= 0 {
+		return errInvalidLiveInterval
+	}
+
+	return errors.Join(cfg.validateActiveParams(), cfg.validateCacheLimits(), cfg.validateHNLimits(),
+		validateHNCache(cfg.HNCache), validateSanitizeAttrs(cfg.SanitizeAttrs), validateGinMode(cfg.GinMode),
+		cfg.validateDigest(), cfg.validateFollows(), cfg.validateIngest(), cfg.validateResponseBudget(),
+		cfg.validateBatchItems(), cfg.validateProfiles(), cfg.validateDurations())
+}
+
+// validateDurations rejects negative durations, which flag parsing accepts. Zero disables the
+// settings that allow it; the ones that must be positive are checked with the settings they go with.
+func (cfg config) validateDurations() error {
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"precompute-interval", cfg.PrecomputeInterval},
+		{"shutdown-timeout", cfg.ShutdownTimeout},
+		{"read-header-timeout", cfg.ReadHeaderTimeout},
+		{"active-cache-ttl", cfg.ActiveCacheTTL},
+		{"tree-cache-ttl", cfg.TreeCacheTTL},
+		{"rank-interval", cfg.RankInterval},
+		{"max-request-duration", cfg.MaxRequestDuration},
+		{"ready-hn-window", cfg.ReadyHNWindow},
+		{"cors-max-age", cfg.CORSMaxAge},
+		{"updates-interval", cfg.UpdatesInterval},
+		{"ingest-interval", cfg.IngestInterval},
+	}
+
+	for _, d := range durations {
+		if d.value < 0 {
+			return fmt.Errorf("%w: --%s %v", errNegativeDuration, d.name, d.value)
+		}
+	}
+
+	return nil
+}
+
+// validateResponseBudget checks that the /active response limits are unlimited or positive.
+func (cfg config) validateResponseBudget() error {
+	if cfg.MaxResponseItems < 0 || cfg.MaxResponseBytes < 0 {
+		return fmt.Errorf("%w: %d, %d", errInvalidBudget, cfg.MaxResponseItems, cfg.MaxResponseBytes)
+	}
+
+	return nil
+}
+
+// validateBatchItems checks that POST /items accepts at least one ID.
+func (cfg config) validateBatchItems() error {
+	if cfg.MaxBatchItems < 1 {
+		return fmt.Errorf("%w: %d", errInvalidBatchItems, cfg.MaxBatchItems)
+	}
+
+	return nil
+}
+
+// validateProfiles checks that fetched profiles are kept and can be fetched.
+func (cfg config) validateProfiles() error {
+	if cfg.ProfileTTL <= 0 || cfg.ProfileRate < 0 {
+		return fmt.Errorf("%w: %v, %g", errInvalidProfiles, cfg.ProfileTTL, cfg.ProfileRate)
+	}
+
+	return nil
+}
+
+// validateIngest checks that ingesting new items makes progress.
+func (cfg config) validateIngest() error {
+	if cfg.IngestBatch < 1 {
+		return fmt.Errorf("%w: %d", errInvalidIngestBatch, cfg.IngestBatch)
+	}
+
+	return nil
+}
+
+// validateFollows checks that follows last at all and can be created.
+func (cfg config) validateFollows() error {
+	if cfg.FollowTTL <= 0 || cfg.MaxFollows < 1 {
+		return fmt.Errorf("%w: %v, %d", errInvalidFollows, cfg.FollowTTL, cfg.MaxFollows)
+	}
+
+	return nil
+}
+
+// validateDigest checks that email digests have a schedule and a sender.
+func (cfg config) validateDigest() error {
+	if cfg.DigestInterval < 0 || cfg.DigestLimit < 1 {
+		return fmt.Errorf("%w: %v, %d", errInvalidDigest, cfg.DigestInterval, cfg.DigestLimit)
+	}
+
+	if cfg.SMTPAddr == "" {
+		return nil
+	}
+
+	_, err := mail.ParseAddress(cfg.DigestFrom)
+	if err != nil {
+		return fmt.Errorf("%w: %q", errInvalidDigest, cfg.DigestFrom)
+	}
+
+	return nil
+}
+
+// validateGinMode checks the mode before gin.SetMode, which panics on an unknown one.
+func validateGinMode(mode string) error {
+	if mode != "" && !slices.Contains([]string{gin.DebugMode, gin.ReleaseMode, gin.TestMode}, mode) {
+		return fmt.Errorf("%w: %q", errInvalidGinMode, mode)
+	}
+
+	return nil
+}
+
+// validateActiveParams checks that the defaults of /active select stories at all and are within the
+// bounds of the query parameters, and that reusing snapshots is not configured with negative
+// durations.
+func (cfg config) validateActiveParams() error {
+	if cfg.Window <= 0 || cfg.MaxAge <= 0 || cfg.MinBy < 0 {
+		return fmt.Errorf("%w: %v, %v, %d", errInvalidActiveParams, cfg.Window, cfg.MaxAge, cfg.MinBy)
+	}
+
+	if cfg.MinWindow <= 0 || cfg.MinMinBy < 0 || cfg.activeBounds().check(cfg.activeParams()) != nil {
+		return fmt.Errorf("%w: %v-%v, %v, %d-%d", errInvalidBounds, cfg.MinWindow, cfg.MaxWindow, cfg.MaxMaxAge,
+			cfg.MinMinBy, cfg.MaxMinBy)
+	}
+
+	if cfg.ActiveFreshFor < 0 || cfg.ActiveStaleFor < 0 {
+		return fmt.Errorf("%w: %v, %v", errInvalidFreshness, cfg.ActiveFreshFor, cfg.ActiveStaleFor)
+	}
+
+	return nil
+}
+
+// validateHNLimits checks that requests to the HN API can be made at all, that retries of them are
+// bounded, and that the circuit breaker around them can open and close.
+func (cfg config) validateHNLimits() error {
+	if cfg.HNConcurrency < 1 || cfg.HNBurst < 1 || cfg.HNRate < 0 {
+		return fmt.Errorf("%w: %d, %d, %g", errInvalidHNLimits, cfg.HNConcurrency, cfg.HNBurst, cfg.HNRate)
+	}
+
+	if cfg.HNRetries < 0 || cfg.HNRetryBudget < 0 {
+		return fmt.Errorf("%w: %d, %d", errInvalidHNRetries, cfg.HNRetries, cfg.HNRetryBudget)
+	}
+
+	if cfg.BreakerThreshold < 1 || cfg.BreakerCooldown <= 0 {
+		return fmt.Errorf("%w: %d, %v", errInvalidBreaker, cfg.BreakerThreshold, cfg.BreakerCooldown)
+	}
+
+	return nil
+}
+
+// validateCacheLimits checks that the in-memory caches can hold at least one entry.
+func (cfg config) validateCacheLimits() error {
+	if cfg.CacheEntries < 1 || cfg.TextCacheEntries < 1 {
+		return fmt.Errorf("%w: %d, %d", errInvalidCacheEntries, cfg.CacheEntries, cfg.TextCacheEntries)
+	}
+
+	if cfg.CacheBytes < 0 || cfg.TextCacheBytes < 0 {
+		return fmt.Errorf("%w: %d, %d", errInvalidCacheBytes, cfg.CacheBytes, cfg.TextCacheBytes)
+	}
+
+	return nil
+}
+
+// validateHNCache rejects an empty path and URLs such as redis:// since the HN client can only
+// cache in a SQLite file.
+func validateHNCache(hnCache string) error {
+	if hnCache == "" || strings.Contains(hnCache, "://") {
+		return fmt.Errorf("%w: %q", errInvalidHNCache, hnCache)
+	}
+
+	return nil
+}
+
+// defaultPort keeps honoring the PORT variable that gin's Run used before the port was configurable.
+func defaultPort() int {
+	const fallback = 8080
+
+	port, ok := os.LookupEnv("PORT")
+	if !ok {
+		return fallback
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return fallback
+	}
+
+	return p
+}
+
+// applyEnv sets each flag not already in set that has a corresponding UNLURKER_* environment
+// variable, and adds it to set. UNLURKER_* variables matching no flag are logged since they are
+// most likely misspelled.
+func applyEnv(fs *flag.FlagSet, set map[string]bool) error {
+	var err error
+
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		flagName, ok := strings.CutPrefix(name, envPrefix)
+
+		if ok && fs.Lookup(strings.ToLower(strings.ReplaceAll(flagName, "_", "-"))) == nil {
+			log.Printf("ignoring %s, which matches no setting", name)
+		}
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+
+		value, ok := os.LookupEnv(name)
+		if !ok || set[f.Name] || err != nil {
+			return
+		}
+
+		serr := fs.Set(f.Name, value)
+		if serr != nil {
+			err = fmt.Errorf("invalid %s: %w", name, serr)
+		}
+
+		set[f.Name] = true
+	})
+
+	return err
+}
+
+// effectiveSettings returns the value of every flag after the environment and config file were
+// applied, with secrets redacted so the settings can be logged.
+func effectiveSettings(fs *flag.FlagSet) map[string]string {
+	settings := make(map[string]string)
+
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if value != "" && slices.Contains([]string{
+			"admin-token", "sentry-dsn", "slack-webhook-url", "discord-webhook-url", "smtp-password",
+		}, f.Name) {
+			value = "[redacted]"
+		}
+
+		settings[f.Name] = value
+	})
+
+	return settings
+}
+
+// activeParams returns the default parameters of /active, which are also the parameters of the
+// precomputed snapshot.
+func (cfg config) activeParams() activeParams {
+	return activeParams{Window: cfg.Window, MaxAge: cfg.MaxAge, MinBy: cfg.MinBy}
+}
+
+// activeBounds returns the ranges the /active query parameters must be in.
+func (cfg config) activeBounds() activeBounds {
+	return activeBounds{
+		MinWindow: cfg.MinWindow,
+		MaxWindow: cfg.MaxWindow,
+		MaxMaxAge: cfg.MaxMaxAge,
+		MinMinBy:  cfg.MinMinBy,
+		MaxMinBy:  cfg.MaxMinBy,
+	}
+}
+
+// responseBudget returns the limits on the size of /active responses.
+func (cfg config) responseBudget() responseBudget {
+	return responseBudget{MaxBytes: cfg.MaxResponseBytes, MaxItems: cfg.MaxResponseItems}
+}
+
+func splitList(s string) []string {
+	var result []string
+
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	errUnknownConfigKey   = errors.New("unknown setting")
+	errInvalidConfigValue = errors.New("unsupported value")
+)
+
+// applyConfigFile sets each flag not already in set to the value of the key of the same name in
+// the YAML file at path, such as cors-origins for --cors-origins. Nothing is read if path is empty.
+func applyConfigFile(fs *flag.FlagSet, path string, set map[string]bool) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var settings map[string]any
+
+	err = yaml.Unmarshal(data, &settings)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	for name, v := range settings {
+		if fs.Lookup(name) == nil || name == "config" {
+			return fmt.Errorf("%w in config file %s: %s", errUnknownConfigKey, path, name)
+		}
+
+		if set[name] {
+			continue
+		}
+
+		value, err := configValue(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s in config file %s: %w", name, path, err)
+		}
+
+		err = fs.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("invalid %s in config file %s: %w", name, path, err)
+		}
+	}
+
+	return nil
+}
+
+// configValue formats a YAML or JSON value the way the flag takes it on the command line. Lists
+// become comma-separated and mappings become semicolon-separated key=value pairs, as
+// --cache-control takes them.
+func configValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case []any:
+		return configList(v)
+	case map[string]any:
+		return configMapping(v)
+	default:
+		return "", fmt.Errorf("%w: %v", errInvalidConfigValue, v)
+	}
+}
+
+func configList(v []any) (string, error) {
+	parts := make([]string, 0, len(v))
+
+	for _, e := range v {
+		part, err := configValue(e)
+		if err != nil {
+			return "", err
+		}
+
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, ","), nil
+}
+
+func configMapping(v map[string]any) (string, error) {
+	parts := make([]string, 0, len(v))
+
+	for _, k := range slices.Sorted(maps.Keys(v)) {
+		part, err := configValue(v[k])
+		if err != nil {
+			return "", err
+		}
+
+		parts = append(parts, k+"="+part)
+	}
+
+	return strings.Join(parts, ";"), nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// minConversationLength is the number of replies in a chain, alternating between two authors,
+// that makes it a conversation: one author, the other, and the first again.
+const minConversationLength = 3
+
+// onlyFilter is which comments the only query parameter keeps, along with their ancestors.
+type onlyFilter string
+
+const (
+	onlyAll           onlyFilter = ""
+	onlyConversations onlyFilter = "conversations"
+	onlyFollowed      onlyFilter = "followed"
+)
+
+// parseOnly reads the only query parameter, which is either absent, "conversations", or
+// "followed".
+func parseOnly(c *gin.Context) (onlyFilter, bool) {
+	param, ok := c.GetQuery("only")
+	if !ok {
+		return onlyAll, true
+	}
+
+	switch only := onlyFilter(param); only {
+	case onlyConversations, onlyFollowed:
+		return only, true
+	default:
+		return onlyAll, false
+	}
+}
+
+// conversationIDs finds the back-and-forth exchanges in a flattened tree with the given items and
+// depths: chains of replies below depth 0 whose authors alternate between two people for at least
+// minConversationLength replies. It returns the ID of the first reply of the conversation of each
+// entry, or 0 for entries that are not part of one.
+func conversationIDs(items []*hn.Item, depths []int) []int {
+	parent := parentIndexes(depths)
+	length, start := alternatingChains(items, parent)
+	conversations := make([]int, len(items))
+
+	for i := range items {
+		switch {
+		case length[i] == minConversationLength:
+			for j := i; j != parent[start[i]]; j = parent[j] {
+				if conversations[j] == 0 {
+					conversations[j] = items[start[i]].ID
+				}
+			}
+		case length[i] > minConversationLength && conversations[i] == 0:
+			conversations[i] = items[start[i]].ID
+		}
+	}
+
+	return conversations
+}
+
+// alternatingChains returns, for each entry, the length of the longest chain of replies ending
+// with it whose authors alternate between two people, and the index of the first entry of that
+// chain. Replies to items at depth 0 only start chains, since those items are stories.
+func alternatingChains(items []*hn.Item, parent []int) ([]int, []int) {
+	length := make([]int, len(items))
+	start := make([]int, len(items))
+
+	for i, item := range items {
+		length[i], start[i] = 1, i
+
+		p := parent[i]
+		if p < 0 || parent[p] < 0 || item.By == "" || item.By == items[p].By {
+			continue
+		}
+
+		length[i], start[i] = 2, p
+
+		g := parent[p]
+		if parent[g] >= 0 && length[p] >= 2 && items[g].By == item.By {
+			length[i], start[i] = length[p]+1, start[p]
+		}
+	}
+
+	return length, start
+}
+
+// parentIndexes returns the index of the parent of each entry of a flattened tree with the given
+// depths, or -1 for entries without one.
+func parentIndexes(depths []int) []int {
+	parent := make([]int, len(depths))
+	ancestors := make([]int, 0, len(depths))
+
+	for i, depth := range depths {
+		for len(ancestors) > 0 && depths[ancestors[len(ancestors)-1]] >= depth {
+			ancestors = ancestors[:len(ancestors)-1]
+		}
+
+		parent[i] = -1
+		if len(ancestors) > 0 {
+			parent[i] = ancestors[len(ancestors)-1]
+		}
+
+		ancestors = append(ancestors, i)
+	}
+
+	return parent
+}
+
+// withAncestors returns keep with the ancestors of each kept entry of a flattened tree with the
+// given depths kept too.
+func withAncestors(depths []int, keep []bool) []bool {
+	result := make([]bool, len(depths))
+	ancestors := make([]int, 0, len(depths))
+
+	for i := range depths {
+		for len(ancestors) > 0 && depths[ancestors[len(ancestors)-1]] >= depths[i] {
+			ancestors = ancestors[:len(ancestors)-1]
+		}
+
+		if keep[i] {
+			result[i] = true
+
+			for j := len(ancestors) - 1; j >= 0 && !result[ancestors[j]]; j-- {
+				result[ancestors[j]] = true
+			}
+		}
+
+		ancestors = append(ancestors, i)
+	}
+
+	return result
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+func TestConversationIDs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		authors []string
+		depths  []int
+		want    []int
+	}{
+		{name: "empty", authors: []string{}, depths: []int{}, want: []int{}},
+		{name: "exchange", authors: []string{"s", "a", "b", "a"}, depths: []int{0, 1, 2, 3}, want: []int{0, 2, 2, 2}},
+		{name: "too short", authors: []string{"s", "a", "b"}, depths: []int{0, 1, 2}, want: []int{0, 0, 0}},
+		{
+			name:    "longer exchange",
+			authors: []string{"s", "a", "b", "a", "b"},
+			depths:  []int{0, 1, 2, 3, 4},
+			want:    []int{0, 2, 2, 2, 2},
+		},
+		{
+			name:    "same author replying",
+			authors: []string{"s", "a", "a", "a"},
+			depths:  []int{0, 1, 2, 3},
+			want:    []int{0, 0, 0, 0},
+		},
+		{
+			name:    "three authors",
+			authors: []string{"s", "a", "b", "c"},
+			depths:  []int{0, 1, 2, 3},
+			want:    []int{0, 0, 0, 0},
+		},
+		{
+			name:    "story author not counted",
+			authors: []string{"a", "b", "a"},
+			depths:  []int{0, 1, 2},
+			want:    []int{0, 0, 0},
+		},
+		{
+			name:    "deleted reply breaks chain",
+			authors: []string{"s", "a", "", "a"},
+			depths:  []int{0, 1, 2, 3},
+			want:    []int{0, 0, 0, 0},
+		},
+		{
+			name:    "sibling outside",
+			authors: []string{"s", "a", "b", "a", "c"},
+			depths:  []int{0, 1, 2, 3, 2},
+			want:    []int{0, 2, 2, 2, 0},
+		},
+		{
+			name:    "exchange starting deeper",
+			authors: []string{"s", "c", "a", "b", "a"},
+			depths:  []int{0, 1, 2, 3, 4},
+			want:    []int{0, 0, 3, 3, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			items := make([]*hn.Item, 0, len(tt.authors))
+			for i, by := range tt.authors {
+				items = append(items, treeItem(i+1, by, false, false))
+			}
+
+			if got := conversationIDs(items, tt.depths); !slices.Equal(got, tt.want) {
+				t.Errorf("conversations %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithAncestors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		depths []int
+		keep   []bool
+		want   []bool
+	}{
+		{name: "empty", depths: []int{}, keep: []bool{}, want: []bool{}},
+		{
+			name:   "none kept",
+			depths: []int{0, 1, 2},
+			keep:   []bool{false, false, false},
+			want:   []bool{false, false, false},
+		},
+		{
+			name:   "root kept",
+			depths: []int{0, 1, 2},
+			keep:   []bool{true, false, false},
+			want:   []bool{true, false, false},
+		},
+		{
+			name:   "deep reply kept",
+			depths: []int{0, 1, 2, 1, 2},
+			keep:   []bool{false, false, true, false, false},
+			want:   []bool{true, true, true, false, false},
+		},
+		{
+			name:   "last reply kept",
+			depths: []int{0, 1, 2, 1, 2},
+			keep:   []bool{false, false, false, false, true},
+			want:   []bool{true, false, false, true, true},
+		},
+		{
+			name:   "several roots",
+			depths: []int{0, 1, 0, 1},
+			keep:   []bool{false, false, false, true},
+			want:   []bool{false, false, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := withAncestors(tt.depths, tt.keep); !slices.Equal(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultCORSMethods = "GET,POST,HEAD"
+	defaultCORSHeaders = "Authorization,Content-Type,Accept,X-Request-ID"
+	// corsExposedHeaders are the response headers scripts on other origins may read.
+	corsExposedHeaders = "X-Request-ID,X-Unlurker-Version,X-Cache"
+)
+
+// corsOriginAllowed reports whether origin matches one of allowed, where * matches any origin and
+// *.example.com matches the subdomains of example.com.
+func corsOriginAllowed(origin string, allowed []string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	for _, pattern := range allowed {
+		switch {
+		case pattern == "*":
+			return true
+		case strings.EqualFold(pattern, origin):
+			return true
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(strings.ToLower(u.Hostname()), strings.ToLower(pattern[1:])) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// allowCORS adds CORS headers for requests from the configured origins and answers their preflight
+// requests. Requests from other origins are served without CORS headers, so browsers block them.
+// Nothing is added if no origins are configured.
+func allowCORS(live *liveConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := live.Load()
+
+		origin := c.GetHeader("Origin")
+		if len(cfg.CORSOrigins) == 0 || origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if !corsOriginAllowed(origin, cfg.CORSOrigins) {
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", strings.Join(cfg.CORSMethods, ", "))
+			c.Header("Access-Control-Allow-Headers", strings.Join(cfg.CORSHeaders, ", "))
+			c.Header("Access-Control-Max-Age", strconv.Itoa(int(cfg.CORSMaxAge.Seconds())))
+			c.AbortWithStatus(http.StatusNoContent)
+
+			return
+		}
+
+		c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
+		c.Next()
+	}
+}
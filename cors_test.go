@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestCORSOriginAllowed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{name: "exact", origin: "https://example.com", allowed: []string{"https://example.com"}, want: true},
+		{name: "exact any case", origin: "https://Example.com", allowed: []string{"https://example.com"}, want: true},
+		{name: "other scheme", origin: "http://example.com", allowed: []string{"https://example.com"}, want: false},
+		{name: "other port", origin: "https://example.com:8443", allowed: []string{"https://example.com"}, want: false},
+		{name: "second of several", origin: "https://b.org", allowed: []string{"https://a.org", "https://b.org"}, want: true},
+		{name: "subdomain", origin: "https://app.example.com", allowed: []string{"*.example.com"}, want: true},
+		{name: "nested subdomain", origin: "https://a.b.example.com", allowed: []string{"*.example.com"}, want: true},
+		{name: "subdomain with port", origin: "https://app.example.com:8443", allowed: []string{"*.example.com"}, want: true},
+		{name: "bare domain", origin: "https://example.com", allowed: []string{"*.example.com"}, want: false},
+		{name: "lookalike", origin: "https://evil-example.com", allowed: []string{"*.example.com"}, want: false},
+		{name: "as subdomain", origin: "https://example.com.evil.org", allowed: []string{"*.example.com"}, want: false},
+		{name: "any", origin: "https://anything.org", allowed: []string{"*"}, want: true},
+		{name: "empty origin", origin: "", allowed: []string{"*"}, want: false},
+		{name: "null origin", origin: "null", allowed: []string{"*"}, want: false},
+		{name: "none allowed", origin: "https://example.com", allowed: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := corsOriginAllowed(tt.origin, tt.allowed); got != tt.want {
+				t.Errorf("allowed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
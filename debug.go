@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recentGCPauses is how many of the latest GC pauses /debug/runtime reports.
+const recentGCPauses = 16
+
+// registerDebug adds the pprof profiles and a runtime summary under /debug, or nothing if there is
+// no admin token since profiles expose the internals of the process.
+func registerDebug(r *gin.Engine, cfg config) {
+	if cfg.AdminToken == "" {
+		return
+	}
+
+	debug := r.Group("/debug", requireAdminToken(cfg.AdminToken))
+	debug.GET("/runtime", handleRuntime)
+	debug.GET("/pprof/", gin.WrapF(pprof.Index))
+	debug.GET("/pprof/cmdline", gin.WrapF(pprof.Cmdline))
+	debug.GET("/pprof/profile", gin.WrapF(pprof.Profile))
+	debug.POST("/pprof/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/pprof/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/pprof/trace", gin.WrapF(pprof.Trace))
+	debug.GET("/pprof/:profile", func(c *gin.Context) { pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request) })
+}
+
+type runtimeGC struct {
+	LastGC         string    `json:"lastGc,omitempty"`
+	RecentPausesMs []float64 `json:"recentPausesMs"`
+	Count          uint32    `json:"count"`
+	PauseTotalMs   float64   `json:"pauseTotalMs"`
+}
+
+type runtimeHeap struct {
+	AllocBytes    uint64 `json:"allocBytes"`
+	InUseBytes    uint64 `json:"inUseBytes"`
+	SysBytes      uint64 `json:"sysBytes"`
+	Objects       uint64 `json:"objects"`
+	NextGCBytes   uint64 `json:"nextGcBytes"`
+	TotalSysBytes uint64 `json:"totalSysBytes"`
+}
+
+type handleRuntimeResponse struct {
+	GC         runtimeGC   `json:"gc"`
+	Heap       runtimeHeap `json:"heap"`
+	Goroutines int         `json:"goroutines"`
+	GOMAXPROCS int         `json:"gomaxprocs"`
+}
+
+// handleRuntime responds with the goroutine count, heap usage, and recent GC pauses of the
+// process, which is usually enough to tell whether memory growth is worth a heap profile.
+func handleRuntime(c *gin.Context) {
+	var stats runtime.MemStats
+
+	runtime.ReadMemStats(&stats)
+
+	// PauseNs is a circular buffer with the latest pause at (NumGC+255)%256
+	pauses := make([]float64, 0, recentGCPauses)
+
+	for i := range min(int(stats.NumGC), recentGCPauses) {
+		pause := stats.PauseNs[(int(stats.NumGC)-1-i+len(stats.PauseNs))%len(stats.PauseNs)]
+		pauses = append(pauses, float64(pause)/float64(time.Millisecond))
+	}
+
+	lastGC := ""
+	if stats.LastGC != 0 {
+		lastGC = time.Unix(0, int64(stats.LastGC)).UTC().Format(time.RFC3339Nano) //nolint:gosec // fits until 2262
+	}
+
+	c.Header("Cache-Control", "no-store")
+	respond(c, http.StatusOK, handleRuntimeResponse{
+		GC: runtimeGC{
+			LastGC:         lastGC,
+			RecentPausesMs: pauses,
+			Count:          stats.NumGC,
+			PauseTotalMs:   float64(stats.PauseTotalNs) / float64(time.Millisecond),
+		},
+		Heap: runtimeHeap{
+			AllocBytes:    stats.HeapAlloc,
+			InUseBytes:    stats.HeapInuse,
+			SysBytes:      stats.HeapSys,
+			Objects:       stats.HeapObjects,
+			NextGCBytes:   stats.NextGC,
+			TotalSysBytes: stats.Sys,
+		},
+		Goroutines: runtime.NumGoroutine(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+	})
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// digestCheckInterval is how often the digest recipients are checked for one that is due.
+const digestCheckInterval = time.Minute
+
+// digestRecipient is an email address sent the top active threads every digest interval. A thread
+// is included when its title has any of the keywords and it peaked at minActiveComments or more
+// comments within the window; empty filters include every thread.
+type digestRecipient struct {
+	ID                string   `json:"id"`
+	Email             string   `json:"email"`
+	LastError         string   `json:"lastError,omitempty"`
+	Keywords          []string `json:"keywords"`
+	MinActiveComments int      `json:"minActiveComments"`
+	Limit             int      `json:"limit,omitempty"`
+	Created           int64    `json:"created"`
+	LastSent          int64    `json:"lastSent,omitempty"`
+}
+
+// digestThread is a thread that was in the archived /active snapshots of a digest period, with the
+// most activity it had in any of them.
+type digestThread struct {
+	Title                string `json:"title"`
+	HNURL                string `json:"hnUrl"`
+	ID                   int    `json:"id"`
+	Score                int    `json:"score"`
+	Descendants          int    `json:"descendants"`
+	PeakActiveComments   int    `json:"peakActiveComments"`
+	PeakActiveCommenters int    `json:"peakActiveCommenters"`
+}
+
+// saveDigestRecipient stores a new digest recipient.
+func (s *store) saveDigestRecipient(ctx context.Context, r digestRecipient) error {
+	keywords, err := json.Marshal(r.Keywords)
+	if err != nil {
+		return fmt.Errorf("failed to encode keywords: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO digest_recipients
+		(id, email, keywords, min_active_comments, max_threads, created) VALUES (?, ?, ?, ?, ?, ?)`,
+		r.ID, r.Email, string(keywords), r.MinActiveComments, r.Limit, r.Created)
+	if err != nil {
+		return fmt.Errorf("failed to save digest recipient: %w", err)
+	}
+
+	return nil
+}
+
+// digestRecipients returns every digest recipient, oldest first.
+func (s *store) digestRecipients(ctx context.Context) ([]digestRecipient, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, email, keywords, min_active_comments, max_threads,
+			created, last_sent, last_error
+		FROM digest_recipients ORDER BY created, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read digest recipients: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	recipients := make([]digestRecipient, 0)
+
+	for rows.Next() {
+		var (
+			r        digestRecipient
+			keywords string
+		)
+
+		err = rows.Scan(&r.ID, &r.Email, &keywords, &r.MinActiveComments, &r.Limit,
+			&r.Created, &r.LastSent, &r.LastError)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read digest recipient: %w", err)
+		}
+
+		err = json.Unmarshal([]byte(keywords), &r.Keywords)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode digest recipient %s: %w", r.ID, err)
+		}
+
+		recipients = append(recipients, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read digest recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// deleteDigestRecipient removes a digest recipient, reporting whether it existed.
+func (s *store) deleteDigestRecipient(ctx context.Context, id string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM digest_recipients WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete digest recipient %s: %w", id, err)
+	}
+
+	n, _ := result.RowsAffected()
+
+	return n > 0, nil
+}
+
+// recordDigest stores when a digest was last sent to a recipient and why it failed, if it did.
+func (s *store) recordDigest(ctx context.Context, id string, at time.Time, sendErr error) error {
+	lastError := ""
+	if sendErr != nil {
+		lastError = sendErr.Error()
+	}
+
+	_, err := s.db.ExecContext(ctx, `UPDATE digest_recipients SET last_sent = ?, last_error = ? WHERE id = ?`,
+		at.Unix(), lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to record digest to %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// digestThreads returns the threads of the archived snapshots taken within period before now,
+// busiest first. The period is capped at the range /history/active allows.
+func (s *store) digestThreads(ctx context.Context, now time.Time, period time.Duration) ([]digestThread, error) {
+	snapshots, err := s.archivedSnapshots(ctx, now.Add(-min(period, maxHistoryRange)), now)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*digestThread)
+
+	// snapshots are oldest first, so the score and descendants end up the latest ones
+	for _, snapshot := range snapshots {
+		for _, root := range snapshot.Roots {
+			thread, ok := byID[root.ID]
+			if !ok {
+				thread = &digestThread{
+					Title:                root.Title,
+					HNURL:                hnItemURL + strconv.Itoa(root.ID),
+					ID:                   root.ID,
+					Score:                0,
+					Descendants:          0,
+					PeakActiveComments:   0,
+					PeakActiveCommenters: 0,
+				}
+				byID[root.ID] = thread
+			}
+
+			thread.Score = root.Score
+			thread.Descendants = root.Descendants
+			thread.PeakActiveComments = max(thread.PeakActiveComments, root.ActiveComments)
+			thread.PeakActiveCommenters = max(thread.PeakActiveCommenters, root.ActiveCommenters)
+		}
+	}
+
+	threads := make([]digestThread, 0, len(byID))
+	for _, thread := range byID {
+		threads = append(threads, *thread)
+	}
+
+	slices.SortFunc(threads, func(a, b digestThread) int {
+		return cmp.Or(cmp.Compare(b.PeakActiveComments, a.PeakActiveComments), cmp.Compare(b.ID, a.ID))
+	})
+
+	return threads, nil
+}
+
+// filter returns the threads the recipient wants, up to its limit or else defaultLimit.
+func (r digestRecipient) filter(threads []digestThread, defaultLimit int) []digestThread {
+	limit := cmp.Or(r.Limit, defaultLimit)
+	result := make([]digestThread, 0, limit)
+
+	for _, thread := range threads {
+		if len(result) == limit {
+			break
+		}
+
+		if thread.PeakActiveComments < r.MinActiveComments {
+			continue
+		}
+
+		if len(r.Keywords) > 0 && !containsAny(strings.ToLower(thread.Title), r.Keywords) {
+			continue
+		}
+
+		result = append(result, thread)
+	}
+
+	return result
+}
+
+func containsAny(s string, substrs []string) bool {
+	return slices.ContainsFunc(substrs, func(substr string) bool { return strings.Contains(s, substr) })
+}
+
+type digestData struct {
+	Period  string
+	Threads []digestThread
+}
+
+//nolint:gochecknoglobals // parsed once
+var (
+	digestText = template.Must(template.New("text").Parse(`The busiest Hacker News threads of the last {{.Period}}:
+{{range .Threads}}
+{{.Title}}
+{{.HNURL}}
+{{.PeakActiveComments}} active comments from {{.PeakActiveCommenters}} commenters, {{.Score}} points, ` +
+		`{{.Descendants}} comments
+{{else}}
+No threads matched your filters.
+{{end}}`))
+
+	digestHTML = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html><body>
+<p>The busiest Hacker News threads of the last {{.Period}}:</p>
+{{range .Threads}}<p><a href="{{.HNURL}}"><b>{{.Title}}</b></a><br>
+{{.PeakActiveComments}} active comments from {{.PeakActiveCommenters}} commenters, {{.Score}} points, ` +
+		`{{.Descendants}} comments</p>
+{{else}}<p>No threads matched your filters.</p>
+{{end}}</body></html>
+`))
+)
+
+// digestMessage renders the digest email with text and HTML alternatives.
+func digestMessage(
+	from string, to string, now time.Time, period time.Duration, threads []digestThread,
+) ([]byte, error) {
+	data := digestData{Period: digestPeriod(period), Threads: threads}
+
+	var body bytes.Buffer
+
+	w := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		execute     func(*quotedprintable.Writer) error
+		contentType string
+	}{
+		{func(qp *quotedprintable.Writer) error { return digestText.Execute(qp, data) }, "text/plain"},
+		{func(qp *quotedprintable.Writer) error { return digestHTML.Execute(qp, data) }, "text/html"},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create digest part: %w", err)
+		}
+
+		qp := quotedprintable.NewWriter(pw)
+
+		err = errors.Join(part.execute(qp), qp.Close())
+		if err != nil {
+			return nil, fmt.Errorf("failed to render digest: %w", err)
+		}
+	}
+
+	err := w.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mimeHeader(fmt.Sprintf("Unlurker digest: %d active threads", len(threads))))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// digestPeriod formats a period without trailing zero units, like 24h instead of 24h0m0s.
+func digestPeriod(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+
+	return s
+}
+
+func mimeHeader(s string) string {
+	return mime.QEncoding.Encode("utf-8", s)
+}
+
+// sendDigests emails each digest recipient once per digest interval until ctx is done. Nothing is
+// sent while no SMTP server is configured, and a recipient whose digest failed is retried the next
+// interval. It returns immediately if there is no store.
+func sendDigests(ctx context.Context, live *liveConfig, st *store) {
+	if st == nil {
+		return
+	}
+
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		sendDueDigests(ctx, live.Load(), st, time.Now())
+	}
+}
+
+func sendDueDigests(ctx context.Context, cfg config, st *store, now time.Time) {
+	if cfg.SMTPAddr == "" || cfg.DigestInterval <= 0 {
+		return
+	}
+
+	recipients, err := st.digestRecipients(ctx)
+	if err != nil {
+		log.Printf("failed to send digests: %v", err)
+		return
+	}
+
+	var threads []digestThread
+
+	for _, r := range recipients {
+		if now.Sub(time.Unix(r.LastSent, 0)) < cfg.DigestInterval {
+			continue
+		}
+
+		if threads == nil {
+			threads, err = st.digestThreads(ctx, now, cfg.DigestInterval)
+			if err != nil {
+				log.Printf("failed to send digests: %v", err)
+				return
+			}
+		}
+
+		err = sendDigest(cfg, r, now, r.filter(threads, cfg.DigestLimit))
+		if err != nil {
+			log.Printf("failed to send digest %s: %v", r.ID, err)
+		}
+
+		err = st.recordDigest(ctx, r.ID, now, err)
+		if err != nil {
+			log.Printf("failed to record digest: %v", err)
+		}
+	}
+}
+
+// sendDigest emails threads to the recipient, authenticating if an SMTP username is configured.
+func sendDigest(cfg config, r digestRecipient, now time.Time, threads []digestThread) error {
+	msg, err := digestMessage(cfg.DigestFrom, r.Email, now, cfg.DigestInterval, threads)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+
+	if cfg.SMTPUsername != "" {
+		host, _, _ := net.SplitHostPort(cfg.SMTPAddr)
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, host)
+	}
+
+	from, err := mail.ParseAddress(cfg.DigestFrom)
+	if err != nil {
+		return fmt.Errorf("invalid digest sender: %w", err)
+	}
+
+	err = smtp.SendMail(cfg.SMTPAddr, auth, from.Address, []string{r.Email}, msg)
+	if err != nil {
+		return fmt.Errorf("failed to send digest: %w", err)
+	}
+
+	return nil
+}
+
+// registerDigests adds the endpoints managing digest recipients, which need the admin token.
+// Nothing is added without a token.
+func registerDigests(r *gin.Engine, live *liveConfig, st *store) {
+	cfg := live.Load()
+	if cfg.AdminToken == "" {
+		return
+	}
+
+	digests := r.Group("/digests", requireAdminToken(cfg.AdminToken))
+	digests.POST("", func(c *gin.Context) { handleCreateDigestRecipient(c, st) })
+	digests.GET("", func(c *gin.Context) { handleListDigestRecipients(c, st) })
+	digests.DELETE("/:id", func(c *gin.Context) { handleDeleteDigestRecipient(c, st) })
+	digests.GET("/:id/preview", func(c *gin.Context) { handlePreviewDigest(c, live, st) })
+}
+
+type digestRecipientRequest struct {
+	Email             string   `json:"email"`
+	Keywords          []string `json:"keywords"`
+	MinActiveComments int      `json:"minActiveComments"`
+	Limit             int      `json:"limit"`
+}
+
+// handleCreateDigestRecipient adds a digest recipient with optional filters from a JSON body.
+func handleCreateDigestRecipient(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	var req digestRecipientRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&req)
+	if err != nil || req.MinActiveComments < 0 || req.Limit < 0 {
+		respondError(c, http.StatusBadRequest, codeInvalidBody, "invalid request body")
+		return
+	}
+
+	addr, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		respondParamError(c, codeInvalidEmail, "email", "invalid email")
+		return
+	}
+
+	r := digestRecipient{
+		ID:                newRequestID(),
+		Email:             addr.Address,
+		LastError:         "",
+		Keywords:          normalizeTerms(req.Keywords, ""),
+		MinActiveComments: req.MinActiveComments,
+		Limit:             req.Limit,
+		Created:           time.Now().Unix(),
+		LastSent:          0,
+	}
+
+	err = st.saveDigestRecipient(c.Request.Context(), r)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to save digest recipient")
+		return
+	}
+
+	respond(c, http.StatusCreated, r)
+}
+
+type handleListDigestRecipientsResponse struct {
+	Recipients []digestRecipient `json:"recipients"`
+}
+
+// handleListDigestRecipients responds with every digest recipient and how its last digest went.
+func handleListDigestRecipients(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	recipients, err := st.digestRecipients(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read digest recipients")
+		return
+	}
+
+	respond(c, http.StatusOK, handleListDigestRecipientsResponse{Recipients: recipients})
+}
+
+// handleDeleteDigestRecipient stops the digests of a recipient.
+func handleDeleteDigestRecipient(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	found, err := st.deleteDigestRecipient(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to delete digest recipient")
+		return
+	}
+
+	if !found {
+		respondError(c, http.StatusNotFound, codeRecipientNotFound, "digest recipient not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handlePreviewDigest responds with the HTML of the digest the recipient would be sent now.
+func handlePreviewDigest(c *gin.Context, live *liveConfig, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	ctx := c.Request.Context()
+	cfg := live.Load()
+
+	recipients, err := st.digestRecipients(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read digest recipients")
+		return
+	}
+
+	i := slices.IndexFunc(recipients, func(r digestRecipient) bool { return r.ID == c.Param("id") })
+	if i < 0 {
+		respondError(c, http.StatusNotFound, codeRecipientNotFound, "digest recipient not found")
+		return
+	}
+
+	period := cmp.Or(cfg.DigestInterval, defaultDigestInterval)
+
+	threads, err := st.digestThreads(ctx, time.Now(), period)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read snapshots")
+		return
+	}
+
+	var html strings.Builder
+
+	err = digestHTML.Execute(&html, digestData{
+		Period:  digestPeriod(period),
+		Threads: recipients[i].filter(threads, cfg.DigestLimit),
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to render digest")
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html.String()))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+const dupesSearchHits = 50
+
+// trackingParams are query parameters that identify how a link was shared rather than what it
+// points to. Parameters starting with "utm_" are also removed.
+func trackingParams() map[string]bool {
+	return map[string]bool{
+		"fbclid":  true,
+		"gclid":   true,
+		"dclid":   true,
+		"msclkid": true,
+		"yclid":   true,
+		"igshid":  true,
+		"mc_cid":  true,
+		"mc_eid":  true,
+		"ref":     true,
+		"ref_src": true,
+		"_hsenc":  true,
+		"_hsmi":   true,
+	}
+}
+
+// normalizeStoryURL reduces a story URL to a key shared by the URLs that point to the same page:
+// the scheme, a leading "www.", default ports, the fragment, a trailing slash, and tracking
+// parameters are removed and the remaining parameters are sorted. It returns false if rawURL is
+// not an absolute http or https URL.
+func normalizeStoryURL(rawURL string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return "", false
+	}
+
+	key := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		key += ":" + port
+	}
+
+	key += strings.TrimSuffix(u.EscapedPath(), "/")
+	query := withoutTracking(u.Query())
+
+	if encoded := query.Encode(); encoded != "" {
+		key += "?" + encoded
+	}
+
+	return key, true
+}
+
+// withoutTracking removes the tracking parameters from query and returns it.
+func withoutTracking(query url.Values) url.Values {
+	tracking := trackingParams()
+
+	for name := range query {
+		if tracking[name] || strings.HasPrefix(name, "utm_") {
+			query.Del(name)
+		}
+	}
+
+	return query
+}
+
+type dupeStory struct {
+	*storyMetadata
+
+	Title string `json:"title"`
+	By    string `json:"by,omitempty"`
+	Link  string `json:"link"`
+	Time  int64  `json:"time"`
+	ID    int    `json:"id"`
+}
+
+type handleDupesResponse struct {
+	URL          string      `json:"url"`
+	Items        []dupeStory `json:"items"`
+	SearchFailed bool        `json:"searchFailed"`
+}
+
+// handleDupes responds with the submissions of the same page as the url query parameter, newest
+// first, from the recent stories and from HN Search. If HN Search fails the recent stories are
+// still returned and searchFailed is set.
+func handleDupes(c *gin.Context, client *itemClient, httpClient *http.Client) {
+	ctx := c.Request.Context()
+
+	key, ok := normalizeStoryURL(c.Query("url"))
+	if !ok {
+		respondParamError(c, codeInvalidURL, "url", "invalid url")
+		return
+	}
+
+	recent, err := recentStories(ctx, client, httpClient)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve stories")
+		return
+	}
+
+	dupes := make(map[int]dupeStory)
+
+	for _, item := range recent {
+		if itemKey, ok := normalizeStoryURL(item.URL); ok && itemKey == key {
+			dupes[item.ID] = newDupeStory(item)
+		}
+	}
+
+	searchFailed := searchDupes(ctx, key, dupes) != nil
+
+	items := make([]dupeStory, 0, len(dupes))
+	for _, dupe := range dupes {
+		items = append(items, dupe)
+	}
+
+	slices.SortFunc(items, func(a, b dupeStory) int { return cmp.Compare(b.Time, a.Time) })
+
+	respond(c, http.StatusOK, handleDupesResponse{URL: key, Items: items, SearchFailed: searchFailed})
+}
+
+// searchDupes adds the stories found by HN Search whose URL normalizes to key to dupes, keeping
+// any already there since items from the HN API are more current.
+func searchDupes(ctx context.Context, key string, dupes map[int]dupeStory) error {
+	result, err := searchHN(ctx, "search", url.Values{
+		"query":                        {key},
+		"restrictSearchableAttributes": {"url"},
+		"tags":                         {"story"},
+		"hitsPerPage":                  {strconv.Itoa(dupesSearchHits)},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hit := range result.Hits {
+		id, err := strconv.Atoi(hit.ObjectID)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := dupes[id]; ok {
+			continue
+		}
+
+		if hitKey, ok := normalizeStoryURL(hit.URL); ok && hitKey == key {
+			dupes[id] = dupeStory{
+				storyMetadata: &storyMetadata{
+					Type:        "story",
+					URL:         hit.URL,
+					Domain:      storyDomain(hit.URL),
+					Score:       hit.Points,
+					Descendants: hit.NumComments,
+				},
+				Title: hit.Title,
+				By:    hit.Author,
+				Link:  hnItemURL + hit.ObjectID,
+				Time:  hit.CreatedAt,
+				ID:    id,
+			}
+		}
+	}
+
+	return nil
+}
+
+func newDupeStory(item *hn.Item) dupeStory {
+	return dupeStory{
+		storyMetadata: newStoryMetadata(item),
+		Title:         item.Title,
+		By:            item.By,
+		Link:          hnItemURL + strconv.Itoa(item.ID),
+		Time:          item.Time,
+		ID:            item.ID,
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestNormalizeStoryURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		url  string
+		key  string
+		ok   bool
+	}{
+		{name: "plain", url: "https://example.com/a", key: "example.com/a", ok: true},
+		{name: "scheme", url: "http://example.com/a", key: "example.com/a", ok: true},
+		{name: "www", url: "https://www.example.com/a", key: "example.com/a", ok: true},
+		{name: "host case", url: "https://Example.COM/A", key: "example.com/A", ok: true},
+		{name: "trailing slash", url: "https://example.com/a/", key: "example.com/a", ok: true},
+		{name: "root", url: "https://example.com/", key: "example.com", ok: true},
+		{name: "fragment", url: "https://example.com/a#section", key: "example.com/a", ok: true},
+		{name: "default http port", url: "http://example.com:80/a", key: "example.com/a", ok: true},
+		{name: "default https port", url: "https://example.com:443/a", key: "example.com/a", ok: true},
+		{name: "other port", url: "https://example.com:8080/a", key: "example.com:8080/a", ok: true},
+		{name: "sorted query", url: "https://example.com/a?b=2&a=1", key: "example.com/a?a=1&b=2", ok: true},
+		{
+			name: "tracking parameters",
+			url:  "https://example.com/a?utm_source=hn&id=7&fbclid=x&ref=y&utm_campaign=z",
+			key:  "example.com/a?id=7",
+			ok:   true,
+		},
+		{name: "only tracking parameters", url: "https://example.com/a?utm_medium=social", key: "example.com/a", ok: true},
+		{name: "escaped path", url: "https://example.com/a%20b", key: "example.com/a%20b", ok: true},
+		{name: "surrounding space", url: "  https://example.com/a  ", key: "example.com/a", ok: true},
+		{name: "empty", url: "", key: "", ok: false},
+		{name: "relative", url: "/a/b", key: "", ok: false},
+		{name: "other scheme", url: "ftp://example.com/a", key: "", ok: false},
+		{name: "no host", url: "https:///a", key: "", ok: false},
+		{name: "invalid", url: "https://exa mple.com/%zz", key: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			key, ok := normalizeStoryURL(tt.url)
+			if key != tt.key || ok != tt.ok {
+				t.Errorf("normalizeStoryURL(%q) = %q, %v, want %q, %v", tt.url, key, ok, tt.key, tt.ok)
+			}
+		})
+	}
+}
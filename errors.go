@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorCode is a stable identifier clients can branch on; messages may change but codes do not.
+type errorCode string
+
+const (
+	codeInvalidWindow        errorCode = "INVALID_WINDOW"
+	codeInvalidMaxAge        errorCode = "INVALID_MAX_AGE"
+	codeInvalidMinBy         errorCode = "INVALID_MIN_BY"
+	codeInvalidUser          errorCode = "INVALID_USER"
+	codeInvalidID            errorCode = "INVALID_ID"
+	codeInvalidLimit         errorCode = "INVALID_LIMIT"
+	codeInvalidCursor        errorCode = "INVALID_CURSOR"
+	codeInvalidMaxDepth      errorCode = "INVALID_MAX_DEPTH"
+	codeInvalidDepth         errorCode = "INVALID_DEPTH"
+	codeInvalidShape         errorCode = "INVALID_SHAPE"
+	codeInvalidTimeFormat    errorCode = "INVALID_TIME_FORMAT"
+	codeInvalidText          errorCode = "INVALID_TEXT"
+	codeInvalidOffset        errorCode = "INVALID_OFFSET"
+	codeInvalidHydrate       errorCode = "INVALID_HYDRATE"
+	codeInvalidQuery         errorCode = "INVALID_QUERY"
+	codeInvalidFormat        errorCode = "INVALID_FORMAT"
+	codeInvalidFields        errorCode = "INVALID_FIELDS"
+	codeInvalidShowDead      errorCode = "INVALID_SHOW_DEAD"
+	codeInvalidTypes         errorCode = "INVALID_TYPES"
+	codeInvalidDomains       errorCode = "INVALID_DOMAINS"
+	codeInvalidGroupBy       errorCode = "INVALID_GROUP_BY"
+	codeInvalidMinScore      errorCode = "INVALID_MIN_SCORE"
+	codeInvalidCommentSort   errorCode = "INVALID_COMMENT_SORT"
+	codeInvalidSeenMaxID     errorCode = "INVALID_SEEN_MAX_ID"
+	codeInvalidBody          errorCode = "INVALID_BODY"
+	codeInvalidOnly          errorCode = "INVALID_ONLY"
+	codeInvalidActivity      errorCode = "INVALID_ACTIVITY"
+	codeInvalidEnrich        errorCode = "INVALID_ENRICH"
+	codeInvalidFollow        errorCode = "INVALID_FOLLOW"
+	codeInvalidURL           errorCode = "INVALID_URL"
+	codeInvalidSort          errorCode = "INVALID_SORT"
+	codeInvalidPage          errorCode = "INVALID_PAGE"
+	codeInvalidAt            errorCode = "INVALID_AT"
+	codeInvalidFrom          errorCode = "INVALID_FROM"
+	codeInvalidTo            errorCode = "INVALID_TO"
+	codeInvalidCache         errorCode = "INVALID_CACHE"
+	codeInvalidTimeout       errorCode = "INVALID_TIMEOUT"
+	codeInvalidPartial       errorCode = "INVALID_PARTIAL"
+	codeInvalidResume        errorCode = "INVALID_RESUME"
+	codeInvalidSetting       errorCode = "INVALID_SETTING"
+	codeInvalidKind          errorCode = "INVALID_KIND"
+	codeInvalidScope         errorCode = "INVALID_SCOPE"
+	codeInvalidValue         errorCode = "INVALID_VALUE"
+	codeInvalidEmail         errorCode = "INVALID_EMAIL"
+	codeDurationNotPositive  errorCode = "DURATION_NOT_POSITIVE"
+	codeWindowOutOfRange     errorCode = "WINDOW_OUT_OF_RANGE"
+	codeMaxAgeOutOfRange     errorCode = "MAX_AGE_OUT_OF_RANGE"
+	codeMinByOutOfRange      errorCode = "MIN_BY_OUT_OF_RANGE"
+	codeSinceExpired         errorCode = "SINCE_EXPIRED"
+	codeHNUpstreamError      errorCode = "HN_UPSTREAM_ERROR"
+	codeItemNotFound         errorCode = "ITEM_NOT_FOUND"
+	codeUserNotFound         errorCode = "USER_NOT_FOUND"
+	codeListNotFound         errorCode = "LIST_NOT_FOUND"
+	codeSubscriptionNotFound errorCode = "SUBSCRIPTION_NOT_FOUND"
+	codeWatchNotFound        errorCode = "WATCH_NOT_FOUND"
+	codeRecipientNotFound    errorCode = "RECIPIENT_NOT_FOUND"
+	codeFollowNotFound       errorCode = "FOLLOW_NOT_FOUND"
+	codeFollowedUserNotFound errorCode = "FOLLOWED_USER_NOT_FOUND"
+	codeTooManyFollows       errorCode = "TOO_MANY_FOLLOWS"
+	codeTooManyIDs           errorCode = "TOO_MANY_IDS"
+	codeInternalError        errorCode = "INTERNAL_ERROR"
+	codeStoreDisabled        errorCode = "STORE_DISABLED"
+	codeIngestDisabled       errorCode = "INGEST_DISABLED"
+	codeUnauthorized         errorCode = "UNAUTHORIZED"
+	codeTimeout              errorCode = "TIMEOUT"
+)
+
+type errorResponse struct {
+	Details map[string]string `json:"details,omitempty"`
+	Code    errorCode         `json:"code"`
+	Message string            `json:"message"`
+	Errors  []paramError      `json:"errors,omitempty"`
+}
+
+// respondError aborts the request with the standard error body. Server errors of requests that ran
+// out of time are reported as a 504 instead, since the failure is most likely the timeout.
+func respondError(c *gin.Context, status int, code errorCode, message string) {
+	if status >= http.StatusInternalServerError {
+		// a CDN must not keep serving a failure for the max-age meant for the route
+		c.Header("Cache-Control", "no-store")
+	}
+
+	if timeout, ok := timedOut(c); ok && status >= http.StatusInternalServerError {
+		respond(c, http.StatusGatewayTimeout, errorResponse{
+			Details: map[string]string{"timeout": timeout.String()},
+			Errors:  nil,
+			Code:    codeTimeout,
+			Message: "the request did not finish within its timeout; retry with a smaller request",
+		})
+		c.Abort()
+
+		return
+	}
+
+	respond(c, status, errorResponse{Details: nil, Errors: nil, Code: code, Message: message})
+	c.Abort()
+}
+
+// respondParamError aborts the request with a 400 naming the offending query or path parameter.
+func respondParamError(c *gin.Context, code errorCode, param string, message string) {
+	errs := paramErrors{}
+	errs.add(code, param, message)
+	errs.respond(c)
+}
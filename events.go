@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventKeepalive is how long an event stream may go quiet before a comment is sent to keep
+	// proxies from closing the connection.
+	eventKeepalive = 15 * time.Second
+	// eventsBuffered is how many events a stream may fall behind before it misses some.
+	eventsBuffered = 16
+)
+
+// eventBroker passes events to the streams listening for them by key. Streams that fall behind miss
+// events rather than holding up the others.
+type eventBroker[T any] struct {
+	listeners map[string]map[chan T]bool
+	mu        sync.Mutex
+}
+
+func newEventBroker[T any]() *eventBroker[T] {
+	return &eventBroker[T]{listeners: make(map[string]map[chan T]bool), mu: sync.Mutex{}}
+}
+
+// listen returns a channel receiving the events for key, which is closed by close, and a function
+// to stop listening.
+func (b *eventBroker[T]) listen(key string) (<-chan T, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan T, eventsBuffered)
+
+	if b.listeners[key] == nil {
+		b.listeners[key] = make(map[chan T]bool)
+	}
+
+	b.listeners[key][events] = true
+
+	return events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if b.listeners[key][events] {
+			delete(b.listeners[key], events)
+			close(events)
+		}
+
+		if len(b.listeners[key]) == 0 {
+			delete(b.listeners, key)
+		}
+	}
+}
+
+func (b *eventBroker[T]) publish(key string, event T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for events := range b.listeners[key] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// close ends the streams listening for keys.
+func (b *eventBroker[T]) close(keys []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		for events := range b.listeners[key] {
+			close(events)
+		}
+
+		delete(b.listeners, key)
+	}
+}
+
+// streamEvents sends the events as server-sent events named name until the client goes away,
+// events is closed, or ctx is done.
+func streamEvents[T any](ctx context.Context, c *gin.Context, name string, events <-chan T) {
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if ok {
+				c.SSEvent(name, event)
+			}
+
+			return ok
+		case <-keepalive.C:
+			_, err := io.WriteString(w, ": keepalive\n\n")
+			return err == nil
+		}
+	})
+}
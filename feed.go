@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	jsonFeedVersion     = "https://jsonfeed.org/version/1.1"
+	jsonFeedContentType = "application/feed+json"
+	hnItemURL           = "https://news.ycombinator.com/item?id="
+	unlurkerHomeURL     = "https://hn.unlurker.com"
+)
+
+//nolint:tagliatelle // field names are defined by the JSON Feed specification
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+//nolint:tagliatelle // field names are defined by the JSON Feed specification
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ContentHTML   string           `json:"content_html,omitempty"`
+	ContentText   string           `json:"content_text"`
+	DatePublished string           `json:"date_published"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// wantsJSONFeed reports whether the request asked for /active as a JSON Feed, either through the
+// .json-feed route or the Accept header.
+func wantsJSONFeed(c *gin.Context) bool {
+	if c.FullPath() == "/active.json-feed" {
+		return true
+	}
+
+	return c.NegotiateFormat(gin.MIMEJSON, jsonFeedContentType) == jsonFeedContentType
+}
+
+// renderActiveFeed responds with the active roots as a JSON Feed 1.1 document, one entry per root.
+func renderActiveFeed(
+	c *gin.Context,
+	roots []handleActiveRoot,
+	formatter *textFormatter,
+	showUser bool,
+) {
+	items := make([]jsonFeedItem, 0, len(roots))
+
+	for _, root := range roots {
+		var authors []jsonFeedAuthor
+		if showUser && root.Item.By != "" {
+			authors = []jsonFeedAuthor{{Name: root.Item.By, URL: "https://news.ycombinator.com/user?id=" + root.Item.By}}
+		}
+
+		items = append(items, jsonFeedItem{
+			ID:            strconv.Itoa(root.Item.ID),
+			URL:           hnItemURL + strconv.Itoa(root.Item.ID),
+			ExternalURL:   root.Item.URL,
+			Title:         root.Item.Title,
+			ContentHTML:   root.Item.Text,
+			ContentText:   formatter.format(root.Item),
+			DatePublished: time.Unix(root.Time, 0).UTC().Format(time.RFC3339),
+			Authors:       authors,
+		})
+	}
+
+	feed := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       "Unlurker: active Hacker News discussions",
+		HomePageURL: unlurkerHomeURL,
+		FeedURL:     "",
+		Description: "Hacker News stories with recent comment activity",
+		Items:       items,
+	}
+
+	body, err := json.Marshal(feed)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to encode feed")
+		return
+	}
+
+	c.Data(http.StatusOK, jsonFeedContentType+"; charset=utf-8", body)
+}
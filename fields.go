@@ -0,0 +1,134 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldSet is the set of JSON field names requested with the fields query parameter.
+type fieldSet map[string]bool
+
+type sparseActiveResponse struct {
+	*responseTruncation
+	Items              any  `json:"items"`
+	SecondChanceFailed bool `json:"secondChanceFailed"`
+	Stale              bool `json:"stale,omitempty"`
+}
+
+type sparsePageResponse struct {
+	Items      any    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// parseFields reads the optional fields query parameter, a comma-separated list of the JSON field
+// names of item, which must be a struct. A nil result means every field.
+func parseFields(c *gin.Context, item any) (fieldSet, bool) {
+	param, ok := c.GetQuery("fields")
+	if !ok {
+		return nil, true
+	}
+
+	known := make(fieldSet)
+	addFieldNames(reflect.TypeOf(item), known)
+
+	fields := make(fieldSet)
+
+	for _, name := range splitList(param) {
+		if !known[name] {
+			return nil, false
+		}
+
+		fields[name] = true
+	}
+
+	if len(fields) == 0 {
+		return nil, false
+	}
+
+	return fields, true
+}
+
+func addFieldNames(t reflect.Type, names fieldSet) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if field.Anonymous && name == "" {
+			addFieldNames(field.Type, names)
+			continue
+		}
+
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+}
+
+// pick converts a slice of items, or pointers to items, to maps holding only the requested
+// fields. Requested fields are always present, even when empty, except the story fields of items
+// that are not roots. Children are kept whenever there are any so nested responses keep their
+// shape.
+func (f fieldSet) pick(items any) []map[string]any {
+	v := reflect.ValueOf(items)
+	result := make([]map[string]any, 0, v.Len())
+
+	for i := range v.Len() {
+		item := reflect.Indirect(v.Index(i))
+		m := make(map[string]any, len(f))
+		f.pickInto(item, m)
+		result = append(result, m)
+	}
+
+	return result
+}
+
+func (f fieldSet) pickInto(v reflect.Value, m map[string]any) {
+	t := v.Type()
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		value := v.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		switch {
+		case field.Anonymous && name == "":
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					continue
+				}
+
+				value = value.Elem()
+			}
+
+			f.pickInto(value, m)
+		case name == "children":
+			if value.Len() > 0 {
+				m[name] = f.pick(value.Interface())
+			}
+		case f[name]:
+			m[name] = plainValue(value)
+		}
+	}
+}
+
+// plainValue returns the value of a field of a basic kind. Fields promoted from unexported
+// embedded structs such as storyMetadata cannot be read with Interface, so read them by kind.
+func plainValue(v reflect.Value) any {
+	//nolint:exhaustive // response fields only have these kinds
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	default:
+		return v.Interface()
+	}
+}
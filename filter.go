@@ -0,0 +1,197 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// storyKinds returns the values accepted by the types query parameter.
+func storyKinds() map[string]bool {
+	return map[string]bool{"story": true, "ask": true, "show": true, "job": true, "poll": true}
+}
+
+// rootFilter selects which roots of an active snapshot are returned. The zero value keeps all of
+// them.
+type rootFilter struct {
+	Kinds    map[string]bool
+	Blocked  map[string]bool
+	Query    string
+	Exclude  []string
+	Domains  []string
+	MinScore int
+	Limit    int
+	Offset   int
+}
+
+// parseRootFilter reads the query parameters that filter /active roots, adding an error to errs for
+// each that is invalid.
+func parseRootFilter(c *gin.Context, errs *paramErrors) rootFilter {
+	kinds, ok := parseKinds(c)
+	if !ok {
+		errs.add(codeInvalidTypes, "types", "invalid types")
+	}
+
+	domains, ok := parseDomains(c)
+	if !ok {
+		errs.add(codeInvalidDomains, "domains", "invalid domains")
+	}
+
+	minScore, err := strconv.Atoi(c.DefaultQuery("min-score", "0"))
+	if err != nil {
+		errs.add(codeInvalidMinScore, "min-score", "invalid min-score")
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		errs.add(codeInvalidLimit, "limit", "invalid limit")
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		errs.add(codeInvalidOffset, "offset", "invalid offset")
+	}
+
+	var exclude []string
+	for _, term := range splitList(c.Query("exclude")) {
+		exclude = append(exclude, strings.ToLower(term))
+	}
+
+	return rootFilter{
+		Kinds:    kinds,
+		Blocked:  nil,
+		Query:    strings.ToLower(strings.TrimSpace(c.Query("q"))),
+		Exclude:  exclude,
+		Domains:  domains,
+		MinScore: minScore,
+		Limit:    limit,
+		Offset:   offset,
+	}
+}
+
+// parseKinds reads the optional types query parameter. A nil result means every kind.
+func parseKinds(c *gin.Context) (map[string]bool, bool) {
+	param, ok := c.GetQuery("types")
+	if !ok {
+		return nil, true
+	}
+
+	known := storyKinds()
+	kinds := make(map[string]bool)
+
+	for _, kind := range splitList(param) {
+		if !known[kind] {
+			return nil, false
+		}
+
+		kinds[kind] = true
+	}
+
+	return kinds, len(kinds) > 0
+}
+
+// parseDomains reads the optional domains query parameter. A nil result means every domain.
+func parseDomains(c *gin.Context) ([]string, bool) {
+	param, ok := c.GetQuery("domains")
+	if !ok {
+		return nil, true
+	}
+
+	var domains []string
+	for _, domain := range splitList(param) {
+		domains = append(domains, strings.TrimPrefix(strings.ToLower(domain), "www."))
+	}
+
+	return domains, len(domains) > 0
+}
+
+// apply returns a copy of snapshot holding only the roots that pass the filter, skipping the first
+// Offset of them and keeping at most Limit unless it is 0. The snapshot may be shared with other
+// requests, so it is not modified.
+func (f rootFilter) apply(snapshot *activeSnapshot) *activeSnapshot {
+	roots := make([]handleActiveRoot, 0, len(snapshot.Roots))
+
+	for _, root := range snapshot.Roots {
+		if f.keep(root.Item) {
+			roots = append(roots, root)
+		}
+	}
+
+	roots = roots[min(f.Offset, len(roots)):]
+	if f.Limit > 0 {
+		roots = roots[:min(f.Limit, len(roots))]
+	}
+
+	filtered := *snapshot
+	filtered.Roots = roots
+
+	return &filtered
+}
+
+func (f rootFilter) keep(item *hn.Item) bool {
+	if f.Blocked[item.By] || item.Score < f.MinScore {
+		return false
+	}
+
+	if f.Kinds != nil && !f.Kinds[storyKind(item)] {
+		return false
+	}
+
+	if f.Domains != nil && !matchesDomain(storyDomain(item.URL), f.Domains) {
+		return false
+	}
+
+	return f.matches(item)
+}
+
+// matches reports whether the title, URL, or domain of item contains the query and none of the
+// excluded terms, ignoring case.
+func (f rootFilter) matches(item *hn.Item) bool {
+	if f.Query == "" && len(f.Exclude) == 0 {
+		return true
+	}
+
+	s := strings.ToLower(item.Title + "\n" + item.URL + "\n" + storyDomain(item.URL))
+
+	if !strings.Contains(s, f.Query) {
+		return false
+	}
+
+	for _, term := range f.Exclude {
+		if strings.Contains(s, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// storyKind classifies a story like the HN navigation does: Ask HN and Show HN posts are
+// recognized by their title prefix since HN gives them the plain story type.
+func storyKind(item *hn.Item) string {
+	switch {
+	case item.Type == "job" || item.Type == "poll":
+		return item.Type
+	case strings.HasPrefix(item.Title, "Ask HN:"):
+		return "ask"
+	case strings.HasPrefix(item.Title, "Show HN:"):
+		return "show"
+	default:
+		return "story"
+	}
+}
+
+// matchesDomain reports whether domain is one of domains or a subdomain of one of them.
+func matchesDomain(domain string, domains []string) bool {
+	domain = strings.ToLower(domain)
+
+	for _, d := range domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestStoryKind(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		itemType string
+		title    string
+		want     string
+	}{
+		{name: "story", itemType: "story", title: "A story", want: "story"},
+		{name: "ask", itemType: "story", title: "Ask HN: Anything?", want: "ask"},
+		{name: "show", itemType: "story", title: "Show HN: A thing", want: "show"},
+		{name: "job", itemType: "job", title: "Hiring", want: "job"},
+		{name: "poll", itemType: "poll", title: "Ask HN: Poll?", want: "poll"},
+		{name: "prefix not at start", itemType: "story", title: "Why Ask HN: works", want: "story"},
+		{name: "prefix in lowercase", itemType: "story", title: "ask hn: anything?", want: "story"},
+		{name: "prefix without colon", itemType: "story", title: "Show HN Weekly", want: "story"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			item := treeItem(1, "", false, false)
+			item.Type = tt.itemType
+			item.Title = tt.title
+
+			if got := storyKind(item); got != tt.want {
+				t.Errorf("kind %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesDomain(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		domain  string
+		domains []string
+		want    bool
+	}{
+		{name: "exact", domain: "example.com", domains: []string{"example.com"}, want: true},
+		{name: "any case", domain: "Example.COM", domains: []string{"example.com"}, want: true},
+		{name: "subdomain", domain: "blog.example.com", domains: []string{"example.com"}, want: true},
+		{name: "nested subdomain", domain: "a.b.example.com", domains: []string{"example.com"}, want: true},
+		{name: "lookalike", domain: "evil-example.com", domains: []string{"example.com"}, want: false},
+		{name: "suffix without dot", domain: "notexample.com", domains: []string{"example.com"}, want: false},
+		{name: "parent of listed", domain: "example.com", domains: []string{"blog.example.com"}, want: false},
+		{name: "second of several", domain: "github.io", domains: []string{"example.com", "github.io"}, want: true},
+		{name: "empty domain", domain: "", domains: []string{"example.com"}, want: false},
+		{name: "none listed", domain: "example.com", domains: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := matchesDomain(tt.domain, tt.domains); got != tt.want {
+				t.Errorf("matches %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+const (
+	// maxFollowParam is the most users the follow query parameter can name.
+	maxFollowParam = 100
+
+	// maxFollowedUsers is the most users the server's follow list can hold.
+	maxFollowedUsers = 1000
+
+	// maxUserNameLength is the longest name HN allows for an account.
+	maxUserNameLength = 15
+
+	userNameChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
+)
+
+// followedUser is a user on the server's follow list, whose comments are marked followed in /active
+// for every client. Names are kept in lower case since HN does not allow two accounts whose names
+// differ only in case.
+type followedUser struct {
+	Name    string `json:"name"`
+	Created int64  `json:"created"`
+}
+
+// saveFollowedUser adds a user to the follow list unless it already has max users, reporting
+// whether it was added.
+func (s *store) saveFollowedUser(ctx context.Context, u followedUser, max int) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `INSERT INTO followed_users (name, created)
+		SELECT ?, ?
+		WHERE (SELECT COUNT(*) FROM followed_users) < ?
+		ON CONFLICT (name) DO NOTHING`,
+		u.Name, u.Created, max)
+	if err != nil {
+		return false, fmt.Errorf("failed to save followed user: %w", err)
+	}
+
+	n, _ := result.RowsAffected()
+
+	return n > 0, nil
+}
+
+// followedUser returns the user with the given name from the follow list, reporting whether it is
+// on it.
+func (s *store) followedUser(ctx context.Context, name string) (followedUser, bool, error) {
+	var u followedUser
+
+	err := s.db.QueryRowContext(ctx, `SELECT name, created FROM followed_users WHERE name = ?`, name).
+		Scan(&u.Name, &u.Created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return u, false, nil
+	}
+
+	if err != nil {
+		return u, false, fmt.Errorf("failed to read followed user %s: %w", name, err)
+	}
+
+	return u, true, nil
+}
+
+// followedUsers returns the follow list in order of name.
+func (s *store) followedUsers(ctx context.Context) ([]followedUser, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, created FROM followed_users ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read followed users: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	users := make([]followedUser, 0)
+
+	for rows.Next() {
+		var u followedUser
+
+		err = rows.Scan(&u.Name, &u.Created)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read followed user: %w", err)
+		}
+
+		users = append(users, u)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read followed users: %w", err)
+	}
+
+	return users, nil
+}
+
+// deleteFollowedUser removes a user from the follow list, reporting whether it was on it.
+func (s *store) deleteFollowedUser(ctx context.Context, name string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM followed_users WHERE name = ?`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete followed user %s: %w", name, err)
+	}
+
+	n, _ := result.RowsAffected()
+
+	return n > 0, nil
+}
+
+// followList is the server's follow list held in memory, so /active does not read the store on
+// every request. It is loaded at startup and replaced, never modified, when a user is added or
+// removed. Readers load it without locking; mu only keeps concurrent changes from losing each other.
+type followList struct {
+	names atomic.Pointer[map[string]bool]
+	mu    sync.Mutex
+}
+
+// loadFollowList reads the follow list from st, or returns an empty list if st is nil.
+func loadFollowList(ctx context.Context, st *store) (*followList, error) {
+	names := make(map[string]bool)
+
+	if st != nil {
+		users, err := st.followedUsers(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, u := range users {
+			names[u.Name] = true
+		}
+	}
+
+	l := &followList{names: atomic.Pointer[map[string]bool]{}, mu: sync.Mutex{}}
+	l.names.Store(&names)
+
+	return l, nil
+}
+
+// set replaces the list with a copy that has or does not have name.
+func (l *followList) set(name string, followed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	names := maps.Clone(*l.names.Load())
+	if followed {
+		names[name] = true
+	} else {
+		delete(names, name)
+	}
+
+	l.names.Store(&names)
+}
+
+// with returns the users named by the follow query parameter along with those on the list, in
+// lower case. A nil list has no users.
+func (l *followList) with(names []string) map[string]bool {
+	var followed map[string]bool
+	if l != nil {
+		followed = maps.Clone(*l.names.Load())
+	} else {
+		followed = make(map[string]bool, len(names))
+	}
+
+	for _, name := range names {
+		followed[name] = true
+	}
+
+	return followed
+}
+
+// isFollowed reports whether item was written by one of the followed users.
+func isFollowed(item *hn.Item, followed map[string]bool) bool {
+	return item.By != "" && followed[strings.ToLower(item.By)]
+}
+
+// validUserName reports whether name could be the name of an HN account: 2 to 15 letters, digits,
+// dashes, and underscores.
+func validUserName(name string) bool {
+	return len(name) >= 2 && len(name) <= maxUserNameLength && strings.Trim(name, userNameChars) == ""
+}
+
+// parseFollow reads the follow query parameter, a comma-separated list of at most maxFollowParam
+// users, in lower case, adding an error to errs if it is invalid.
+func parseFollow(c *gin.Context, errs *paramErrors) []string {
+	names := splitList(c.Query("follow"))
+	if len(names) > maxFollowParam {
+		errs.add(codeInvalidFollow, "follow", fmt.Sprintf("at most %d users", maxFollowParam))
+		return nil
+	}
+
+	for i, name := range names {
+		if !validUserName(name) {
+			errs.add(codeInvalidFollow, "follow", "invalid user "+name)
+			return nil
+		}
+
+		names[i] = strings.ToLower(name)
+	}
+
+	return names
+}
+
+// registerFollowedUsers adds the endpoints managing the server's follow list, which need the admin
+// token since the list applies to every client. Nothing is added without a token. Changes update
+// list and drop the cached /active responses that marked the users followed or not.
+func registerFollowedUsers(
+	r *gin.Engine,
+	cfg config,
+	st *store,
+	list *followList,
+	responses *lruCache[string, cachedResponse],
+) {
+	if cfg.AdminToken == "" {
+		return
+	}
+
+	changed := func(name string, followed bool) {
+		list.set(name, followed)
+		responses.DeleteFunc(func(key string) bool { return strings.HasPrefix(key, "/active") })
+	}
+
+	users := r.Group("/followed-users", requireAdminToken(cfg.AdminToken))
+	users.GET("", func(c *gin.Context) { handleListFollowedUsers(c, st) })
+	users.GET("/:name", func(c *gin.Context) { handleGetFollowedUser(c, st) })
+	users.PUT("/:name", func(c *gin.Context) { handlePutFollowedUser(c, st, changed) })
+	users.DELETE("/:name", func(c *gin.Context) { handleDeleteFollowedUser(c, st, changed) })
+}
+
+// parseFollowedUserName reads the name path parameter, in lower case, responding with an error and
+// returning false if it is invalid or the server has no store.
+func parseFollowedUserName(c *gin.Context, st *store) (string, bool) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return "", false
+	}
+
+	name := c.Param("name")
+	if !validUserName(name) {
+		respondParamError(c, codeInvalidUser, "name", "invalid name")
+		return "", false
+	}
+
+	return strings.ToLower(name), true
+}
+
+type handleListFollowedUsersResponse struct {
+	Users []followedUser `json:"users"`
+}
+
+// handleListFollowedUsers responds with the server's follow list.
+func handleListFollowedUsers(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	users, err := st.followedUsers(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read followed users")
+		return
+	}
+
+	respond(c, http.StatusOK, handleListFollowedUsersResponse{Users: users})
+}
+
+// handleGetFollowedUser responds with one user of the server's follow list.
+func handleGetFollowedUser(c *gin.Context, st *store) {
+	name, ok := parseFollowedUserName(c, st)
+	if !ok {
+		return
+	}
+
+	u, found, err := st.followedUser(c.Request.Context(), name)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read followed user")
+		return
+	}
+
+	if !found {
+		respondError(c, http.StatusNotFound, codeFollowedUserNotFound, "user not followed")
+		return
+	}
+
+	respond(c, http.StatusOK, u)
+}
+
+// handlePutFollowedUser adds a user to the server's follow list, calling changed once it is saved.
+// Adding a user already on it responds with the user as it is.
+func handlePutFollowedUser(c *gin.Context, st *store, changed func(name string, followed bool)) {
+	name, ok := parseFollowedUserName(c, st)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	u, found, err := st.followedUser(ctx, name)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read followed user")
+		return
+	}
+
+	if found {
+		respond(c, http.StatusOK, u)
+		return
+	}
+
+	u = followedUser{Name: name, Created: time.Now().Unix()}
+
+	saved, err := st.saveFollowedUser(ctx, u, maxFollowedUsers)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to save followed user")
+		return
+	}
+
+	if !saved {
+		respondError(c, http.StatusConflict, codeTooManyFollows, "too many followed users")
+		return
+	}
+
+	changed(name, true)
+
+	respond(c, http.StatusCreated, u)
+}
+
+// handleDeleteFollowedUser removes a user from the server's follow list, calling changed once it is
+// deleted.
+func handleDeleteFollowedUser(c *gin.Context, st *store, changed func(name string, followed bool)) {
+	name, ok := parseFollowedUserName(c, st)
+	if !ok {
+		return
+	}
+
+	deleted, err := st.deleteFollowedUser(c.Request.Context(), name)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to delete followed user")
+		return
+	}
+
+	if !deleted {
+		respondError(c, http.StatusNotFound, codeFollowedUserNotFound, "user not followed")
+		return
+	}
+
+	changed(name, false)
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"maps"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestFollowListSetConcurrently(t *testing.T) {
+	t.Parallel()
+
+	list, err := loadFollowList(t.Context(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list.set("removed", true)
+
+	const users = 50
+
+	var wg sync.WaitGroup
+
+	want := make(map[string]bool, users)
+
+	for i := range users {
+		name := "user" + strconv.Itoa(i)
+		want[name] = true
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			list.set(name, true)
+		}()
+	}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		list.set("removed", false)
+	}()
+
+	wg.Wait()
+
+	if got := list.with(nil); !maps.Equal(got, want) {
+		t.Errorf("list has %d users, want %d: %v", len(got), len(want), got)
+	}
+}
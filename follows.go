@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+const followEventItems = "follow.items"
+
+// follow watches one thread for new descendants, or only for replies by one user, and sends them
+// to the follow's event stream and, if it has one, its webhook URL signed with the secret. Follows
+// expire so that ones nobody listens to do not keep the thread being fetched.
+type follow struct {
+	ID         string `json:"id"`
+	User       string `json:"user,omitempty"`
+	WebhookURL string `json:"webhookUrl,omitempty"`
+	Secret     string `json:"secret,omitempty"`
+	EventsURL  string `json:"eventsUrl"`
+	Item       int    `json:"item"`
+	LastID     int    `json:"-"`
+	Created    int64  `json:"created"`
+	Expires    int64  `json:"expires"`
+}
+
+type followEvent struct {
+	Event  string                          `json:"event"`
+	Follow string                          `json:"follow"`
+	Items  []handleItemDescendantsResponse `json:"items"`
+	Item   int                             `json:"item"`
+	Time   int64                           `json:"time"`
+}
+
+// saveFollow stores a new follow unless there are already max unexpired ones, reporting whether it
+// was stored.
+func (s *store) saveFollow(ctx context.Context, f follow, max int) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `INSERT INTO follows
+			(id, item, user, webhook_url, secret, last_id, created, expires)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE (SELECT COUNT(*) FROM follows WHERE expires > ?) < ?`,
+		f.ID, f.Item, f.User, f.WebhookURL, f.Secret, f.LastID, f.Created, f.Expires, f.Created, max)
+	if err != nil {
+		return false, fmt.Errorf("failed to save follow: %w", err)
+	}
+
+	n, _ := result.RowsAffected()
+
+	return n > 0, nil
+}
+
+// follows returns the follows that have not expired by now.
+func (s *store) follows(ctx context.Context, now time.Time) ([]follow, error) {
+	return s.queryFollows(ctx, `SELECT id, item, user, webhook_url, secret, last_id, created, expires
+		FROM follows WHERE expires > ? ORDER BY item, created, id`, now.Unix())
+}
+
+// follow returns the follow with the given ID unless it expired by now, reporting whether it exists.
+func (s *store) follow(ctx context.Context, id string, now time.Time) (follow, bool, error) {
+	var invalid follow
+
+	follows, err := s.queryFollows(ctx, `SELECT id, item, user, webhook_url, secret, last_id, created, expires
+		FROM follows WHERE id = ? AND expires > ?`, id, now.Unix())
+	if err != nil || len(follows) == 0 {
+		return invalid, false, err
+	}
+
+	return follows[0], true, nil
+}
+
+func (s *store) queryFollows(ctx context.Context, query string, args ...any) ([]follow, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read follows: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	follows := make([]follow, 0)
+
+	for rows.Next() {
+		var f follow
+
+		err = rows.Scan(&f.ID, &f.Item, &f.User, &f.WebhookURL, &f.Secret, &f.LastID, &f.Created, &f.Expires)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read follow: %w", err)
+		}
+
+		f.EventsURL = followEventsURL(f.ID)
+		follows = append(follows, f)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read follows: %w", err)
+	}
+
+	return follows, nil
+}
+
+// advanceFollow stores the largest descendant ID a follow has seen.
+func (s *store) advanceFollow(ctx context.Context, id string, lastID int) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE follows SET last_id = ? WHERE id = ?`, lastID, id)
+	if err != nil {
+		return fmt.Errorf("failed to advance follow %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// deleteFollows removes the follows with the given ID, or those expired by now if id is empty, and
+// returns the IDs removed.
+func (s *store) deleteFollows(ctx context.Context, id string, now time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `DELETE FROM follows WHERE (? != '' AND id = ?) OR (? = '' AND expires <= ?)
+		RETURNING id`, id, id, id, now.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete follows: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	var ids []string
+
+	for rows.Next() {
+		var deleted string
+
+		err = rows.Scan(&deleted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read deleted follow: %w", err)
+		}
+
+		ids = append(ids, deleted)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete follows: %w", err)
+	}
+
+	return ids, nil
+}
+
+func followEventsURL(id string) string {
+	return "/follows/" + id + "/events"
+}
+
+type follower struct {
+	client    *itemClient
+	webhooks  *http.Client
+	formatter *textFormatter
+	store     *store
+	broker    *eventBroker[followEvent]
+	// threads holds the IDs of the items of each followed thread as of its last check.
+	threads map[int]map[int]bool
+}
+
+// followThreads checks the followed threads every interval and sends their new descendants to the
+// follows until ctx is done. A thread is only fetched again when the shared poll of the HN updates
+// list published to updates reports a change to one of its items, or every liveResyncTicks
+// intervals; with that poll disabled, every thread is fetched every interval. It returns
+// immediately if there is no store.
+func followThreads(
+	ctx context.Context,
+	cfg config,
+	client *itemClient,
+	formatter *textFormatter,
+	st *store,
+	broker *eventBroker[followEvent],
+	updates *eventBroker[map[int]bool],
+) {
+	if st == nil {
+		return
+	}
+
+	f := &follower{
+		client:    client,
+		webhooks:  &http.Client{Timeout: webhookTimeout},
+		formatter: formatter,
+		store:     st,
+		broker:    broker,
+		threads:   make(map[int]map[int]bool),
+	}
+
+	changes, stop := updates.listen(updatedItemsKey)
+	defer stop()
+
+	ticker := time.NewTicker(cfg.LiveInterval)
+	defer ticker.Stop()
+
+	for tick := 1; ; tick++ {
+		changed, err := collectChanges(ctx, ticker.C, changes)
+		if err != nil {
+			return
+		}
+
+		if tick%liveResyncTicks == 0 || cfg.UpdatesInterval <= 0 {
+			changed = nil
+		}
+
+		f.check(ctx, time.Now(), changed)
+	}
+}
+
+// check drops the expired follows and fetches each followed thread once for all of its follows if
+// any of its items are in changed, or whatever changed if changed is nil.
+func (f *follower) check(ctx context.Context, now time.Time, changed map[int]bool) {
+	expired, err := f.store.deleteFollows(ctx, "", now)
+	if err != nil {
+		log.Printf("failed to expire follows: %v", err)
+	}
+
+	f.broker.close(expired)
+
+	follows, err := f.store.follows(ctx, now)
+	if err != nil {
+		log.Printf("failed to check follows: %v", err)
+		return
+	}
+
+	followed := make(map[int]bool)
+
+	// follows are ordered by item, so each run of them shares a thread
+	for start := 0; start < len(follows); {
+		end := start + 1
+		for end < len(follows) && follows[end].Item == follows[start].Item {
+			end++
+		}
+
+		itemID := follows[start].Item
+		followed[itemID] = true
+
+		if changed == nil || f.touched(itemID, changed) {
+			f.checkThread(ctx, follows[start:end], now)
+		}
+
+		start = end
+	}
+
+	maps.DeleteFunc(f.threads, func(itemID int, _ map[int]bool) bool { return !followed[itemID] })
+}
+
+// touched reports whether any item of a followed thread is in changed, or if the thread was not
+// fetched yet.
+func (f *follower) touched(itemID int, changed map[int]bool) bool {
+	known, ok := f.threads[itemID]
+	if !ok {
+		return true
+	}
+
+	for id := range changed {
+		if known[id] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *follower) checkThread(ctx context.Context, follows []follow, now time.Time) {
+	itemID := follows[0].Item
+
+	root, flat, err := fetchTree(ctx, f.client, itemID)
+	if err != nil || root == nil {
+		log.Printf("failed to check follows of item %d: %v", itemID, err)
+		return
+	}
+
+	known := make(map[int]bool, len(flat))
+	for _, item := range flat {
+		known[item.ID] = true
+	}
+
+	f.threads[itemID] = known
+
+	for _, fl := range follows {
+		items, lastID := f.newItems(fl, root, flat)
+		if lastID == fl.LastID {
+			continue
+		}
+
+		err = f.store.advanceFollow(ctx, fl.ID, lastID)
+		if err != nil {
+			log.Printf("failed to check follow: %v", err)
+			continue
+		}
+
+		if len(items) > 0 {
+			f.send(ctx, fl, followEvent{Event: followEventItems, Follow: fl.ID, Items: items, Item: itemID, Time: now.Unix()})
+		}
+	}
+}
+
+// newItems returns the descendants in flat posted since the follow last checked that it wants, and
+// the largest descendant ID.
+func (f *follower) newItems(fl follow, root *hn.Item, flat []unl.FlatItem) ([]handleItemDescendantsResponse, int) {
+	var items []handleItemDescendantsResponse
+
+	lastID := fl.LastID
+
+	for _, item := range flat[1:] {
+		if item.ID <= fl.LastID {
+			continue
+		}
+
+		lastID = max(lastID, item.ID)
+
+		if fl.User == "" || strings.EqualFold(item.By, fl.User) {
+			items = append(items, newLiveItem(item, root, f.formatter))
+		}
+	}
+
+	return items, lastID
+}
+
+// send passes an event to the event streams of the follow and posts it to its webhook URL.
+func (f *follower) send(ctx context.Context, fl follow, event followEvent) {
+	f.broker.publish(event.Follow, event)
+
+	if fl.WebhookURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("failed to encode follow webhook: %v", err)
+		return
+	}
+
+	err = postWebhook(ctx, f.webhooks, fl.WebhookURL, fl.Secret, followEventItems, body)
+	if err != nil {
+		log.Printf("failed to send webhook of follow %s: %v", fl.ID, err)
+	}
+}
+
+// registerFollows adds the endpoints for following threads. Creating a follow needs the admin token,
+// since every follow adds to the work the server does each interval, and is not possible without
+// one; the random ID of a follow is all it takes to read it, stream its events, or delete it. Event
+// streams end when ctx is done so they do not hold up shutting down.
+func registerFollows(
+	ctx context.Context, r *gin.Engine, cfg config, client *itemClient, st *store, broker *eventBroker[followEvent],
+) {
+	if cfg.AdminToken != "" {
+		r.POST("/item/:id/follow", requireAdminToken(cfg.AdminToken), func(c *gin.Context) {
+			handleCreateFollow(c, cfg, client, st)
+		})
+	}
+
+	r.GET("/follows/:id", func(c *gin.Context) { handleGetFollow(c, st) })
+	r.DELETE("/follows/:id", func(c *gin.Context) { handleDeleteFollow(c, st, broker) })
+	r.GET("/follows/:id/events", func(c *gin.Context) { handleFollowEvents(ctx, c, st, broker) })
+}
+
+type followRequest struct {
+	User       string `json:"user"`
+	WebhookURL string `json:"webhookUrl"`
+}
+
+// parseFollowRequest reads the optional JSON body of a new follow, responding with an error and
+// returning false if it is invalid.
+func parseFollowRequest(c *gin.Context) (followRequest, bool) {
+	var req followRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, codeInvalidBody, "invalid request body")
+		return req, false
+	}
+
+	req.User = strings.TrimSpace(req.User)
+
+	if req.WebhookURL == "" {
+		return req, true
+	}
+
+	u, err := url.Parse(req.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		respondParamError(c, codeInvalidURL, "webhookUrl", "invalid webhookUrl")
+		return req, false
+	}
+
+	req.WebhookURL = u.String()
+
+	return req, true
+}
+
+// handleCreateFollow starts following a thread. Only descendants posted after the follow is
+// created are sent.
+func handleCreateFollow(c *gin.Context, cfg config, client *itemClient, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondParamError(c, codeInvalidID, "id", "invalid id")
+		return
+	}
+
+	req, ok := parseFollowRequest(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	root, flat, err := fetchTree(ctx, client, itemID)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve item")
+		return
+	}
+
+	if root == nil {
+		respondError(c, http.StatusNotFound, codeItemNotFound, "item not found")
+		return
+	}
+
+	now := time.Now()
+	f := follow{
+		ID:         newRequestID(),
+		User:       req.User,
+		WebhookURL: req.WebhookURL,
+		Secret:     "",
+		EventsURL:  "",
+		Item:       itemID,
+		LastID:     0,
+		Created:    now.Unix(),
+		Expires:    now.Add(cfg.FollowTTL).Unix(),
+	}
+
+	f.EventsURL = followEventsURL(f.ID)
+
+	if f.WebhookURL != "" {
+		f.Secret = newRequestID() + newRequestID()
+	}
+
+	for _, item := range flat {
+		f.LastID = max(f.LastID, item.ID)
+	}
+
+	saved, err := st.saveFollow(ctx, f, cfg.MaxFollows)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to save follow")
+		return
+	}
+
+	if !saved {
+		respondError(c, http.StatusServiceUnavailable, codeTooManyFollows, "too many follows; try again later")
+		return
+	}
+
+	respond(c, http.StatusCreated, f)
+}
+
+// handleGetFollow responds with a follow without its secret. The random ID is all it takes to read
+// or delete a follow.
+func handleGetFollow(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	f, found, err := st.follow(c.Request.Context(), c.Param("id"), time.Now())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read follow")
+		return
+	}
+
+	if !found {
+		respondError(c, http.StatusNotFound, codeFollowNotFound, "follow not found")
+		return
+	}
+
+	f.Secret = ""
+
+	respond(c, http.StatusOK, f)
+}
+
+// handleDeleteFollow stops a follow and ends its event streams.
+func handleDeleteFollow(c *gin.Context, st *store, broker *eventBroker[followEvent]) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	deleted, err := st.deleteFollows(c.Request.Context(), c.Param("id"), time.Now())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to delete follow")
+		return
+	}
+
+	if len(deleted) == 0 {
+		respondError(c, http.StatusNotFound, codeFollowNotFound, "follow not found")
+		return
+	}
+
+	broker.close(deleted)
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleFollowEvents streams the events of a follow as server-sent events until the client goes
+// away, the follow is deleted or expires, or the server shuts down. A comment is sent when there
+// have been no events for a while.
+func handleFollowEvents(ctx context.Context, c *gin.Context, st *store, broker *eventBroker[followEvent]) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	_, found, err := st.follow(c.Request.Context(), c.Param("id"), time.Now())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read follow")
+		return
+	}
+
+	if !found {
+		respondError(c, http.StatusNotFound, codeFollowNotFound, "follow not found")
+		return
+	}
+
+	events, stop := broker.listen(c.Param("id"))
+	defer stop()
+
+	streamEvents(ctx, c, followEventItems, events)
+}
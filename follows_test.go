@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestFollowerTouched(t *testing.T) {
+	t.Parallel()
+
+	f := &follower{
+		client:    nil,
+		webhooks:  nil,
+		formatter: nil,
+		store:     nil,
+		broker:    nil,
+		threads:   map[int]map[int]bool{1: {1: true, 2: true, 3: true}},
+	}
+
+	tests := []struct {
+		changed map[int]bool
+		name    string
+		item    int
+		want    bool
+	}{
+		{changed: map[int]bool{}, name: "nothing changed", item: 1, want: false},
+		{changed: map[int]bool{4: true, 5: true}, name: "other threads changed", item: 1, want: false},
+		{changed: map[int]bool{4: true, 2: true}, name: "reply changed", item: 1, want: true},
+		{changed: map[int]bool{1: true}, name: "root changed", item: 1, want: true},
+		{changed: map[int]bool{}, name: "not fetched yet", item: 9, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := f.touched(tt.item, tt.changed); got != tt.want {
+				t.Errorf("touched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+type frontPageStory struct {
+	Title       string `json:"title"`
+	By          string `json:"by,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	SubmitAge   string `json:"submitAge"`
+	DisplayAge  string `json:"displayAge"`
+	SubmitTime  int64  `json:"submitTime"`
+	DisplayTime int64  `json:"displayTime"`
+	ID          int    `json:"id"`
+	Rank        int    `json:"rank,omitempty"`
+	Score       int    `json:"score"`
+	Descendants int    `json:"descendants"`
+}
+
+type handleFrontPageResponse struct {
+	Items []frontPageStory `json:"items"`
+}
+
+// getFrontPage returns the stories on the front page ordered by rank. Displayed times come from
+// the front-page scrape, which reveals second-chance stories whose time was adjusted, and ranks
+// come from the order of the official topstories list.
+func getFrontPage(
+	ctx context.Context, client *itemClient, httpClient *http.Client, now time.Time,
+) ([]frontPageStory, error) {
+	frontPageTimes, err := fetchFrontPageTimes(ctx, client, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch front page: %w", err)
+	}
+
+	top, err := fetchStoryIDs(ctx, httpClient, "topstories")
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(frontPageTimes))
+	for id := range frontPageTimes {
+		ids = append(ids, id)
+	}
+
+	items, err := fetchItems(ctx, client, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	stories := make([]frontPageStory, 0, len(ids))
+
+	for id, displayTime := range frontPageTimes {
+		item, ok := items[id]
+		if !ok || item == nil {
+			continue
+		}
+
+		stories = append(stories, frontPageStory{
+			Title:       item.Title,
+			By:          item.By,
+			URL:         item.URL,
+			Domain:      storyDomain(item.URL),
+			SubmitAge:   unl.PrettyFormatDuration(now.Sub(time.Unix(item.Time, 0))),
+			DisplayAge:  unl.PrettyFormatDuration(now.Sub(time.Unix(displayTime, 0))),
+			SubmitTime:  item.Time,
+			DisplayTime: displayTime,
+			ID:          item.ID,
+			Rank:        slices.Index(top, item.ID) + 1,
+			Score:       item.Score,
+			Descendants: item.Descendants,
+		})
+	}
+
+	slices.SortFunc(stories, func(a, b frontPageStory) int {
+		// stories missing from topstories have rank 0 and go last
+		if (a.Rank == 0) != (b.Rank == 0) {
+			return b.Rank - a.Rank
+		}
+
+		return a.Rank - b.Rank
+	})
+
+	return stories, nil
+}
+
+// handleSecondChance lists the front-page stories whose displayed time differs from their submit
+// time, which is how stories from the second-chance pool appear.
+func handleSecondChance(c *gin.Context, client *itemClient, httpClient *http.Client) {
+	stories, err := getFrontPage(c.Request.Context(), client, httpClient, time.Now())
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve front page")
+		return
+	}
+
+	stories = slices.DeleteFunc(stories, func(s frontPageStory) bool { return s.DisplayTime == s.SubmitTime })
+
+	respond(c, http.StatusOK, handleFrontPageResponse{Items: stories})
+}
+
+// handleFrontPage returns the current front page with ranks and both submit and displayed times.
+func handleFrontPage(c *gin.Context, client *itemClient, httpClient *http.Client) {
+	stories, err := getFrontPage(c.Request.Context(), client, httpClient, time.Now())
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve front page")
+		return
+	}
+
+	respond(c, http.StatusOK, handleFrontPageResponse{Items: stories})
+}
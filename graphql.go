@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+type graphQLRequest struct {
+	Variables     map[string]any `json:"variables"`
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+}
+
+// newGraphQLSchema exposes items, their trees, the active roots, and users as a graph. Fields that
+// need another fetch, such as kids, parent, and tree, are only resolved when a query selects them.
+func newGraphQLSchema(
+	client *itemClient,
+	httpClient *http.Client,
+	source *activeSource,
+	formatter *textFormatter,
+) (graphql.Schema, error) {
+	itemType := newGraphQLItemType(client, formatter)
+	userType := newGraphQLUserType(client, itemType)
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: newGraphQLQueryType(client, httpClient, source, itemType, userType),
+	})
+	if err != nil {
+		return graphql.Schema{}, fmt.Errorf("failed to create GraphQL schema: %w", err)
+	}
+
+	return schema, nil
+}
+
+func newGraphQLItemType(client *itemClient, formatter *textFormatter) *graphql.Object {
+	var itemType *graphql.Object
+
+	treeEntryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TreeEntry",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"depth": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						entry, _ := p.Source.(treeEntry)
+						return entry.Depth, nil
+					},
+				},
+				"item": &graphql.Field{
+					Type: graphql.NewNonNull(itemType),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						entry, _ := p.Source.(treeEntry)
+						return entry.Item, nil
+					},
+				},
+			}
+		}),
+	})
+
+	itemType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":          itemField(graphql.NewNonNull(graphql.Int), func(i *hn.Item) any { return i.ID }),
+				"type":        itemField(graphql.String, func(i *hn.Item) any { return i.Type }),
+				"by":          itemField(graphql.String, func(i *hn.Item) any { return i.By }),
+				"time":        itemField(graphql.Int, func(i *hn.Item) any { return i.Time }),
+				"title":       itemField(graphql.String, func(i *hn.Item) any { return i.Title }),
+				"url":         itemField(graphql.String, func(i *hn.Item) any { return i.URL }),
+				"domain":      itemField(graphql.String, func(i *hn.Item) any { return storyDomain(i.URL) }),
+				"score":       itemField(graphql.Int, func(i *hn.Item) any { return i.Score }),
+				"descendants": itemField(graphql.Int, func(i *hn.Item) any { return i.Descendants }),
+				"dead":        itemField(graphql.Boolean, func(i *hn.Item) any { return i.Dead }),
+				"deleted":     itemField(graphql.Boolean, func(i *hn.Item) any { return i.Deleted }),
+				"age": itemField(graphql.String, func(i *hn.Item) any {
+					return unl.PrettyFormatDuration(time.Since(time.Unix(i.Time, 0)))
+				}),
+				"text": itemField(graphql.String, func(i *hn.Item) any { return formatter.format(i) }),
+				"parent": fetchItemField(itemType, func(ctx context.Context, i *hn.Item) (any, error) {
+					return graphQLItem(ctx, client, i.Parent)
+				}),
+				"kids": fetchItemField(graphql.NewList(graphql.NewNonNull(itemType)),
+					func(ctx context.Context, i *hn.Item) (any, error) { return graphQLItems(ctx, client, i.Kids) }),
+				"tree": &graphql.Field{
+					Type:        graphql.NewList(graphql.NewNonNull(treeEntryType)),
+					Description: "The item and all of its descendants in display order",
+					Args: graphql.FieldConfigArgument{
+						"maxDepth": &graphql.ArgumentConfig{Type: graphql.Int},
+					},
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						item, _ := p.Source.(*hn.Item)
+
+						maxDepth, ok := p.Args["maxDepth"].(int)
+						if !ok {
+							maxDepth = -1
+						}
+
+						entries, err := getTree(p.Context, client, item)
+						if err != nil {
+							return nil, err
+						}
+
+						kept := entries[:0]
+						for _, entry := range entries {
+							if maxDepth < 0 || entry.Depth <= maxDepth {
+								kept = append(kept, entry)
+							}
+						}
+
+						budget, _ := p.Context.Value(graphQLBudgetKey{}).(*graphQLBudget)
+
+						return budget.take(kept), nil
+					},
+				},
+			}
+		}),
+	})
+
+	return itemType
+}
+
+func newGraphQLUserType(client *itemClient, itemType *graphql.Object) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":      userField(graphql.NewNonNull(graphql.String), func(u *hnUser) any { return u.ID }),
+			"about":   userField(graphql.String, func(u *hnUser) any { return u.About }),
+			"created": userField(graphql.Int, func(u *hnUser) any { return u.Created }),
+			"karma":   userField(graphql.Int, func(u *hnUser) any { return u.Karma }),
+			"submitted": &graphql.Field{
+				Type:        graphql.NewList(graphql.NewNonNull(itemType)),
+				Description: "Stories and comments, newest first",
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultUserLimit},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					user, _ := p.Source.(*hnUser)
+
+					limit, _ := p.Args["limit"].(int)
+					limit = max(0, min(limit, maxUserLimit, len(user.Submitted)))
+
+					return graphQLItems(p.Context, client, user.Submitted[:limit])
+				},
+			},
+		},
+	})
+}
+
+func newGraphQLQueryType(
+	client *itemClient,
+	httpClient *http.Client,
+	source *activeSource,
+	itemType *graphql.Object,
+	userType *graphql.Object,
+) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"item": &graphql.Field{
+				Type: itemType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, _ := p.Args["id"].(int)
+
+					return graphQLItem(p.Context, client, id)
+				},
+			},
+			"items": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(itemType)),
+				Args: graphql.FieldConfigArgument{
+					"ids": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.Int)))},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					raw, _ := p.Args["ids"].([]any)
+
+					ids := make([]int, 0, len(raw))
+					for _, v := range raw {
+						id, _ := v.(int)
+						ids = append(ids, id)
+					}
+
+					return graphQLItems(p.Context, client, ids)
+				},
+			},
+			"active": &graphql.Field{
+				Type:        graphql.NewList(graphql.NewNonNull(itemType)),
+				Description: "Stories with recent comment activity, as returned by /active",
+				Args: graphql.FieldConfigArgument{
+					"window": &graphql.ArgumentConfig{Type: graphql.String},
+					"maxAge": &graphql.ArgumentConfig{Type: graphql.String},
+					"minBy":  &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return graphQLActive(p, source)
+				},
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					name, _ := p.Args["name"].(string)
+
+					return fetchUser(p.Context, httpClient, name)
+				},
+			},
+		},
+	})
+}
+
+// graphQLItems returns the items that exist, in the order of ids.
+func graphQLItems(ctx context.Context, client *itemClient, ids []int) ([]*hn.Item, error) {
+	items, err := fetchItems(ctx, client, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*hn.Item, 0, len(ids))
+
+	for _, id := range ids {
+		item, ok := items[id]
+		if ok && item != nil {
+			result = append(result, item)
+		}
+	}
+
+	return result, nil
+}
+
+// graphQLItem returns the item, or nil if it does not exist.
+func graphQLItem(ctx context.Context, client *itemClient, id int) (*hn.Item, error) {
+	if id == 0 {
+		return nil, nil //nolint:nilnil // a missing item is null in GraphQL
+	}
+
+	items, err := graphQLItems(ctx, client, []int{id})
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+
+	return items[0], nil
+}
+
+func itemField(t graphql.Output, get func(*hn.Item) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			item, _ := p.Source.(*hn.Item)
+			if item == nil {
+				return nil, nil //nolint:nilnil // a null field is a valid GraphQL result
+			}
+
+			return get(item), nil
+		},
+	}
+}
+
+func fetchItemField(t graphql.Output, fetch func(context.Context, *hn.Item) (any, error)) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			item, _ := p.Source.(*hn.Item)
+			if item == nil {
+				return nil, nil //nolint:nilnil // a null field is a valid GraphQL result
+			}
+
+			return fetch(p.Context, item)
+		},
+	}
+}
+
+func userField(t graphql.Output, get func(*hnUser) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			user, _ := p.Source.(*hnUser)
+			if user == nil {
+				return nil, nil //nolint:nilnil // a null field is a valid GraphQL result
+			}
+
+			return get(user), nil
+		},
+	}
+}
+
+func graphQLActive(p graphql.ResolveParams, source *activeSource) ([]*hn.Item, error) {
+	// the defaults are not in the schema since a config reload can change them
+	defaults := source.Params()
+
+	windowArg, ok := p.Args["window"].(string)
+	if !ok {
+		windowArg = defaults.Window.String()
+	}
+
+	maxAgeArg, ok := p.Args["maxAge"].(string)
+	if !ok {
+		maxAgeArg = defaults.MaxAge.String()
+	}
+
+	minBy, ok := p.Args["minBy"].(int)
+	if !ok {
+		minBy = defaults.MinBy
+	}
+
+	window, err := parsePositiveDuration(windowArg, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window duration: %w", err)
+	}
+
+	maxAge, err := parsePositiveDuration(maxAgeArg, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maxAge duration: %w", err)
+	}
+
+	params := activeParams{Window: window, MaxAge: maxAge, MinBy: minBy}
+
+	err = source.Bounds().check(params)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := source.Get(p.Context, params)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := make([]*hn.Item, 0, len(snapshot.Roots))
+	for _, root := range snapshot.Roots {
+		roots = append(roots, root.Item)
+	}
+
+	return roots, nil
+}
+
+// graphQLBudget applies the response budget to the trees of one query, in the order they are
+// resolved, as /active applies it to its roots.
+type graphQLBudget struct {
+	roots *budgetedRoots
+	mu    sync.Mutex
+}
+
+type graphQLBudgetKey struct{}
+
+// take applies what is left of the budget to the entries of the next tree. A nil budget is
+// unlimited.
+func (b *graphQLBudget) take(entries []treeEntry) []treeEntry {
+	if b == nil {
+		return entries
+	}
+
+	items := make([]handleActiveResponseItem, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, newBudgetEntry(entry))
+	}
+
+	b.mu.Lock()
+	kept := b.roots.take(items)
+	b.mu.Unlock()
+
+	ids := make(map[int]bool, len(kept))
+	for _, item := range kept {
+		ids[item.ID] = true
+	}
+
+	return slices.DeleteFunc(entries, func(entry treeEntry) bool { return !ids[entry.Item.ID] })
+}
+
+// newBudgetEntry returns what the budget needs of a tree entry to measure it.
+func newBudgetEntry(entry treeEntry) handleActiveResponseItem {
+	return handleActiveResponseItem{
+		storyMetadata:     nil,
+		Metrics:           nil,
+		By:                entry.Item.By,
+		Text:              entry.Item.Text,
+		Age:               "",
+		TimeISO:           "",
+		Children:          nil,
+		AccountAgeDays:    nil,
+		Karma:             nil,
+		ActiveWindows:     nil,
+		ActivityScore:     0,
+		Time:              entry.Item.Time,
+		ID:                entry.Item.ID,
+		Parent:            entry.Item.Parent,
+		Root:              0,
+		Depth:             entry.Depth,
+		TruncatedChildren: 0,
+		Conversation:      0,
+		Active:            false,
+		Collapsed:         false,
+		SecondChance:      false,
+		OP:                false,
+		Dead:              entry.Item.Dead,
+		Deleted:           entry.Item.Deleted,
+		IsNew:             false,
+		NewUser:           false,
+		Followed:          false,
+	}
+}
+
+// handleGraphQL executes a query sent either as a JSON POST body or in the query string of a GET.
+// The trees in the result share the response budget, and what was left out of them is reported
+// in extensions.truncation like the truncated fields of /active.
+func handleGraphQL(c *gin.Context, schema graphql.Schema, budget responseBudget) {
+	req := graphQLRequest{
+		Variables:     nil,
+		Query:         c.Query("query"),
+		OperationName: c.Query("operationName"),
+	}
+
+	if c.Request.Method == http.MethodPost {
+		err := json.NewDecoder(c.Request.Body).Decode(&req)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, codeInvalidQuery, "invalid GraphQL request body")
+			return
+		}
+	} else if variables := c.Query("variables"); variables != "" {
+		err := json.Unmarshal([]byte(variables), &req.Variables)
+		if err != nil {
+			respondParamError(c, codeInvalidQuery, "variables", "invalid variables")
+			return
+		}
+	}
+
+	if req.Query == "" {
+		respondParamError(c, codeInvalidQuery, "query", "missing query")
+		return
+	}
+
+	budgeted := &graphQLBudget{roots: newBudgetedRoots(budget), mu: sync.Mutex{}}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(c.Request.Context(), graphQLBudgetKey{}, budgeted),
+	})
+
+	if truncation := budgeted.roots.truncation(); truncation != nil {
+		result.Extensions = map[string]any{"truncation": truncation}
+	}
+
+	respond(c, http.StatusOK, result)
+}
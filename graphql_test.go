@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+func graphQLTreeEntry(id, depth int) treeEntry {
+	item := &hn.Item{
+		ID:          id,
+		Deleted:     false,
+		Type:        "comment",
+		By:          "",
+		Time:        0,
+		Text:        "",
+		Dead:        false,
+		Parent:      0,
+		Poll:        0,
+		Kids:        nil,
+		URL:         "",
+		Score:       0,
+		Title:       "",
+		Parts:       nil,
+		Descendants: 0,
+	}
+
+	return treeEntry{Item: item, Depth: depth}
+}
+
+func TestGraphQLBudgetTake(t *testing.T) {
+	t.Parallel()
+
+	tree := func(root int) []treeEntry {
+		return []treeEntry{
+			graphQLTreeEntry(root, 0),
+			graphQLTreeEntry(root+1, 1),
+			graphQLTreeEntry(root+2, 2),
+		}
+	}
+
+	budget := &graphQLBudget{roots: newBudgetedRoots(responseBudget{MaxBytes: 0, MaxItems: 4}), mu: sync.Mutex{}}
+
+	var ids []int
+
+	for _, root := range []int{10, 20} {
+		for _, entry := range budget.take(tree(root)) {
+			ids = append(ids, entry.Item.ID)
+		}
+	}
+
+	if want := []int{10, 11, 12, 20}; !slices.Equal(ids, want) {
+		t.Errorf("entries %v, want %v", ids, want)
+	}
+
+	want := responseTruncation{Truncated: true, TotalItems: 6, ReturnedItems: 4, OmittedItems: 2}
+	if got := budget.roots.truncation(); got == nil || *got != want {
+		t.Errorf("truncation %v, want %v", got, want)
+	}
+
+	var unlimited *graphQLBudget
+	if got := unlimited.take(tree(30)); len(got) != 3 {
+		t.Errorf("%d entries without a budget, want 3", len(got))
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type activeGroup struct {
+	Items  any    `json:"items"`
+	Domain string `json:"domain"`
+}
+
+type handleActiveGroupedResponse struct {
+	*responseTruncation
+	Groups             []activeGroup `json:"groups"`
+	SecondChanceFailed bool          `json:"secondChanceFailed"`
+	Stale              bool          `json:"stale,omitempty"`
+}
+
+// parseGroupByDomain reads the group-by query parameter, which is either absent or "domain".
+func parseGroupByDomain(c *gin.Context) (bool, bool) {
+	param, ok := c.GetQuery("group-by")
+	if !ok {
+		return false, true
+	}
+
+	return param == "domain", param == "domain"
+}
+
+func shapeActiveItems(items []handleActiveResponseItem, nested bool, fields fieldSet) any {
+	switch {
+	case fields != nil && nested:
+		return fields.pick(nestActiveItems(items))
+	case fields != nil:
+		return fields.pick(items)
+	case nested:
+		return nestActiveItems(items)
+	default:
+		return items
+	}
+}
+
+// respondActiveGroups responds with the items of each root grouped by the domain of the root, in
+// the order each domain first appears. Text posts are grouped under an empty domain. Each group
+// has the shape the items would have had without grouping.
+func respondActiveGroups(
+	c *gin.Context,
+	items []handleActiveResponseItem,
+	nested bool,
+	fields fieldSet,
+	snapshot *activeSnapshot,
+	truncation *responseTruncation,
+) {
+	var order []string
+
+	byDomain := make(map[string][]handleActiveResponseItem)
+	domain := ""
+
+	for _, item := range items {
+		if item.Depth == 0 {
+			domain = ""
+			if item.storyMetadata != nil {
+				domain = item.Domain
+			}
+
+			if _, ok := byDomain[domain]; !ok {
+				order = append(order, domain)
+			}
+		}
+
+		byDomain[domain] = append(byDomain[domain], item)
+	}
+
+	groups := make([]activeGroup, 0, len(order))
+
+	for _, domain := range order {
+		groups = append(groups, activeGroup{Items: shapeActiveItems(byDomain[domain], nested, fields), Domain: domain})
+	}
+
+	respond(c, http.StatusOK, handleActiveGroupedResponse{
+		Groups:             groups,
+		SecondChanceFailed: snapshot.SecondChanceFailed,
+		Stale:              snapshot.Stale,
+		responseTruncation: truncation,
+	})
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jasonthorsness/unlurker-web/backend/unlurkerpb"
+	"github.com/jasonthorsness/unlurker/hn"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer implements the Unlurker gRPC service over the same data as the HTTP handlers.
+type grpcServer struct {
+	unlurkerpb.UnimplementedUnlurkerServer
+
+	client    *itemClient
+	source    *activeSource
+	formatter *textFormatter
+	live      *liveConfig
+}
+
+func newGRPCServer(
+	client *itemClient,
+	source *activeSource,
+	formatter *textFormatter,
+	live *liveConfig,
+) *grpcServer {
+	return &grpcServer{
+		UnimplementedUnlurkerServer: unlurkerpb.UnimplementedUnlurkerServer{},
+		client:                      client,
+		source:                      source,
+		formatter:                   formatter,
+		live:                        live,
+	}
+}
+
+// serveGRPC runs the gRPC server on the gRPC port until ctx is done, then stops it the same way
+// serve stops the HTTP server.
+func serveGRPC(ctx context.Context, stop context.CancelFunc, impl unlurkerpb.UnlurkerServer, cfg config) {
+	addr := net.JoinHostPort(cfg.Addr, strconv.Itoa(cfg.GRPCPort))
+
+	var lc net.ListenConfig
+
+	lis, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		log.Printf("failed to start gRPC server: %v", err)
+		stop()
+
+		return
+	}
+
+	server := grpc.NewServer()
+	unlurkerpb.RegisterUnlurkerServer(server, impl)
+
+	go func() {
+		log.Printf("gRPC listening on %s", addr)
+
+		serr := server.Serve(lis)
+		if serr != nil && !errors.Is(serr, grpc.ErrServerStopped) {
+			log.Printf("failed to start gRPC server: %v", serr)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+
+	stopped := make(chan struct{})
+
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(cfg.ShutdownTimeout):
+		log.Printf("failed to shut down gRPC server gracefully")
+		server.Stop()
+	}
+}
+
+func (s *grpcServer) Active(ctx context.Context, req *unlurkerpb.ActiveRequest) (*unlurkerpb.ActiveResponse, error) {
+	defaults := s.source.Params()
+
+	window, err := parsePositiveDuration(stringOrDefault(req.GetWindow(), defaults.Window.String()), time.Second)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid window duration: "+err.Error())
+	}
+
+	maxAge, err := parsePositiveDuration(stringOrDefault(req.GetMaxAge(), defaults.MaxAge.String()), time.Second)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid max_age duration: "+err.Error())
+	}
+
+	minBy := defaults.MinBy
+	if req.MinBy != nil {
+		minBy = int(req.GetMinBy())
+	}
+
+	maxDepth, err := grpcMaxDepth(req.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	params := activeParams{Window: window, MaxAge: maxAge, MinBy: minBy}
+
+	err = s.source.Bounds().check(params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	now := time.Now()
+
+	snapshot, err := s.source.Get(ctx, params)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	cfg := s.live.Load()
+	roots := newBudgetedRoots(cfg.responseBudget())
+
+	items := joinRoots(slices.Collect(roots.all(activeRoots(snapshot, s.formatter, now, activeItemOptions{
+		Blocked:  blockedSet(cfg.Block),
+		Seen:     seenWatermarks{ByRoot: nil, Default: 0},
+		MaxDepth: maxDepth,
+		Format:   timeFormatUnix,
+		Text:     textModeHTML,
+		Activity: activityWindow,
+		Me:       "",
+		Windows:  nil,
+		Profiles: nil,
+		Sort:     commentSortRank,
+		ShowUser: !req.GetHideUser(),
+		ShowDead: false,
+		Only:     onlyAll,
+		Follow:   nil,
+		Followed: nil,
+		Enrich:   enrichment{Karma: false},
+	}))))
+
+	response := &unlurkerpb.ActiveResponse{
+		Items:              make([]*unlurkerpb.Item, 0, len(items)),
+		SecondChanceFailed: snapshot.SecondChanceFailed,
+		Truncation:         newGRPCTruncation(roots.truncation()),
+	}
+
+	for _, item := range items {
+		response.Items = append(response.Items, &unlurkerpb.Item{
+			Id:                int64(item.ID),
+			By:                item.By,
+			Text:              item.Text,
+			Time:              item.Time,
+			Depth:             int32(item.Depth),             //nolint:gosec // depths are small
+			TruncatedChildren: int32(item.TruncatedChildren), //nolint:gosec // counts are small
+			Story:             newGRPCStory(item.storyMetadata),
+			Active:            item.Active,
+			SecondChance:      item.SecondChance,
+		})
+	}
+
+	return response, nil
+}
+
+// newGRPCTruncation returns the truncation of a response as a message, or nil if nothing was left
+// out.
+func newGRPCTruncation(t *responseTruncation) *unlurkerpb.Truncation {
+	if t == nil {
+		return nil
+	}
+
+	return &unlurkerpb.Truncation{
+		Truncated:     t.Truncated,
+		TotalItems:    int32(t.TotalItems),    //nolint:gosec // counts are small
+		ReturnedItems: int32(t.ReturnedItems), //nolint:gosec // counts are small
+		OmittedItems:  int32(t.OmittedItems),  //nolint:gosec // counts are small
+	}
+}
+
+func (s *grpcServer) ItemTree(
+	req *unlurkerpb.ItemTreeRequest,
+	stream grpc.ServerStreamingServer[unlurkerpb.Item],
+) error {
+	ctx := stream.Context()
+
+	maxDepth, err := grpcMaxDepth(req.MaxDepth)
+	if err != nil {
+		return err
+	}
+
+	_, item, err := getItem(ctx, s.client, int(req.GetId()))
+	if err != nil {
+		return grpcItemError(err)
+	}
+
+	entries, err := getTree(ctx, s.client, item)
+	if err != nil {
+		return grpcItemError(err)
+	}
+
+	items := make([]*hn.Item, 0, len(entries))
+	depths := make([]int, 0, len(entries))
+
+	for _, entry := range entries {
+		items = append(items, entry.Item)
+		depths = append(depths, entry.Depth)
+	}
+
+	unblocked := pruneBlocked(items, depths, blockedSet(s.live.Load().Block))
+	entries, depths = keepOnly(entries, unblocked), keepOnly(depths, unblocked)
+
+	keep, truncated := truncateDepth(depths, maxDepth)
+
+	for i, entry := range entries {
+		if !keep[i] {
+			continue
+		}
+
+		err = stream.Send(s.newGRPCItem(entry, req.GetHideUser(), truncated[i]))
+		if err != nil {
+			return fmt.Errorf("failed to send item: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *grpcServer) Ancestors(
+	ctx context.Context,
+	req *unlurkerpb.AncestorsRequest,
+) (*unlurkerpb.AncestorsResponse, error) {
+	chain, err := getAncestors(ctx, s.client, int(req.GetId()))
+	if err != nil {
+		return nil, grpcItemError(err)
+	}
+
+	response := &unlurkerpb.AncestorsResponse{Items: make([]*unlurkerpb.Item, 0, len(chain))}
+
+	for depth, item := range chain {
+		response.Items = append(response.Items, s.newGRPCItem(treeEntry{Item: item, Depth: depth}, req.GetHideUser(), 0))
+	}
+
+	return response, nil
+}
+
+func (s *grpcServer) newGRPCItem(entry treeEntry, hideUser bool, truncated int) *unlurkerpb.Item {
+	by := entry.Item.By
+	if hideUser {
+		by = ""
+	}
+
+	var story *unlurkerpb.Story
+	if entry.Depth == 0 {
+		story = newGRPCStory(newStoryMetadata(entry.Item))
+	}
+
+	return &unlurkerpb.Item{
+		Id:                int64(entry.Item.ID),
+		By:                by,
+		Text:              s.formatter.format(entry.Item),
+		Time:              entry.Item.Time,
+		Depth:             int32(entry.Depth), //nolint:gosec // depths are small
+		TruncatedChildren: int32(truncated),   //nolint:gosec // counts are small
+		Story:             story,
+		Active:            false,
+		SecondChance:      false,
+	}
+}
+
+func newGRPCStory(story *storyMetadata) *unlurkerpb.Story {
+	if story == nil {
+		return nil
+	}
+
+	return &unlurkerpb.Story{
+		Type:        story.Type,
+		Url:         story.URL,
+		Domain:      story.Domain,
+		Score:       int32(story.Score),       //nolint:gosec // scores fit in 32 bits
+		Descendants: int32(story.Descendants), //nolint:gosec // counts fit in 32 bits
+	}
+}
+
+// grpcMaxDepth converts an optional max_depth to the convention of parseMaxDepth.
+func grpcMaxDepth(maxDepth *int32) (int, error) {
+	if maxDepth == nil {
+		return -1, nil
+	}
+
+	if *maxDepth < 0 {
+		return 0, status.Error(codes.InvalidArgument, "invalid max_depth")
+	}
+
+	return int(*maxDepth), nil
+}
+
+// grpcItemError maps an error from getItem to the equivalent of respondItemError.
+func grpcItemError(err error) error {
+	if errors.Is(err, errItemNotFound) {
+		return status.Error(codes.NotFound, "item not found")
+	}
+
+	return status.Error(codes.Unavailable, "failed to retrieve item")
+}
+
+func stringOrDefault(s string, def string) string {
+	if s == "" {
+		return def
+	}
+
+	return s
+}
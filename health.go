@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	readyCheckTimeout = 2 * time.Second
+	// precomputeStallFactor is how many precompute intervals may pass without a refresh before
+	// precomputing is considered stalled.
+	precomputeStallFactor = 3
+)
+
+type readyCheck struct {
+	Message string `json:"message,omitempty"`
+	OK      bool   `json:"ok"`
+}
+
+type handleReadyzResponse struct {
+	Checks map[string]readyCheck `json:"checks"`
+	Status string                `json:"status"`
+}
+
+type handleHealthzResponse struct {
+	Status string `json:"status"`
+}
+
+// readiness checks whether the server can do its work: write to its SQLite databases, reach the
+// HN API, and keep the default /active snapshot fresh.
+type readiness struct {
+	source   *activeSource
+	store    *store
+	upstream *limitedTransport
+	live     *liveConfig
+}
+
+// handleHealthz responds as long as the process can serve requests at all.
+func handleHealthz(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	respond(c, http.StatusOK, handleHealthzResponse{Status: "ok"})
+}
+
+// handleReadyz runs every readiness check and responds with 503 if any of them failed.
+func (r *readiness) handleReadyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readyCheckTimeout)
+	defer cancel()
+
+	checks := map[string]readyCheck{
+		"itemCache":  newReadyCheck(withItemCache(ctx, r.live.Load(), checkWritable)),
+		"hnApi":      r.checkUpstream(),
+		"precompute": r.checkPrecompute(),
+	}
+
+	if r.store != nil {
+		checks["store"] = newReadyCheck(checkWritable(ctx, r.store.db))
+	}
+
+	status, code := "ok", http.StatusOK
+
+	for _, check := range checks {
+		if !check.OK {
+			status, code = "fail", http.StatusServiceUnavailable
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	respond(c, code, handleReadyzResponse{Checks: checks, Status: status})
+}
+
+func newReadyCheck(_ struct{}, err error) readyCheck {
+	if err != nil {
+		return readyCheck{Message: err.Error(), OK: false}
+	}
+
+	return readyCheck{Message: "", OK: true}
+}
+
+// checkUpstream fails if no request to the HN API has succeeded within the configured window.
+func (r *readiness) checkUpstream() readyCheck {
+	last := r.upstream.LastSuccess()
+	if time.Since(last) > r.live.Load().ReadyHNWindow {
+		return readyCheck{Message: "no successful request since " + last.UTC().Format(time.RFC3339), OK: false}
+	}
+
+	return readyCheck{Message: "", OK: true}
+}
+
+// checkPrecompute fails if the default /active snapshot has not been refreshed for several
+// intervals, which means the background loop is stuck. It passes if precomputing is disabled.
+func (r *readiness) checkPrecompute() readyCheck {
+	interval := r.source.Interval()
+	if interval <= 0 {
+		return readyCheck{Message: "disabled", OK: true}
+	}
+
+	last := r.source.Refreshed()
+	if time.Since(last) > precomputeStallFactor*interval {
+		return readyCheck{Message: "not refreshed since " + last.UTC().Format(time.RFC3339), OK: false}
+	}
+
+	return readyCheck{Message: "", OK: true}
+}
+
+// checkWritable takes and releases the write lock of a SQLite database without writing to it.
+func checkWritable(ctx context.Context, db *sql.DB) (struct{}, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return struct{}{}, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	_, err = conn.ExecContext(ctx, `BEGIN IMMEDIATE`)
+	if err != nil {
+		return struct{}{}, fmt.Errorf("failed to lock for writing: %w", err)
+	}
+
+	_, err = conn.ExecContext(ctx, `ROLLBACK`)
+	if err != nil {
+		return struct{}{}, fmt.Errorf("failed to release write lock: %w", err)
+	}
+
+	return struct{}{}, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultHistoryRange = 24 * time.Hour
+	maxHistoryRange     = 7 * 24 * time.Hour
+)
+
+type archivedRoot struct {
+	Title            string `json:"title"`
+	Time             int64  `json:"time"`
+	ID               int    `json:"id"`
+	Score            int    `json:"score"`
+	Descendants      int    `json:"descendants"`
+	ActiveComments   int    `json:"activeComments"`
+	ActiveCommenters int    `json:"activeCommenters"`
+}
+
+type archivedSnapshot struct {
+	Roots              []archivedRoot `json:"roots"`
+	Time               int64          `json:"time"`
+	ActiveComments     int            `json:"activeComments"`
+	SecondChanceFailed bool           `json:"secondChanceFailed"`
+}
+
+// archiveSnapshot stores the roots of a precomputed snapshot with their scores and activity so
+// the busiest times of day can be charted later.
+func (s *store) archiveSnapshot(ctx context.Context, snapshot *activeSnapshot) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin archiving snapshot: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO snapshots (time, second_chance_failed) VALUES (?, ?)`,
+		snapshot.Time.Unix(), snapshot.SecondChanceFailed)
+	if err != nil {
+		return fmt.Errorf("failed to archive snapshot: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to archive snapshot: %w", err)
+	}
+
+	activeAfter := snapshot.Time.Add(-snapshot.Params.Window)
+
+	for _, root := range snapshot.Roots {
+		items, depths := flattenRoot(root.Item, snapshot.Tree)
+		metrics := newRootMetrics(items, depths, activeAfter, snapshot.Time)
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO snapshot_roots
+			(snapshot, id, time, title, score, descendants, active_comments, active_commenters)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, root.Item.ID, root.Time, root.Item.Title, root.Item.Score, root.Item.Descendants,
+			metrics.ActiveComments, metrics.ActiveCommenters)
+		if err != nil {
+			return fmt.Errorf("failed to archive root %d: %w", root.Item.ID, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// archivedSnapshots returns the archived snapshots taken from from to to, oldest first, with
+// their roots ordered by active comments.
+func (s *store) archivedSnapshots(ctx context.Context, from time.Time, to time.Time) ([]archivedSnapshot, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT s.id, s.time, s.second_chance_failed,
+			COALESCE(r.id, 0), COALESCE(r.time, 0), COALESCE(r.title, ''), COALESCE(r.score, 0),
+			COALESCE(r.descendants, 0), COALESCE(r.active_comments, 0), COALESCE(r.active_commenters, 0)
+		FROM snapshots s LEFT JOIN snapshot_roots r ON r.snapshot = s.id
+		WHERE s.time >= ? AND s.time <= ?
+		ORDER BY s.time, s.id, r.active_comments DESC, r.id`, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	snapshots := make([]archivedSnapshot, 0)
+	lastID := int64(-1)
+
+	for rows.Next() {
+		var (
+			id       int64
+			snapshot archivedSnapshot
+			root     archivedRoot
+		)
+
+		err = rows.Scan(&id, &snapshot.Time, &snapshot.SecondChanceFailed,
+			&root.ID, &root.Time, &root.Title, &root.Score, &root.Descendants,
+			&root.ActiveComments, &root.ActiveCommenters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+
+		if id != lastID {
+			snapshot.Roots = make([]archivedRoot, 0)
+			snapshots = append(snapshots, snapshot)
+			lastID = id
+		}
+
+		// snapshots without roots have one row with zero values from the outer join
+		if root.ID != 0 {
+			last := &snapshots[len(snapshots)-1]
+			last.Roots = append(last.Roots, root)
+			last.ActiveComments += root.ActiveComments
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+type handleHistoryActiveResponse struct {
+	Snapshots []archivedSnapshot `json:"snapshots"`
+}
+
+// parseTimeRange reads the from and to query parameters as RFC 3339 times, which default to the
+// last day and may be at most a week apart, responding with an error and returning false if either
+// is invalid.
+func parseTimeRange(c *gin.Context) (time.Time, time.Time, bool) {
+	var invalid time.Time
+
+	to := time.Now()
+
+	if param := c.Query("to"); param != "" {
+		var err error
+
+		to, err = time.Parse(time.RFC3339, param)
+		if err != nil {
+			respondParamError(c, codeInvalidTo, "to", "invalid to")
+			return invalid, invalid, false
+		}
+	}
+
+	from := to.Add(-defaultHistoryRange)
+
+	if param := c.Query("from"); param != "" {
+		var err error
+
+		from, err = time.Parse(time.RFC3339, param)
+		if err != nil || from.After(to) || to.Sub(from) > maxHistoryRange {
+			respondParamError(c, codeInvalidFrom, "from", "invalid from")
+			return invalid, invalid, false
+		}
+	}
+
+	return from, to, true
+}
+
+// handleHistoryActive responds with the archived snapshots of the default /active set taken
+// between from and to.
+func handleHistoryActive(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	from, to, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	snapshots, err := st.archivedSnapshots(c.Request.Context(), from, to)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read snapshots")
+		return
+	}
+
+	respond(c, http.StatusOK, handleHistoryActiveResponse{Snapshots: snapshots})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const hnAPIBaseURL = "https://hacker-news.firebaseio.com/v0/"
+
+var errUnexpectedStatus = errors.New("unexpected status")
+
+// fetchHNJSON decodes the JSON document at the given path of the official HN API into v, fetched
+// with the HN HTTP client.
+func fetchHNJSON(ctx context.Context, httpClient *http.Client, path string, v any) error {
+	return fetchJSON(ctx, httpClient, hnAPIBaseURL+path, v)
+}
+
+// fetchJSON decodes the JSON document at rawURL, fetched with httpClient, into v.
+func fetchJSON(ctx context.Context, httpClient *http.Client, rawURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s: %w", rawURL, err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w fetching %s: %d", errUnexpectedStatus, rawURL, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", rawURL, err)
+	}
+
+	return nil
+}
+
+// fetchStoryIDs returns the IDs in one of the HN story lists such as "newstories".
+func fetchStoryIDs(ctx context.Context, httpClient *http.Client, list string) ([]int, error) {
+	var ids []int
+
+	err := fetchHNJSON(ctx, httpClient, list+".json", &ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+type hnUser struct {
+	ID        string `json:"id"`
+	About     string `json:"about"`
+	Submitted []int  `json:"submitted"`
+	Created   int64  `json:"created"`
+	Karma     int    `json:"karma"`
+}
+
+// fetchUser returns the public profile of an HN user or nil if the user does not exist.
+func fetchUser(ctx context.Context, httpClient *http.Client, name string) (*hnUser, error) {
+	var user *hnUser
+
+	err := fetchHNJSON(ctx, httpClient, "user/"+url.PathEscape(name)+".json", &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+)
+
+// limitedTransport bounds the requests to one host, both how many are open at once and how many
+// start per second, so a burst of requests for huge threads does not get the server throttled.
+// Requests to other hosts pass through unchanged.
+type limitedTransport struct {
+	next        http.RoundTripper
+	sem         *semaphore.Weighted
+	limiter     *rate.Limiter
+	host        string
+	lastSuccess atomic.Int64
+}
+
+// newLimitedTransport limits the requests next makes to host to concurrency at a time and perSecond
+// per second with bursts of up to burst. A perSecond of 0 does not limit the rate.
+func newLimitedTransport(
+	next http.RoundTripper, host string, concurrency int, perSecond float64, burst int,
+) *limitedTransport {
+	limit := rate.Limit(perSecond)
+	if perSecond <= 0 {
+		limit = rate.Inf
+	}
+
+	t := &limitedTransport{
+		next:        next,
+		sem:         semaphore.NewWeighted(int64(concurrency)),
+		limiter:     rate.NewLimiter(limit, burst),
+		host:        host,
+		lastSuccess: atomic.Int64{},
+	}
+
+	// count starting up as a success so the server is not reported unready before its first request
+	t.lastSuccess.Store(time.Now().UnixNano())
+
+	return t
+}
+
+// SetRate changes the rate requests to the host may start at, as for newLimitedTransport.
+func (t *limitedTransport) SetRate(perSecond float64, burst int) {
+	limit := rate.Limit(perSecond)
+	if perSecond <= 0 {
+		limit = rate.Inf
+	}
+
+	t.limiter.SetLimit(limit)
+	t.limiter.SetBurst(burst)
+}
+
+// LastSuccess returns when a request to the host last got a response other than 429 or 5xx.
+func (t *limitedTransport) LastSuccess() time.Time {
+	return time.Unix(0, t.lastSuccess.Load())
+}
+
+// newHNHTTPClient returns the HTTP client for the official HN API, which the HN client and
+// fetchHNJSON are given, routing its requests through a retryingTransport and a limitedTransport.
+// Retries are outside the limits so each attempt counts against them and backing off does not hold
+// a connection. The limitedTransport is returned so the health of the API can be checked.
+func newHNHTTPClient(cfg config) (*http.Client, *limitedTransport) {
+	u, _ := url.Parse(hnAPIBaseURL)
+
+	limited := newLimitedTransport(http.DefaultTransport, u.Host, cfg.HNConcurrency, cfg.HNRate, cfg.HNBurst)
+
+	return &http.Client{Transport: &retryingTransport{next: limited, host: u.Host, retries: cfg.HNRetries}}, limited
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.next.RoundTrip(req) //nolint:wrapcheck // plain wrapper
+	}
+
+	ctx, span := startSpan(req.Context(), "HN "+req.Method, semconv.URLPath(req.URL.Path))
+	defer span.End()
+
+	req = req.WithContext(ctx)
+
+	countUpstreamFetch(ctx)
+
+	err := t.sem.Acquire(ctx, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed waiting for a connection to %s: %w", t.host, err)
+	}
+
+	err = t.limiter.Wait(ctx)
+	if err != nil {
+		t.sem.Release(1)
+		return nil, fmt.Errorf("failed waiting for the rate limit of %s: %w", t.host, err)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.sem.Release(1)
+		return nil, err //nolint:wrapcheck // plain wrapper
+	}
+
+	if !retryable(resp, nil) {
+		t.lastSuccess.Store(time.Now().UnixNano())
+	}
+
+	// the connection stays in use until the body is closed
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() { t.sem.Release(1) }, once: sync.Once{}}
+
+	return resp, nil
+}
+
+// releasingBody calls release once when the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+
+	return err //nolint:wrapcheck // plain wrapper
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+const hnSearchBaseURL = "https://hn.algolia.com/api/v1/"
+
+// hnSearchHit is one result of the HN Search API, which has its own field names.
+//
+//nolint:tagliatelle // external API
+type hnSearchHit struct {
+	ObjectID    string   `json:"objectID"`
+	Title       string   `json:"title"`
+	URL         string   `json:"url"`
+	Author      string   `json:"author"`
+	StoryText   string   `json:"story_text"`
+	CommentText string   `json:"comment_text"`
+	Tags        []string `json:"_tags"`
+	CreatedAt   int64    `json:"created_at_i"`
+	Points      int      `json:"points"`
+	NumComments int      `json:"num_comments"`
+	ParentID    int      `json:"parent_id"`
+	StoryID     int      `json:"story_id"`
+}
+
+type hnSearchResponse struct {
+	Hits    []hnSearchHit `json:"hits"`
+	NbHits  int           `json:"nbHits"`
+	Page    int           `json:"page"`
+	NbPages int           `json:"nbPages"`
+}
+
+// searchHN runs a query against the HN Search API, where endpoint is "search" for relevance
+// order or "search_by_date" for newest first.
+func searchHN(ctx context.Context, endpoint string, params url.Values) (*hnSearchResponse, error) {
+	var result hnSearchResponse
+
+	err := fetchJSON(ctx, http.DefaultClient, hnSearchBaseURL+endpoint+"?"+params.Encode(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// item converts the hit to an HN item so it can be formatted like items from the HN API. The
+// type comes from the tags and the text from whichever of story_text and comment_text is set.
+func (h hnSearchHit) item(id int) *hn.Item {
+	item := new(hn.Item)
+	item.ID = id
+	item.By = h.Author
+	item.Time = h.CreatedAt
+	item.Title = h.Title
+	item.URL = h.URL
+	item.Score = h.Points
+	item.Descendants = h.NumComments
+	item.Parent = h.ParentID
+	item.Text = h.StoryText
+
+	if h.CommentText != "" {
+		item.Text = h.CommentText
+	}
+
+	for _, t := range []string{"comment", "story", "poll", "pollopt", "job"} {
+		if slices.Contains(h.Tags, t) {
+			item.Type = t
+			break
+		}
+	}
+
+	return item
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+const streamEventItem = "item"
+
+// streamItem is an item as it was when it was created, sent by /items/stream.
+type streamItem struct {
+	Type   string `json:"type"`
+	By     string `json:"by,omitempty"`
+	Title  string `json:"title,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Text   string `json:"text,omitempty"`
+	ID     int    `json:"id"`
+	Parent int    `json:"parent,omitempty"`
+	Time   int64  `json:"time"`
+}
+
+// ingester follows maxitem of the HN API to fetch each item soon after it is created. Fetching puts
+// the items in the cache of the HN client, so computing what is active finds the new comments
+// there, and the items are archived in the store and passed to the streams of /items/stream.
+type ingester struct {
+	client     *itemClient
+	httpClient *http.Client
+	store      *store
+	broker     *eventBroker[streamItem]
+	formatter  *textFormatter
+	batch      int
+	last       int
+}
+
+// ingestItems fetches the items created since the last poll every interval until ctx is done.
+// Ingestion starts from the newest item when it begins, and when it falls more than batch items
+// behind it skips ahead rather than catching up.
+func ingestItems(
+	ctx context.Context,
+	client *itemClient,
+	httpClient *http.Client,
+	st *store,
+	broker *eventBroker[streamItem],
+	formatter *textFormatter,
+	interval time.Duration,
+	batch int,
+) {
+	if interval <= 0 {
+		return
+	}
+
+	in := &ingester{
+		client:     client,
+		httpClient: httpClient,
+		store:      st,
+		broker:     broker,
+		formatter:  formatter,
+		batch:      batch,
+		last:       0,
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		in.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (in *ingester) poll(ctx context.Context) {
+	var maxItem int
+
+	err := fetchHNJSON(ctx, in.httpClient, "maxitem.json", &maxItem)
+	if err != nil {
+		log.Printf("failed to poll maxitem: %v", err)
+		return
+	}
+
+	if in.last == 0 {
+		in.last = maxItem
+		return
+	}
+
+	from := max(in.last+1, maxItem-in.batch+1)
+	if from > maxItem {
+		return
+	}
+
+	ids := make([]int, 0, maxItem-from+1)
+	for id := from; id <= maxItem; id++ {
+		ids = append(ids, id)
+	}
+
+	items, err := fetchItems(ctx, in.client, ids)
+	if err != nil {
+		log.Printf("failed to ingest items: %v", err)
+		return
+	}
+
+	// new items can take a moment to be served, so the first missing one is retried next time
+	in.last = maxItem
+
+	fetched := make([]*hn.Item, 0, len(items))
+
+	for _, id := range ids {
+		item := items[id]
+		if item == nil {
+			in.last = id - 1
+			break
+		}
+
+		fetched = append(fetched, item)
+	}
+
+	in.record(ctx, fetched)
+}
+
+// record archives and indexes the items and passes them to the item streams, oldest first.
+func (in *ingester) record(ctx context.Context, items []*hn.Item) {
+	if len(items) == 0 {
+		return
+	}
+
+	if in.store != nil {
+		err := in.store.archiveItems(ctx, items)
+		if err != nil {
+			log.Printf("failed to archive ingested items: %v", err)
+		}
+
+		err = in.store.indexItems(ctx, items)
+		if err != nil {
+			log.Printf("failed to index ingested items: %v", err)
+		}
+	}
+
+	for _, item := range items {
+		if item.Dead || item.Deleted {
+			continue
+		}
+
+		in.broker.publish("", streamItem{
+			Type:   item.Type,
+			By:     item.By,
+			Title:  item.Title,
+			URL:    item.URL,
+			Text:   in.formatter.format(item),
+			ID:     item.ID,
+			Parent: item.Parent,
+			Time:   item.Time,
+		})
+	}
+}
+
+// itemTypes returns the values accepted by the types query parameter of /items/stream.
+func itemTypes() []string {
+	return []string{"story", "comment", "job", "poll", "pollopt"}
+}
+
+// handleItemStream streams the items ingested from maxitem as server-sent events, optionally only
+// those of the given types, until the client goes away or the server shuts down.
+func handleItemStream(ctx context.Context, c *gin.Context, broker *eventBroker[streamItem], enabled bool) {
+	if !enabled {
+		respondError(c, http.StatusNotImplemented, codeIngestDisabled, "the server does not ingest new items")
+		return
+	}
+
+	types := make(map[string]bool)
+
+	for _, t := range splitList(c.Query("types")) {
+		if !slices.Contains(itemTypes(), t) {
+			respondParamError(c, codeInvalidTypes, "types", "invalid types")
+			return
+		}
+
+		types[t] = true
+	}
+
+	events, stop := broker.listen("")
+	defer stop()
+
+	filtered := make(chan streamItem, eventsBuffered)
+
+	go func() {
+		defer close(filtered)
+
+		for item := range events {
+			if len(types) > 0 && !types[item.Type] {
+				continue
+			}
+
+			select {
+			case filtered <- item:
+			default:
+			}
+		}
+	}()
+
+	streamEvents(ctx, c, streamEventItem, filtered)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"log"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// changedItemTTL is how long an item stays in the overlay after it last changed. The HN client
+// caches an item for longer the older it is, so this outlasts its copies of items that still
+// change, which are recent ones and the stories they reply to.
+const changedItemTTL = 24 * time.Hour
+
+// itemClient is the HN client with an overlay of the items that changed since it cached them. The
+// client has no way to evict single items from its cache, so changed items are fetched again from
+// the API the first time they are read and the fresh copies are used in place of the cached ones.
+type itemClient struct {
+	*hn.Client
+
+	httpClient *http.Client
+	changed    map[int]changedItem
+	mu         sync.Mutex
+	// retries is how many times fetches that cannot go through httpClient are retried.
+	retries int
+}
+
+// changedItem is an entry of the overlay: when the item last changed and the copy fetched since,
+// which is nil until it is read.
+type changedItem struct {
+	changed time.Time
+	item    *hn.Item
+}
+
+func newItemClient(client *hn.Client, httpClient *http.Client, retries int) *itemClient {
+	return &itemClient{
+		Client:     client,
+		httpClient: httpClient,
+		changed:    make(map[int]changedItem),
+		mu:         sync.Mutex{},
+		retries:    retries,
+	}
+}
+
+// markChanged records that the items changed, so the next read fetches them again, and forgets
+// the items that have not changed for changedItemTTL.
+func (c *itemClient) markChanged(ids map[int]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	maps.DeleteFunc(c.changed, func(_ int, entry changedItem) bool {
+		return now.Sub(entry.changed) > changedItemTTL
+	})
+
+	for id := range ids {
+		c.changed[id] = changedItem{changed: now, item: nil}
+	}
+}
+
+// refresh replaces the changed items of items with fresh copies and returns the replaced items.
+// An item that cannot be fetched again keeps its cached copy.
+func (c *itemClient) refresh(ctx context.Context, items hn.ItemSet) []*hn.Item {
+	replaced, stale := c.overlay(items)
+
+	for _, id := range stale {
+		start := time.Now()
+
+		var item *hn.Item
+
+		err := fetchHNJSON(ctx, c.httpClient, "item/"+strconv.Itoa(id)+".json", &item)
+		if err != nil {
+			log.Printf("failed to fetch changed item %d: %v", id, err)
+			continue
+		}
+
+		if item == nil {
+			continue
+		}
+
+		c.mu.Lock()
+
+		// a change recorded while fetching makes this copy stale already
+		if entry, ok := c.changed[id]; ok && entry.changed.Before(start) {
+			c.changed[id] = changedItem{changed: entry.changed, item: item}
+		}
+
+		c.mu.Unlock()
+
+		items[id] = item
+		replaced = append(replaced, item)
+	}
+
+	return replaced
+}
+
+// overlay replaces the changed items of items that were fetched again already, returning them,
+// and returns the IDs of those that were not.
+func (c *itemClient) overlay(items hn.ItemSet) ([]*hn.Item, []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var (
+		replaced []*hn.Item
+		stale    []int
+	)
+
+	for id, item := range items {
+		entry, ok := c.changed[id]
+		if !ok || item == nil {
+			continue
+		}
+
+		if entry.item == nil {
+			stale = append(stale, id)
+			continue
+		}
+
+		items[id] = entry.item
+		replaced = append(replaced, entry.item)
+	}
+
+	return replaced, stale
+}
+
+// withChanges refreshes the changed items of a tree of items other than the roots and adds the
+// replies to them the cached copies did not have, along with the subtrees of those replies, and
+// reports whether any item was replaced or added. The roots are left as the caller passed them.
+func (c *itemClient) withChanges(ctx context.Context, items hn.ItemSet, roots hn.ItemSet) (bool, error) {
+	descendants := maps.Clone(items)
+	maps.DeleteFunc(descendants, func(id int, _ *hn.Item) bool {
+		_, ok := roots[id]
+		return ok
+	})
+
+	replaced := c.refresh(ctx, descendants)
+	changed := len(replaced) > 0
+
+	for len(replaced) > 0 {
+		missing := missingReplies(replaced, descendants)
+		if len(missing) == 0 {
+			break
+		}
+
+		added, err := c.GetItems(ctx, missing)
+		if err != nil {
+			return changed, err //nolint:wrapcheck // wrapped by the callers of fetchDescendants
+		}
+
+		all, err := c.GetDescendants(ctx, added)
+		if err != nil {
+			return changed, err //nolint:wrapcheck // wrapped by the callers of fetchDescendants
+		}
+
+		maps.Copy(added, all)
+
+		// the new replies may have changed since they were cached as well
+		replaced = c.refresh(ctx, added)
+
+		maps.Copy(descendants, added)
+	}
+
+	maps.Copy(items, descendants)
+
+	return changed, nil
+}
+
+// missingReplies returns the IDs of the replies to the items that are not in all.
+func missingReplies(items []*hn.Item, all hn.ItemSet) []int {
+	var missing []int
+
+	for _, item := range items {
+		for _, kid := range item.Kids {
+			if _, ok := all[kid]; !ok {
+				missing = append(missing, kid)
+			}
+		}
+	}
+
+	return missing
+}
+
+// activeWithChanges applies the overlay to the roots and tree of replies unl.GetActive returns,
+// since it reads through the HN client itself.
+func (c *itemClient) activeWithChanges(
+	ctx context.Context,
+	roots []*hn.Item,
+	tree map[int]hn.ItemSet,
+) ([]*hn.Item, map[int]hn.ItemSet, error) {
+	all := make(hn.ItemSet)
+
+	for _, children := range tree {
+		maps.Copy(all, children)
+	}
+
+	for _, root := range roots {
+		all[root.ID] = root
+	}
+
+	changed, err := c.withChanges(ctx, all, nil)
+	if err != nil || !changed {
+		return roots, tree, err
+	}
+
+	fresh := make([]*hn.Item, 0, len(roots))
+	isRoot := make(map[int]bool, len(roots))
+
+	for _, root := range roots {
+		fresh = append(fresh, all[root.ID])
+		isRoot[root.ID] = true
+	}
+
+	for id, item := range all {
+		if isRoot[id] || item == nil {
+			continue
+		}
+
+		if tree[item.Parent] == nil {
+			tree[item.Parent] = make(hn.ItemSet)
+		}
+
+		tree[item.Parent][id] = item
+	}
+
+	return fresh, tree, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// fakeHNAPI serves item JSON documents by path and counts the requests.
+type fakeHNAPI struct {
+	items    map[string]string
+	requests atomic.Int32
+}
+
+func (f *fakeHNAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.requests.Add(1)
+
+	body, ok := f.items[strings.TrimPrefix(req.URL.String(), hnAPIBaseURL)]
+
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	return &http.Response{
+		Trailer:          nil,
+		Header:           make(http.Header),
+		Body:             io.NopCloser(strings.NewReader(body)),
+		TLS:              nil,
+		Request:          req,
+		Status:           http.StatusText(status),
+		Proto:            "HTTP/1.1",
+		TransferEncoding: nil,
+		ProtoMajor:       1,
+		ProtoMinor:       1,
+		StatusCode:       status,
+		ContentLength:    int64(len(body)),
+		Close:            false,
+		Uncompressed:     false,
+	}, nil
+}
+
+// cachedItem returns a comment as the HN client might have cached it.
+func cachedItem(id int, kids ...int) *hn.Item {
+	return &hn.Item{
+		ID:          id,
+		Deleted:     false,
+		Type:        "comment",
+		By:          "",
+		Time:        0,
+		Text:        "cached " + strconv.Itoa(id),
+		Dead:        false,
+		Parent:      0,
+		Poll:        0,
+		Kids:        kids,
+		URL:         "",
+		Score:       0,
+		Title:       "",
+		Parts:       nil,
+		Descendants: 0,
+	}
+}
+
+func TestItemClientRefresh(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		api       map[string]string
+		name      string
+		changed   []int
+		texts     []string
+		replaced  []int
+		missing   []int
+		requests  int32
+		readTwice bool
+	}{
+		{
+			api:       map[string]string{},
+			name:      "unchanged",
+			changed:   nil,
+			texts:     []string{"cached 1", "cached 2"},
+			replaced:  nil,
+			missing:   nil,
+			requests:  0,
+			readTwice: false,
+		},
+		{
+			api:       map[string]string{"item/2.json": `{"id":2,"text":"fresh 2","kids":[3,4]}`},
+			name:      "changed",
+			changed:   []int{2},
+			texts:     []string{"cached 1", "fresh 2"},
+			replaced:  []int{2},
+			missing:   []int{4},
+			requests:  1,
+			readTwice: false,
+		},
+		{
+			api:       map[string]string{"item/2.json": `{"id":2,"text":"fresh 2","kids":[3,4]}`},
+			name:      "fetched once",
+			changed:   []int{2},
+			texts:     []string{"cached 1", "fresh 2"},
+			replaced:  []int{2},
+			missing:   []int{4},
+			requests:  1,
+			readTwice: true,
+		},
+		{
+			api:       map[string]string{},
+			name:      "failed fetch keeps the cached copy",
+			changed:   []int{2},
+			texts:     []string{"cached 1", "cached 2"},
+			replaced:  nil,
+			missing:   nil,
+			requests:  1,
+			readTwice: false,
+		},
+		{
+			api:       map[string]string{"item/5.json": `{"id":5,"text":"fresh 5"}`},
+			name:      "not read",
+			changed:   []int{5},
+			texts:     []string{"cached 1", "cached 2"},
+			replaced:  nil,
+			missing:   nil,
+			requests:  0,
+			readTwice: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			api := &fakeHNAPI{items: tt.api, requests: atomic.Int32{}}
+			client := newItemClient(nil, &http.Client{Transport: api}, 0)
+
+			changed := make(map[int]bool)
+			for _, id := range tt.changed {
+				changed[id] = true
+			}
+
+			client.markChanged(changed)
+
+			cached := func() hn.ItemSet {
+				return hn.ItemSet{
+					1: cachedItem(1, 2),
+					2: cachedItem(2, 3),
+					3: cachedItem(3),
+				}
+			}
+
+			items := cached()
+			replaced := client.refresh(t.Context(), items)
+
+			if tt.readTwice {
+				items = cached()
+				replaced = client.refresh(t.Context(), items)
+			}
+
+			texts := []string{items[1].Text, items[2].Text}
+			if !slices.Equal(texts, tt.texts) {
+				t.Errorf("texts %v, want %v", texts, tt.texts)
+			}
+
+			ids := make([]int, 0, len(replaced))
+			for _, item := range replaced {
+				ids = append(ids, item.ID)
+			}
+
+			if !slices.Equal(ids, tt.replaced) {
+				t.Errorf("replaced %v, want %v", ids, tt.replaced)
+			}
+
+			if missing := missingReplies(replaced, items); !slices.Equal(missing, tt.missing) {
+				t.Errorf("missing replies %v, want %v", missing, tt.missing)
+			}
+
+			if n := api.requests.Load(); n != tt.requests {
+				t.Errorf("%d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// itemResponse is a single item without its descendants. Stories, and other items without a
+// parent, include their story metadata.
+type itemResponse struct {
+	*storyMetadata
+	By     string `json:"by,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Time   int64  `json:"time"`
+	ID     int    `json:"id"`
+	Parent int    `json:"parent,omitempty"`
+	Dead   bool   `json:"dead,omitempty"`
+}
+
+func newItemResponse(item *hn.Item, formatter *textFormatter, textMode textMode, showUser bool) itemResponse {
+	by := item.By
+	if !showUser {
+		by = ""
+	}
+
+	var story *storyMetadata
+	if item.Parent == 0 {
+		story = newStoryMetadata(item)
+	}
+
+	return itemResponse{
+		storyMetadata: story,
+		By:            by,
+		Text:          formatter.formatAs(item, textMode),
+		Time:          item.Time,
+		ID:            item.ID,
+		Parent:        item.Parent,
+		Dead:          item.Dead,
+	}
+}
+
+type handleItemsRequest struct {
+	IDs []int `json:"ids"`
+}
+
+type handleItemsResponse struct {
+	Items   []itemResponse `json:"items"`
+	Missing []int          `json:"missing"`
+}
+
+// parseItemsRequest reads the IDs from a JSON body like {"ids": [1, 2, 3]}, adding an error to errs
+// if the body is invalid, has no IDs or more than maxIDs, or has an ID that is not positive. The
+// IDs are returned without duplicates in the order they were first given.
+func parseItemsRequest(c *gin.Context, errs *paramErrors, maxIDs int) []int {
+	var req handleItemsRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&req)
+	if err != nil || len(req.IDs) == 0 {
+		errs.add(codeInvalidBody, "body", "invalid request body")
+		return nil
+	}
+
+	ids := make([]int, 0, len(req.IDs))
+	seen := make(map[int]bool, len(req.IDs))
+
+	for _, id := range req.IDs {
+		if id <= 0 {
+			errs.add(codeInvalidID, "ids", "invalid id")
+			return nil
+		}
+
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+
+		ids = append(ids, id)
+	}
+
+	if len(ids) > maxIDs {
+		errs.add(codeTooManyIDs, "ids", "too many ids")
+		return nil
+	}
+
+	return ids
+}
+
+// handleItems returns the items with the IDs in the request body, fetched together in one batch,
+// without their descendants. Items are in the order requested; IDs of items that do not exist or
+// were deleted are listed in missing instead.
+func handleItems(c *gin.Context, client *itemClient, formatter *textFormatter, maxIDs int) {
+	var errs paramErrors
+
+	showUser, ok := parseShowUser(c)
+	if !ok {
+		errs.add(codeInvalidUser, "show-user", "invalid show-user")
+	}
+
+	textMode, ok := parseTextMode(c)
+	if !ok {
+		errs.add(codeInvalidText, "text", "invalid text")
+	}
+
+	ids := parseItemsRequest(c, &errs, maxIDs)
+
+	if errs.respond(c) {
+		return
+	}
+
+	items, err := fetchItems(c.Request.Context(), client, ids)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve items")
+		return
+	}
+
+	response := handleItemsResponse{
+		Items:   make([]itemResponse, 0, len(ids)),
+		Missing: []int{},
+	}
+
+	for _, id := range ids {
+		item, ok := items[id]
+		if !ok || item == nil || item.Deleted {
+			response.Missing = append(response.Missing, id)
+			continue
+		}
+
+		response.Items = append(response.Items, newItemResponse(item, formatter, textMode, showUser))
+	}
+
+	respond(c, http.StatusOK, response)
+}
+
+// handleItem returns a single item without its descendants. The response carries an ETag of its
+// content so clients and CDNs can revalidate it cheaply once the configured Cache-Control max-age
+// runs out.
+func handleItem(c *gin.Context, client *itemClient, formatter *textFormatter) {
+	var errs paramErrors
+
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errs.add(codeInvalidID, "id", "invalid id")
+	}
+
+	showUser, ok := parseShowUser(c)
+	if !ok {
+		errs.add(codeInvalidUser, "show-user", "invalid show-user")
+	}
+
+	textMode, ok := parseTextMode(c)
+	if !ok {
+		errs.add(codeInvalidText, "text", "invalid text")
+	}
+
+	if errs.respond(c) {
+		return
+	}
+
+	_, item, err := getItem(c.Request.Context(), client, itemID)
+	if err != nil {
+		respondItemError(c, err)
+		return
+	}
+
+	response := newItemResponse(item, formatter, textMode, showUser)
+
+	etag := itemETag(response, wantsMsgPack(c))
+	if etag != "" {
+		c.Header("ETag", etag)
+
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	respond(c, http.StatusOK, response)
+}
+
+// itemETag is a strong ETag of the item response in the JSON or MessagePack representation.
+func itemETag(response itemResponse, msgpack bool) string {
+	h := fnv.New64a()
+
+	// writes to a hash never fail
+	err := json.NewEncoder(h).Encode(response)
+	if err != nil {
+		return ""
+	}
+
+	if msgpack {
+		_, _ = h.Write([]byte("msgpack"))
+	}
+
+	return fmt.Sprintf(`"%016x"`, h.Sum64())
+}
+
+// etagMatches reports whether an If-None-Match header matches etag. The comparison is weak, as
+// for If-None-Match it should be, so W/ prefixes are ignored.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
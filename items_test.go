@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+func TestItemETag(t *testing.T) {
+	t.Parallel()
+
+	item := itemResponse{storyMetadata: nil, By: "pg", Text: "hello", Time: 1, ID: 1, Parent: 0, Dead: false}
+	edited := item
+	edited.Text = "hello, edited"
+
+	json := itemETag(item, false)
+	msgpack := itemETag(item, true)
+
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		same bool
+	}{
+		{name: "stable for JSON", a: json, b: itemETag(item, false), same: true},
+		{name: "stable for MessagePack", a: msgpack, b: itemETag(item, true), same: true},
+		{name: "JSON and MessagePack", a: json, b: msgpack, same: false},
+		{name: "edited JSON", a: json, b: itemETag(edited, false), same: false},
+		{name: "edited MessagePack", a: msgpack, b: itemETag(edited, true), same: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if tt.a == "" || tt.b == "" {
+				t.Fatalf("empty ETag")
+			}
+
+			if same := tt.a == tt.b; same != tt.same {
+				t.Errorf("%s and %s same %v, want %v", tt.a, tt.b, same, tt.same)
+			}
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	t.Parallel()
+
+	const etag = `"0123456789abcdef"`
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        bool
+	}{
+		{name: "empty", ifNoneMatch: "", want: false},
+		{name: "exact", ifNoneMatch: `"0123456789abcdef"`, want: true},
+		{name: "weak", ifNoneMatch: `W/"0123456789abcdef"`, want: true},
+		{name: "other", ifNoneMatch: `"fedcba9876543210"`, want: false},
+		{name: "unquoted", ifNoneMatch: `0123456789abcdef`, want: false},
+		{name: "any", ifNoneMatch: "*", want: true},
+		{name: "list", ifNoneMatch: `"fedcba9876543210","0123456789abcdef"`, want: true},
+		{name: "list with spaces", ifNoneMatch: `"fedcba9876543210" , W/"0123456789abcdef"`, want: true},
+		{name: "list without match", ifNoneMatch: `"fedcba9876543210", W/"0000000000000000"`, want: false},
+		{name: "list with any", ifNoneMatch: `"fedcba9876543210", *`, want: true},
+		{name: "lowercase weak prefix", ifNoneMatch: `w/"0123456789abcdef"`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := etagMatches(tt.ifNoneMatch, etag); got != tt.want {
+				t.Errorf("matches %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
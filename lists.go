@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultListLimit = 30
+
+// storyLists maps the names accepted by /lists/:name to the official HN story lists.
+func storyLists() map[string]string {
+	return map[string]string{
+		"top":  "topstories",
+		"best": "beststories",
+		"new":  "newstories",
+		"ask":  "askstories",
+		"show": "showstories",
+		"job":  "jobstories",
+	}
+}
+
+type storyListOptions struct {
+	Format   timeFormat
+	Text     textMode
+	ShowUser bool
+}
+
+// parseStoryListOptions reads the user, text, and time-format query parameters, adding an error to
+// errs for each that is invalid.
+func parseStoryListOptions(c *gin.Context, errs *paramErrors) storyListOptions {
+	showUser, ok := parseShowUser(c)
+	if !ok {
+		errs.add(codeInvalidUser, "show-user", "invalid show-user")
+	}
+
+	text, ok := parseTextMode(c)
+	if !ok {
+		errs.add(codeInvalidText, "text", "invalid text")
+	}
+
+	format, ok := parseTimeFormat(c)
+	if !ok {
+		errs.add(codeInvalidTimeFormat, "time-format", "invalid time-format")
+	}
+
+	return storyListOptions{Format: format, Text: text, ShowUser: showUser}
+}
+
+// hydrateStories retrieves the stories with the given IDs and formats them like /active roots,
+// preserving the order of ids and skipping stories that are deleted or dead.
+func hydrateStories(
+	ctx context.Context,
+	client *itemClient,
+	formatter *textFormatter,
+	ids []int,
+	opts storyListOptions,
+) ([]handleActiveResponseItem, error) {
+	stories, err := fetchItems(ctx, client, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	items := make([]handleActiveResponseItem, 0, len(ids))
+
+	for _, id := range ids {
+		item, ok := stories[id]
+		if !ok || item == nil || item.Deleted || item.Dead {
+			continue
+		}
+
+		by := item.By
+		if !opts.ShowUser {
+			by = ""
+		}
+
+		age, unix, iso := opts.Format.format(now, item.Time)
+
+		items = append(items, handleActiveResponseItem{
+			storyMetadata:     newStoryMetadata(item),
+			Metrics:           nil,
+			By:                by,
+			Text:              formatter.formatAs(item, opts.Text),
+			Age:               age,
+			TimeISO:           iso,
+			Children:          nil,
+			AccountAgeDays:    nil,
+			Karma:             nil,
+			Time:              unix,
+			ID:                item.ID,
+			Parent:            0,
+			Root:              item.ID,
+			Depth:             0,
+			TruncatedChildren: 0,
+			Conversation:      0,
+			Active:            false,
+			ActiveWindows:     nil,
+			ActivityScore:     0,
+			Collapsed:         false,
+			SecondChance:      false,
+			OP:                false,
+			Dead:              item.Dead,
+			Deleted:           item.Deleted,
+			IsNew:             false,
+			NewUser:           false,
+			Followed:          false,
+		})
+	}
+
+	return items, nil
+}
+
+type handleListResponse struct {
+	IDs        []int                      `json:"ids,omitempty"`
+	Items      []handleActiveResponseItem `json:"items,omitempty"`
+	Total      int                        `json:"total"`
+	NextOffset int                        `json:"nextOffset,omitempty"`
+}
+
+// handleList pages through one of the HN story lists, returning only IDs unless hydrate=1.
+//
+//nolint:cyclop // need parsing helper
+func handleList(c *gin.Context, client *itemClient, httpClient *http.Client, formatter *textFormatter) {
+	ctx := c.Request.Context()
+
+	list, ok := storyLists()[c.Param("name")]
+	if !ok {
+		respondError(c, http.StatusNotFound, codeListNotFound, "unknown list")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultListLimit)))
+	if err != nil || limit < 1 || limit > maxNewestLimit {
+		respondParamError(c, codeInvalidLimit, "limit", "invalid limit")
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		respondParamError(c, codeInvalidOffset, "offset", "invalid offset")
+		return
+	}
+
+	hydrate, err := strconv.Atoi(c.DefaultQuery("hydrate", "0"))
+	if err != nil {
+		respondParamError(c, codeInvalidHydrate, "hydrate", "invalid hydrate")
+		return
+	}
+
+	var errs paramErrors
+
+	opts := parseStoryListOptions(c, &errs)
+
+	if errs.respond(c) {
+		return
+	}
+
+	all, err := fetchStoryIDs(ctx, httpClient, list)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve story list")
+		return
+	}
+
+	ids := all[min(offset, len(all)):min(offset+limit, len(all))]
+
+	nextOffset := 0
+	if offset+limit < len(all) {
+		nextOffset = offset + limit
+	}
+
+	if hydrate != 1 {
+		respond(c, http.StatusOK, handleListResponse{IDs: ids, Items: nil, Total: len(all), NextOffset: nextOffset})
+		return
+	}
+
+	items, err := hydrateStories(ctx, client, formatter, ids, opts)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve items")
+		return
+	}
+
+	respond(c, http.StatusOK, handleListResponse{IDs: nil, Items: items, Total: len(all), NextOffset: nextOffset})
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+	"golang.org/x/net/websocket"
+)
+
+type handleLiveMessage struct {
+	Items []handleItemDescendantsResponse `json:"items"`
+}
+
+var errOriginNotAllowed = errors.New("origin not allowed")
+
+// liveResyncTicks is how often, in intervals, a live connection re-fetches the whole tree to catch
+// any change the HN updates list no longer held by the time it was polled.
+const liveResyncTicks = 10
+
+// handleLive upgrades to a WebSocket and, every interval, sends the descendants of the item that
+// were not there before. The first message contains the whole tree. Changes come from the shared
+// poll of the HN updates list published to updates; with that poll disabled, the whole tree is
+// fetched every interval instead. Browsers may only connect from the origin of the server or one
+// allowed by --cors-origins.
+func handleLive(
+	c *gin.Context,
+	client *itemClient,
+	updates *eventBroker[map[int]bool],
+	formatter *textFormatter,
+	cfg config,
+) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondParamError(c, codeInvalidID, "id", "invalid id")
+		return
+	}
+
+	_, _, err = getItem(c.Request.Context(), client, itemID)
+	if err != nil {
+		respondItemError(c, err)
+		return
+	}
+
+	server := websocket.Server{
+		Config: websocket.Config{},
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			return checkLiveOrigin(r, cfg.CORSOrigins)
+		},
+		Handler: func(ws *websocket.Conn) {
+			followTree(ws, client, updates, formatter, itemID, cfg)
+		},
+	}
+
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkLiveOrigin rejects WebSocket handshakes from browsers on other origins than the server's
+// that CORS does not allow, since browsers do not apply CORS to WebSockets themselves. Clients
+// that send no origin are not browsers and are allowed.
+func checkLiveOrigin(r *http.Request, allowed []string) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+
+	if corsOriginAllowed(origin, allowed) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", errOriginNotAllowed, origin)
+}
+
+func followTree(
+	ws *websocket.Conn,
+	client *itemClient,
+	updates *eventBroker[map[int]bool],
+	formatter *textFormatter,
+	itemID int,
+	cfg config,
+) {
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+
+	// clients only send to close the connection, so any read result ends the follow
+	go func() {
+		var discard []byte
+
+		_ = websocket.Message.Receive(ws, &discard)
+
+		cancel()
+	}()
+
+	changes, stop := updates.listen(updatedItemsKey)
+	defer stop()
+
+	ticker := time.NewTicker(cfg.LiveInterval)
+	defer ticker.Stop()
+
+	thread := &liveThread{root: nil, items: nil, seen: make(map[int]bool), id: itemID}
+	changed := make(map[int]bool)
+
+	for tick := 0; ; tick++ {
+		resync := tick%liveResyncTicks == 0 || cfg.UpdatesInterval <= 0
+
+		err := thread.update(ctx, client, changed, resync)
+		if err != nil {
+			log.Printf("failed to follow item %d: %v", itemID, err)
+		} else if items := thread.newItems(formatter); len(items) > 0 {
+			err = websocket.JSON.Send(ws, handleLiveMessage{Items: items})
+			if err != nil {
+				return
+			}
+		}
+
+		changed, err = collectChanges(ctx, ticker.C, changes)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// collectChanges gathers the changed items published until the next tick.
+func collectChanges(ctx context.Context, tick <-chan time.Time, changes <-chan map[int]bool) (map[int]bool, error) {
+	changed := make(map[int]bool)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err() //nolint:wrapcheck // only ends the loop
+		case ids := <-changes:
+			maps.Copy(changed, ids)
+		case <-tick:
+			return changed, nil
+		}
+	}
+}
+
+// liveThread is what a live connection knows of the tree under its item: every item fetched so
+// far and the IDs of those already sent.
+type liveThread struct {
+	root  *hn.Item
+	items hn.ItemSet
+	seen  map[int]bool
+	id    int
+}
+
+// update brings the thread up to date. Only the changed items that belong to the thread are
+// fetched again, along with any new replies to them, unless resync asks for the whole tree.
+func (t *liveThread) update(ctx context.Context, client *itemClient, changed map[int]bool, resync bool) error {
+	if resync || t.root == nil {
+		root, flat, err := fetchTree(ctx, client, t.id)
+		if err != nil {
+			return err
+		}
+
+		t.root, t.items = root, make(hn.ItemSet, len(flat))
+		for _, f := range flat {
+			t.items[f.ID] = f.Item
+		}
+
+		return nil
+	}
+
+	ours := make(map[int]bool)
+
+	for id := range changed {
+		if t.items[id] != nil {
+			ours[id] = true
+		}
+	}
+
+	if len(ours) == 0 {
+		return nil
+	}
+
+	return t.refetch(ctx, client, ours)
+}
+
+// refetch fetches the changed items again, which the item client has marked as changed already,
+// and the subtrees of their new replies.
+func (t *liveThread) refetch(ctx context.Context, client *itemClient, changed map[int]bool) error {
+	items, err := fetchItems(ctx, client, slices.Collect(maps.Keys(changed)))
+	if err != nil {
+		return err
+	}
+
+	var added []int
+
+	for id, item := range items {
+		if item == nil {
+			continue
+		}
+
+		t.items[id] = item
+		if id == t.root.ID {
+			t.root = item
+		}
+
+		for _, kid := range item.Kids {
+			if t.items[kid] == nil {
+				added = append(added, kid)
+			}
+		}
+	}
+
+	return t.addReplies(ctx, client, added)
+}
+
+// addReplies fetches new replies to items of the thread along with their subtrees.
+func (t *liveThread) addReplies(ctx context.Context, client *itemClient, added []int) error {
+	if len(added) == 0 {
+		return nil
+	}
+
+	kids, err := fetchItems(ctx, client, added)
+	if err != nil {
+		return err
+	}
+
+	all, err := fetchDescendants(ctx, client, kids)
+	if err != nil {
+		return err
+	}
+
+	maps.Copy(t.items, all)
+
+	return nil
+}
+
+// newItems returns the items of the thread that have not been sent yet, in display order.
+func (t *liveThread) newItems(formatter *textFormatter) []handleItemDescendantsResponse {
+	if t.root == nil {
+		return nil
+	}
+
+	byParent, _, err := t.items.GroupByParent()
+	if err != nil {
+		return nil
+	}
+
+	var result []handleItemDescendantsResponse
+
+	for _, f := range unl.FlattenTree(t.root, byParent) {
+		if t.seen[f.ID] {
+			continue
+		}
+
+		t.seen[f.ID] = true
+
+		result = append(result, newLiveItem(f, t.root, formatter))
+	}
+
+	return result
+}
+
+// fetchTree fetches an item and all of its descendants and flattens them, the item first. The
+// item is nil if it does not exist.
+func fetchTree(ctx context.Context, client *itemClient, itemID int) (*hn.Item, []unl.FlatItem, error) {
+	items, err := fetchItems(ctx, client, []int{itemID})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := items[itemID]
+	if root == nil {
+		return nil, nil, nil
+	}
+
+	all, err := fetchDescendants(ctx, client, items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allByParent, _, err := all.GroupByParent()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return root, unl.FlattenTree(root, allByParent), nil
+}
+
+func newLiveItem(f unl.FlatItem, root *hn.Item, formatter *textFormatter) handleItemDescendantsResponse {
+	return handleItemDescendantsResponse{
+		storyMetadata:     nil,
+		By:                f.By,
+		Text:              formatter.format(f.Item),
+		Children:          nil,
+		AccountAgeDays:    nil,
+		Karma:             nil,
+		Time:              f.Time,
+		ID:                f.ID,
+		Parent:            f.Parent,
+		Root:              0,
+		Depth:             f.Depth,
+		TruncatedChildren: 0,
+		OP:                isOP(f.Item, root),
+		Dead:              f.Dead,
+		Deleted:           f.Deleted,
+		IsNew:             false,
+		NewUser:           false,
+	}
+}
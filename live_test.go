@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckLiveOrigin(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err     error
+		name    string
+		origin  string
+		allowed []string
+	}{
+		{err: nil, name: "no origin", origin: "", allowed: nil},
+		{err: nil, name: "same origin", origin: "https://unlurker.example", allowed: nil},
+		{err: nil, name: "same origin any case", origin: "https://Unlurker.example", allowed: nil},
+		{err: errOriginNotAllowed, name: "same host other port", origin: "https://unlurker.example:8443", allowed: nil},
+		{err: nil, name: "allowed by CORS", origin: "https://app.example.com", allowed: []string{"*.example.com"}},
+		{err: nil, name: "any allowed by CORS", origin: "https://anything.org", allowed: []string{"*"}},
+		{
+			err:     errOriginNotAllowed,
+			name:    "lookalike",
+			origin:  "https://evil-example.com",
+			allowed: []string{"*.example.com"},
+		},
+		{err: errOriginNotAllowed, name: "other origin", origin: "https://other.org", allowed: nil},
+		{err: errOriginNotAllowed, name: "null origin", origin: "null", allowed: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "https://unlurker.example/item/1/live", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+
+			if err := checkLiveOrigin(r, tt.allowed); !errors.Is(err, tt.err) {
+				t.Errorf("error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCollectChanges(t *testing.T) {
+	t.Parallel()
+
+	changes := make(chan map[int]bool, 2)
+	changes <- map[int]bool{1: true, 2: true}
+	changes <- map[int]bool{2: true, 3: true}
+
+	tick := make(chan time.Time)
+
+	go func() {
+		// the changes are buffered ahead of the tick, but select picks among ready cases at random
+		for len(changes) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		tick <- time.Now()
+	}()
+
+	changed, err := collectChanges(t.Context(), tick, changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[int]bool{1: true, 2: true, 3: true}; !maps.Equal(changed, want) {
+		t.Errorf("changed %v, want %v", changed, want)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err = collectChanges(ctx, tick, changes)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+	requestIDBytes     = 16
+)
+
+type requestIDKey struct{}
+
+// upstreamFetches counts the requests to the HN API made on behalf of one request.
+type upstreamFetches struct {
+	count atomic.Int64
+}
+
+type upstreamFetchesKey struct{}
+
+// logRequests logs one JSON line per request with its ID, route, status, latency, the number of
+// requests made to the HN API for it, and whether the response cache was used. The ID is taken
+// from the X-Request-ID header if the client or a proxy set one and is generated otherwise; it is
+// returned in the same header and available to handlers through requestID.
+func logRequests(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+
+		c.Header(requestIDHeader, id)
+
+		fetches := &upstreamFetches{count: atomic.Int64{}}
+		ctx := context.WithValue(c.Request.Context(), requestIDKey{}, id)
+		ctx = context.WithValue(ctx, upstreamFetchesKey{}, fetches)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		logger.LogAttrs(ctx, level, "request",
+			slog.String("requestId", id),
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latencyMs", float64(time.Since(start))/float64(time.Millisecond)),
+			slog.Int64("upstreamFetches", fetches.count.Load()),
+			slog.String("cache", c.Writer.Header().Get("X-Cache")),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("clientIp", c.ClientIP()),
+			slog.String("traceId", traceID(c.Request.Context())),
+		)
+	}
+}
+
+// traceID returns the ID of the trace of ctx, or an empty string if it is not traced.
+func traceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+
+	return sc.TraceID().String()
+}
+
+// requestID returns the ID of the request of ctx, or an empty string outside of a request.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// countUpstreamFetch records a request to the HN API against the request of ctx, if any.
+func countUpstreamFetch(ctx context.Context) {
+	fetches, ok := ctx.Value(upstreamFetchesKey{}).(*upstreamFetches)
+	if ok {
+		fetches.count.Add(1)
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, requestIDBytes)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
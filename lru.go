@@ -0,0 +1,185 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruEntry[K comparable, V any] struct {
+	stored  time.Time
+	expires time.Time
+	value   V
+	key     K
+	size    int64
+}
+
+// lruCache is a fixed-capacity cache that evicts the least recently used entry when full and
+// treats entries as absent once their TTL has passed. Capacity is a number of entries and, if the
+// cache knows the size of its values, a number of bytes.
+type lruCache[K comparable, V any] struct {
+	entries    map[K]*list.Element
+	order      *list.List
+	size       func(V) int64
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	hits       int64
+	misses     int64
+	mu         sync.Mutex
+}
+
+// newLRUCache creates a cache holding at most maxEntries entries. If size is not nil, the cache
+// also holds at most maxBytes bytes of values as measured by size; a maxBytes of 0 is unlimited.
+func newLRUCache[K comparable, V any](maxEntries int, maxBytes int64, size func(V) int64) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		entries:    make(map[K]*list.Element),
+		order:      list.New(),
+		size:       size,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		bytes:      0,
+		hits:       0,
+		misses:     0,
+		mu:         sync.Mutex{},
+	}
+}
+
+// Get returns the value for key if it is present and has not expired.
+func (c *lruCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return zero, false
+	}
+
+	entry, _ := element.Value.(*lruEntry[K, V])
+	if time.Now().After(entry.expires) {
+		c.remove(element)
+
+		c.misses++
+
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+
+	c.hits++
+
+	return entry.value, true
+}
+
+// Put stores value for key for the duration of ttl, evicting the least recently used entry if the
+// cache is full.
+func (c *lruCache[K, V]) Put(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entry := &lruEntry[K, V]{stored: now, expires: now.Add(ttl), value: value, key: key, size: 0}
+
+	if c.size != nil {
+		entry.size = c.size(value)
+	}
+
+	element, ok := c.entries[key]
+	if ok {
+		c.remove(element)
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	c.bytes += entry.size
+
+	for c.order.Len() > c.maxEntries || (c.maxBytes > 0 && c.bytes > c.maxBytes && c.order.Len() > 1) {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove drops an entry from the cache; the caller must hold the lock.
+func (c *lruCache[K, V]) remove(element *list.Element) {
+	entry, _ := element.Value.(*lruEntry[K, V])
+
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+
+	c.bytes -= entry.size
+}
+
+// Len returns the number of entries in the cache, including any that have expired but have not
+// been evicted yet.
+func (c *lruCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+type lruStats struct {
+	Oldest     time.Time
+	Entries    int
+	MaxEntries int
+	Bytes      int64
+	MaxBytes   int64
+	Hits       int64
+	Misses     int64
+}
+
+// Stats returns the number and size of the entries against the limits, when the oldest of them
+// was stored, and the hits and misses since the cache was created. Sizes are 0 if the cache does
+// not know the size of its values.
+func (c *lruCache[K, V]) Stats() lruStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var oldest time.Time
+
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		entry, _ := element.Value.(*lruEntry[K, V])
+		if oldest.IsZero() || entry.stored.Before(oldest) {
+			oldest = entry.stored
+		}
+	}
+
+	return lruStats{
+		Oldest:     oldest,
+		Entries:    c.order.Len(),
+		MaxEntries: c.maxEntries,
+		Bytes:      c.bytes,
+		MaxBytes:   c.maxBytes,
+		Hits:       c.hits,
+		Misses:     c.misses,
+	}
+}
+
+// DeleteFunc removes the entries whose key matches and returns how many it removed.
+func (c *lruCache[K, V]) DeleteFunc(match func(K) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+
+	for key, element := range c.entries {
+		if match(key) {
+			c.remove(element)
+
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// Purge removes every entry from the cache.
+func (c *lruCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[K]*list.Element)
+	c.order.Init()
+	c.bytes = 0
+}
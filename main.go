@@ -2,45 +2,360 @@ package main
 
 import (
 	"context"
+	"errors"
+	"iter"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"runtime"
+	"slices"
 	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jasonthorsness/unlurker/hn"
-	"github.com/jasonthorsness/unlurker/hn/core"
 	"github.com/jasonthorsness/unlurker/unl"
 	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultWindow = "1h"
+	defaultMaxAge = "24h"
+	defaultMinBy  = 3
+
+	defaultShutdownTimeout   = 10 * time.Second
+	defaultReadHeaderTimeout = 10 * time.Second
 )
 
 func main() {
-	ctx := context.Background()
+	// the log package writes through the default handler too, so every line is JSON
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+
+	cfg, gerr := loadConfig(os.Args)
+	if gerr != nil {
+		log.Fatal(gerr)
+	}
+
+	httpClient, upstream := newHNHTTPClient(cfg)
+
+	shutdownTracing, gerr := setupTracing(context.Background(), cfg.OTLPEndpoint)
+	if gerr != nil {
+		log.Fatal(gerr)
+	}
+
+	defer func() { _ = shutdownTracing(context.Background()) }()
 
-	client, gerr := hn.NewClient(ctx, hn.WithFileCachePath(filepath.Join(os.TempDir(), "hn.db")))
+	hnClient, gerr := hn.NewClient(
+		context.Background(),
+		hn.WithFileCachePath(cfg.HNCache),
+		hn.WithHTTPClient(httpClient),
+	)
 	if gerr != nil {
 		log.Fatal(gerr)
 	}
 
 	defer func() {
-		gerr = client.Close()
+		gerr = hnClient.Close()
 		if gerr != nil {
 			log.Fatalf("error closing client: %v", gerr)
 		}
 	}()
 
-	r := gin.Default()
+	client := newItemClient(hnClient, httpClient, cfg.HNRetries)
 
-	textCache := core.NewMapCache[*hn.Item, string](core.NewClock(), hn.DefaultCacheFor)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	r.GET("/active", func(c *gin.Context) { handleActive(c, client, textCache) })
-	r.GET("/item/:id/tree", func(c *gin.Context) { handleItemDescendants(c, client, textCache) })
+	slog.Info("effective configuration", slog.Any("settings", cfg.Effective))
 
-	gerr = r.Run()
+	var background sync.WaitGroup
+
+	st, gerr := openStore(context.Background(), cfg.StorePath)
+	if gerr != nil {
+		log.Fatal(gerr)
+	}
+
+	defer closeStore(st)
+
+	followed, gerr := loadFollowList(context.Background(), st)
 	if gerr != nil {
-		log.Printf("failed to start server: %v", gerr)
+		log.Fatal(gerr)
+	}
+
+	formatter := newTextFormatter(cfg, st)
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		formatter.Run(ctx, textFlushInterval)
+	}()
+
+	breaker := newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+	source := newActiveSource(client, cfg.activeParams(), st, breaker)
+	live := newLiveConfig(cfg, upstream, source)
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		AddSource:   false,
+		Level:       live.Level(),
+		ReplaceAttr: nil,
+	})))
+
+	info := buildInfo()
+	r := newRouter(live, info)
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		reloadOnHangup(ctx, os.Args, live)
+	}()
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		source.Run(ctx)
+	}()
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		source.RecordSnapshots(ctx)
+	}()
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		recordRanks(ctx, client, httpClient, st, cfg.RankInterval)
+	}()
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		dispatchWebhooks(ctx, source, st)
+	}()
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		notifyChat(ctx, source, live)
+	}()
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		evaluateWatches(ctx, source, st)
+	}()
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		sendDigests(ctx, live, st)
+	}()
+
+	follows := newEventBroker[followEvent]()
+	items := newEventBroker[streamItem]()
+	updates := newEventBroker[map[int]bool]()
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		ingestItems(ctx, client, httpClient, st, items, formatter, cfg.IngestInterval, cfg.IngestBatch)
+	}()
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		followThreads(ctx, cfg, client, formatter, st, follows, updates)
+	}()
+
+	if cfg.GRPCPort > 0 {
+		background.Add(1)
+
+		go func() {
+			defer background.Done()
+			serveGRPC(ctx, stop, newGRPCServer(client, source, formatter, live), cfg)
+		}()
+	}
+
+	responses := newLRUCache[string](cfg.CacheEntries, cfg.CacheBytes, cachedResponse.size)
+	activeCache := cacheResponses(responses, cfg.ActiveCacheTTL)
+	treeCache := cacheResponses(responses, cfg.TreeCacheTTL)
+
+	profiles := newProfileFetcher(httpClient, cfg.ProfileTTL, cfg.ProfileRate)
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		profiles.run(ctx)
+	}()
+
+	active := func(c *gin.Context) { handleActive(c, source, followed, formatter, profiles, live.Load()) }
+
+	r.GET("/active", activeCache, active)
+	r.POST("/active", active)
+	r.GET("/active.json-feed", activeCache, active)
+
+	background.Add(1)
+
+	go func() {
+		defer background.Done()
+		followUpdates(ctx, cfg, client, httpClient, source, responses, updates)
+	}()
+
+	changes := newLRUCache[string, activeState](cfg.CacheEntries, 0, nil)
+
+	r.GET("/active/changes", func(c *gin.Context) {
+		handleActiveChanges(c, source, followed, formatter, live.Load().Block, changes)
+	})
+	r.GET("/active/users", activeCache, func(c *gin.Context) { handleActiveUsers(c, source, live.Load().Block) })
+
+	r.GET("/item/:id/tree", treeCache, func(c *gin.Context) {
+		handleItemDescendants(c, client, formatter, profiles, live.Load().Block)
+	})
+	r.GET("/item/:id", func(c *gin.Context) { handleItem(c, client, formatter) })
+	r.POST("/items", func(c *gin.Context) { handleItems(c, client, formatter, cfg.MaxBatchItems) })
+	r.GET("/item/:id/children", treeCache, func(c *gin.Context) {
+		handleItemChildren(c, client, formatter, live.Load().Block)
+	})
+	r.GET("/item/:id/ancestors", treeCache, func(c *gin.Context) { handleItemAncestors(c, client, formatter) })
+	r.GET("/item/:id/live", func(c *gin.Context) { handleLive(c, client, updates, formatter, live.Load()) })
+	r.GET("/item/:id/stats", func(c *gin.Context) { handleItemStats(c, st) })
+	r.GET("/item/:id/rank-history", func(c *gin.Context) { handleRankHistory(c, st) })
+	r.GET("/front", activeCache, func(c *gin.Context) { handleFrontPage(c, client, httpClient) })
+	r.GET("/newest", activeCache, func(c *gin.Context) { handleNewest(c, client, httpClient, formatter) })
+	r.GET("/quiet", activeCache, func(c *gin.Context) { handleQuiet(c, client, httpClient, source, formatter) })
+	r.GET("/dupes", activeCache, func(c *gin.Context) { handleDupes(c, client, httpClient) })
+	r.GET("/search", activeCache, func(c *gin.Context) { handleSearch(c, formatter) })
+	r.GET("/lists/:name", activeCache, func(c *gin.Context) { handleList(c, client, httpClient, formatter) })
+	r.GET("/second-chance", activeCache, func(c *gin.Context) { handleSecondChance(c, client, httpClient) })
+	r.GET("/user/:name", treeCache, func(c *gin.Context) { handleUser(c, client, httpClient, formatter) })
+
+	schema, gerr := newGraphQLSchema(client, httpClient, source, formatter)
+	if gerr != nil {
+		log.Fatal(gerr)
+	}
+
+	r.GET("/graphql", func(c *gin.Context) { handleGraphQL(c, schema, live.Load().responseBudget()) })
+	r.POST("/graphql", func(c *gin.Context) { handleGraphQL(c, schema, live.Load().responseBudget()) })
+	r.GET("/openapi.json", handleOpenAPI(buildOpenAPI()))
+
+	r.GET("/cache/search", func(c *gin.Context) { handleCacheSearch(c, client, st, formatter) })
+	r.GET("/history/active", func(c *gin.Context) { handleHistoryActive(c, st) })
+	r.GET("/export/active", func(c *gin.Context) { handleExportActive(c, st) })
+
+	registerAdmin(r, live, responses, formatter)
+	registerSubscriptions(r, cfg, st)
+	registerWatches(r, cfg, st)
+	registerDigests(r, live, st)
+	registerFollows(ctx, r, cfg, client, st, follows)
+	registerFollowedUsers(r, cfg, st, followed, responses)
+	r.GET("/items/stream", func(c *gin.Context) { handleItemStream(ctx, c, items, cfg.IngestInterval > 0) })
+	registerDebug(r, cfg)
+
+	ready := &readiness{source: source, store: st, upstream: upstream, live: live}
+
+	r.GET("/version", handleVersion(info))
+	r.GET("/healthz", handleHealthz)
+	r.GET("/readyz", ready.handleReadyz)
+
+	if cfg.Docs {
+		r.GET("/docs", handleDocs)
+	}
+
+	serve(ctx, stop, r, cfg)
+
+	// the client is closed by the deferred call only once nothing else can be using it
+	background.Wait()
+}
+
+// newRouter creates the engine with the middleware shared by every route.
+func newRouter(live *liveConfig, info handleVersionResponse) *gin.Engine {
+	cfg := live.Load()
+
+	reporter, err := newPanicReporter(cfg.SentryDSN, info.Version)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.GinMode != "" {
+		gin.SetMode(cfg.GinMode)
+	}
+
+	r := gin.New()
+	r.Use(canonicalParams())
+	r.Use(logRequests(slog.Default()), recoverPanics(reporter), traceRequests(), addVersionHeader(info))
+	r.Use(allowCORS(live), setCacheControl(live))
+	r.Use(limitRetries(live), limitDuration(live))
+
+	return r
+}
+
+// serve runs the HTTP server until ctx is done, then stops accepting connections and waits up
+// to the configured shutdown timeout for in-flight requests to finish.
+func serve(ctx context.Context, stop context.CancelFunc, handler http.Handler, cfg config) {
+	server := &http.Server{
+		Addr:              net.JoinHostPort(cfg.Addr, strconv.Itoa(cfg.Port)),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+	}
+
+	if len(cfg.AutocertDomains) > 0 {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+		}
+
+		// certificates are obtained with the TLS-ALPN-01 challenge so no plain HTTP listener is needed
+		server.TLSConfig = m.TLSConfig()
+	}
+
+	go func() {
+		var err error
+
+		log.Printf("listening on %s", server.Addr)
+
+		if server.TLSConfig != nil || cfg.TLSCert != "" {
+			err = server.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+		} else {
+			err = server.ListenAndServe()
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("failed to start server: %v", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+
+	log.Printf("shutting down, waiting up to %v for in-flight requests", cfg.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Printf("failed to shut down server gracefully: %v", err)
 	}
 }
 
@@ -50,109 +365,609 @@ type handleActiveRoot struct {
 }
 
 type handleActiveResponseItem struct {
-	By           string `json:"by,omitempty"`
-	Text         string `json:"text,omitempty"`
-	Age          string `json:"age"`
-	ID           int    `json:"id"`
-	Depth        int    `json:"depth"`
-	Active       bool   `json:"active,omitempty"`
-	SecondChance bool   `json:"secondchance,omitempty"`
+	*storyMetadata
+	Metrics           *rootMetrics                `json:"metrics,omitempty"`
+	By                string                      `json:"by,omitempty"`
+	Text              string                      `json:"text,omitempty"`
+	Age               string                      `json:"age,omitempty"`
+	TimeISO           string                      `json:"timeIso,omitempty"`
+	Children          []*handleActiveResponseItem `json:"children,omitempty"`
+	AccountAgeDays    *int                        `json:"accountAgeDays,omitempty"`
+	Karma             *int                        `json:"karma,omitempty"`
+	ActiveWindows     []string                    `json:"activeWindows,omitempty"`
+	ActivityScore     float64                     `json:"activityScore,omitempty"`
+	Time              int64                       `json:"time,omitempty"`
+	ID                int                         `json:"id"`
+	Parent            int                         `json:"parent,omitempty"`
+	Root              int                         `json:"root,omitempty"`
+	Depth             int                         `json:"depth"`
+	TruncatedChildren int                         `json:"truncatedChildren,omitempty"`
+	Conversation      int                         `json:"conversation,omitempty"`
+	Active            bool                        `json:"active,omitempty"`
+	Collapsed         bool                        `json:"collapsed,omitempty"`
+	SecondChance      bool                        `json:"secondchance,omitempty"`
+	OP                bool                        `json:"op,omitempty"`
+	Dead              bool                        `json:"dead,omitempty"`
+	Deleted           bool                        `json:"deleted,omitempty"`
+	IsNew             bool                        `json:"isNew,omitempty"`
+	NewUser           bool                        `json:"newUser,omitempty"`
+	Followed          bool                        `json:"followed,omitempty"`
 }
 
+// handleActiveResponse has the truncation fields after the items since a streamed response only
+// knows them once every item is written.
+//
+//nolint:govet // field order is the JSON order
 type handleActiveResponse struct {
-	Items              []handleActiveResponseItem `json:"items"`
-	SecondChanceFailed bool                       `json:"secondChanceFailed"`
+	Items []handleActiveResponseItem `json:"items"`
+	*responseTruncation
+	SecondChanceFailed bool `json:"secondChanceFailed"`
+	Stale              bool `json:"stale,omitempty"`
 }
 
-//nolint:cyclop // need parsing helper
-func handleActive(c *gin.Context, client *hn.Client, textCache *core.MapCache[*hn.Item, string]) {
-	ctx := c.Request.Context()
+type handleActiveNestedResponse struct {
+	*responseTruncation
+	Items              []*handleActiveResponseItem `json:"items"`
+	SecondChanceFailed bool                        `json:"secondChanceFailed"`
+	Stale              bool                        `json:"stale,omitempty"`
+}
+
+func defaultActiveParams() activeParams {
+	window, _ := time.ParseDuration(defaultWindow)
+	maxAge, _ := time.ParseDuration(defaultMaxAge)
+
+	return activeParams{Window: window, MaxAge: maxAge, MinBy: defaultMinBy}
+}
 
-	window, err := time.ParseDuration(c.DefaultQuery("window", "1h"))
+// parseActiveParams reads the window, max-age, and min-by query parameters, which default to
+// defaults, adding an error to errs for each that is invalid or outside bounds. The window of the
+// params is the longest of the windows, which are returned shortest first.
+func parseActiveParams(
+	c *gin.Context,
+	defaults activeParams,
+	bounds activeBounds,
+	errs *paramErrors,
+) (activeParams, []activeWindow) {
+	windows := parseWindows(c, errs, defaults.Window, bounds)
+
+	var window time.Duration
+	if len(windows) > 0 {
+		window = windows[len(windows)-1].Duration
+	}
+
+	maxAge, ok := parseDurationParam(c, errs, "max-age", defaults.MaxAge, codeInvalidMaxAge, "invalid max-age duration")
+	if ok {
+		errs.addRange(bounds.checkMaxAge(maxAge))
+	}
+
+	minBy, err := strconv.Atoi(c.DefaultQuery("min-by", strconv.Itoa(defaults.MinBy)))
 	if err != nil {
-		c.PureJSON(http.StatusBadRequest, gin.H{"error": "invalid window duration"})
-		return
+		errs.add(codeInvalidMinBy, "min-by", "invalid min-by")
+	} else {
+		errs.addRange(bounds.checkMinBy(minBy))
+	}
+
+	return activeParams{Window: window, MaxAge: maxAge, MinBy: minBy}, windows
+}
+
+// parseActiveItemOptions reads the query parameters that control how the items under /active
+// roots are returned, adding an error to errs for each that is invalid.
+func parseActiveItemOptions(c *gin.Context, defaultBlock []string, errs *paramErrors) activeItemOptions {
+	showUser, ok := parseShowUser(c)
+	if !ok {
+		errs.add(codeInvalidUser, "show-user", "invalid show-user")
+	}
+
+	maxDepth, ok := parseMaxDepth(c)
+	if !ok {
+		errs.add(codeInvalidMaxDepth, "max-depth", "invalid max-depth")
 	}
 
-	maxAge, err := time.ParseDuration(c.DefaultQuery("max-age", "24h"))
+	format, ok := parseTimeFormat(c)
+	if !ok {
+		errs.add(codeInvalidTimeFormat, "time-format", "invalid time-format")
+	}
+
+	textMode, ok := parseTextMode(c)
+	if !ok {
+		errs.add(codeInvalidText, "text", "invalid text")
+	}
+
+	showDead, ok := parseShowDead(c)
+	if !ok {
+		errs.add(codeInvalidShowDead, "show-dead", "invalid show-dead")
+	}
+
+	sort, ok := parseCommentSort(c)
+	if !ok {
+		errs.add(codeInvalidCommentSort, "comment-sort", "invalid comment-sort")
+	}
+
+	only, ok := parseOnly(c)
+	if !ok {
+		errs.add(codeInvalidOnly, "only", "invalid only")
+	}
+
+	activity, me := parseActivity(c, errs)
+
+	enrich, ok := parseEnrich(c)
+	if !ok {
+		errs.add(codeInvalidEnrich, "enrich", "invalid enrich")
+	}
+
+	return activeItemOptions{
+		Blocked:  parseBlocked(c, defaultBlock),
+		Seen:     parseSeen(c, errs),
+		MaxDepth: maxDepth,
+		Format:   format,
+		Text:     textMode,
+		Activity: activity,
+		Me:       me,
+		Windows:  nil,
+		Profiles: nil,
+		Sort:     sort,
+		ShowUser: showUser,
+		ShowDead: showDead,
+		Only:     only,
+		Follow:   parseFollow(c, errs),
+		Followed: nil,
+		Enrich:   enrich,
+	}
+}
+
+// getActiveSnapshot parses the query parameters shared by the /active endpoints and returns the
+// matching snapshot with its roots filtered. If any parameter is invalid, it responds with those
+// errors along with the ones already in errs and returns false; it also responds with an error and
+// returns false if the snapshot cannot be computed.
+func getActiveSnapshot(
+	c *gin.Context,
+	source *activeSource,
+	followed *followList,
+	defaultBlock []string,
+	errs paramErrors,
+) (*activeSnapshot, activeItemOptions, bool) {
+	var invalid activeItemOptions
+
+	params, windows := parseActiveParams(c, source.Params(), source.Bounds(), &errs)
+	opts := parseActiveItemOptions(c, defaultBlock, &errs)
+	opts.Windows = windows
+	filter := parseRootFilter(c, &errs)
+	filter.Blocked = opts.Blocked
+
+	at, ok := parseAt(c)
+	if !ok {
+		errs.add(codeInvalidAt, "at", "invalid at")
+	}
+
+	if errs.respond(c) {
+		return nil, invalid, false
+	}
+
+	opts.Followed = followed.with(opts.Follow)
+
+	snapshot, err := source.At(c.Request.Context(), at, params)
 	if err != nil {
-		c.PureJSON(http.StatusBadRequest, gin.H{"error": "invalid max_age duration"})
+		respondSnapshotError(c, err)
+		return nil, invalid, false
+	}
+
+	if snapshot.Stale {
+		// clients and the response cache should come back for a fresh snapshot
+		c.Header("Cache-Control", "no-cache")
+	}
+
+	return filter.apply(snapshot), opts, true
+}
+
+// respondSnapshotError responds to a failure to get an active snapshot: 501 without the store a
+// past snapshot needs, 503 while HN requests are failing fast, and 502 for any other HN failure.
+func respondSnapshotError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errNoStore):
+		respondStoreDisabled(c)
+	case errors.Is(err, errCircuitOpen):
+		respondError(c, http.StatusServiceUnavailable, codeHNUpstreamError, err.Error())
+	default:
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, err.Error())
+	}
+}
+
+// activeShape is how /active items are returned, from the shape, fields, and group-by parameters.
+type activeShape struct {
+	Fields        fieldSet
+	Nested        bool
+	GroupByDomain bool
+}
+
+// parseActiveShape reads the shape, fields, and group-by query parameters, adding an error to errs
+// for each that is invalid.
+func parseActiveShape(c *gin.Context, errs *paramErrors) activeShape {
+	nested, ok := parseNested(c)
+	if !ok {
+		errs.add(codeInvalidShape, "shape", "invalid shape")
+	}
+
+	fields, ok := parseFields(c, (*handleActiveResponseItem)(nil))
+	if !ok {
+		errs.add(codeInvalidFields, "fields", "invalid fields")
+	}
+
+	groupByDomain, ok := parseGroupByDomain(c)
+	if !ok {
+		errs.add(codeInvalidGroupBy, "group-by", "invalid group-by")
+	}
+
+	return activeShape{Fields: fields, Nested: nested, GroupByDomain: groupByDomain}
+}
+
+// streams reports whether the response is streamed: only the flat JSON shape with all fields is,
+// since grouping, nesting, picking fields, and MessagePack all need every item first.
+func (s activeShape) streams(c *gin.Context) bool {
+	return !s.Nested && s.Fields == nil && !s.GroupByDomain && !wantsMsgPack(c)
+}
+
+func handleActive(
+	c *gin.Context,
+	source *activeSource,
+	followed *followList,
+	formatter *textFormatter,
+	profiles *profileFetcher,
+	cfg config,
+) {
+	var errs paramErrors
+
+	shape := parseActiveShape(c, &errs)
+
+	snapshot, opts, ok := getActiveSnapshot(c, source, followed, cfg.Block, errs)
+	if !ok {
 		return
 	}
 
-	minBy, err := strconv.Atoi(c.DefaultQuery("min-by", "3"))
-	if err != nil {
-		c.PureJSON(http.StatusBadRequest, gin.H{"error": "invalid min_by"})
+	opts.Profiles = profiles
+
+	if wantsJSONFeed(c) {
+		renderActiveFeed(c, snapshot.Roots, formatter, opts.ShowUser)
 		return
 	}
 
-	user, err := strconv.Atoi(c.DefaultQuery("user", "1"))
-	if err != nil {
-		c.PureJSON(http.StatusBadRequest, gin.H{"error": "invalid user"})
+	_, span := startSpan(c.Request.Context(), "FlattenTree", attribute.Int("hn.roots", len(snapshot.Roots)))
+	roots := newBudgetedRoots(cfg.responseBudget())
+	all := roots.all(activeRoots(snapshot, formatter, snapshot.now(), opts))
+
+	if shape.streams(c) {
+		streamActiveResponse(c, snapshot, all, roots.truncation)
+		endSpan(span, nil)
+
 		return
 	}
 
-	now := time.Now()
-	activeAfter := now.Add(-window)
+	items := slices.Collect(all)
+
+	endSpan(span, nil)
+
+	if shape.GroupByDomain {
+		respondActiveGroups(c, joinRoots(items), shape.Nested, shape.Fields, snapshot, roots.truncation())
+		return
+	}
+
+	respondActiveItems(c, joinRoots(items), shape.Nested, shape.Fields, snapshot, roots.truncation())
+}
+
+// respondActiveItems responds with the items of an ungrouped /active response in the requested
+// shape and fields.
+func respondActiveItems(
+	c *gin.Context,
+	items []handleActiveResponseItem,
+	nested bool,
+	fields fieldSet,
+	snapshot *activeSnapshot,
+	truncation *responseTruncation,
+) {
+	if fields != nil {
+		picked := fields.pick(items)
+		if nested {
+			picked = fields.pick(nestActiveItems(items))
+		}
+
+		respond(c, http.StatusOK, sparseActiveResponse{
+			Items:              picked,
+			SecondChanceFailed: snapshot.SecondChanceFailed,
+			Stale:              snapshot.Stale,
+			responseTruncation: truncation,
+		})
 
-	roots, tree, secondChanceFailed, err := getActiveRoots(ctx, client, now, activeAfter, maxAge, minBy)
-	if err != nil {
-		c.PureJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	const estimatedItemsPerRoot = 10
-	items := make([]handleActiveResponseItem, 0, len(roots)*estimatedItemsPerRoot)
+	if nested {
+		respond(c, http.StatusOK, handleActiveNestedResponse{
+			Items:              nestActiveItems(items),
+			SecondChanceFailed: snapshot.SecondChanceFailed,
+			Stale:              snapshot.Stale,
+			responseTruncation: truncation,
+		})
 
-	for _, root := range roots {
-		flat := unl.FlattenTree(root.Item, tree)
-		activeMap := unl.BuildActiveMap(flat, activeAfter)
-		activeMap[root.Item.ID] = unl.ActiveMapChild
+		return
+	}
 
-		for _, item := range flat {
-			t := item.Time
-			ae := activeMap[item.ID]
-			text := ""
+	response := handleActiveResponse{
+		Items:              items,
+		SecondChanceFailed: snapshot.SecondChanceFailed,
+		Stale:              snapshot.Stale,
+		responseTruncation: truncation,
+	}
+
+	respond(c, http.StatusOK, response)
+}
+
+type activeItemOptions struct {
+	Blocked  map[string]bool
+	Profiles *profileFetcher
+	Followed map[string]bool
+	Seen     seenWatermarks
+	Activity string
+	Me       string
+	Only     onlyFilter
+	Windows  []activeWindow
+	Follow   []string
+	MaxDepth int
+	Format   timeFormat
+	Text     textMode
+	Sort     commentSort
+	ShowUser bool
+	ShowDead bool
+	Enrich   enrichment
+}
 
-			secondChance := false
+// activeItems flattens the trees under the snapshot roots into response items, including the text
+// of only the active items and the ancestors that lead to them.
+func activeItems(
+	snapshot *activeSnapshot,
+	formatter *textFormatter,
+	now time.Time,
+	opts activeItemOptions,
+) []handleActiveResponseItem {
+	return joinRoots(slices.Collect(activeRoots(snapshot, formatter, now, opts)))
+}
+
+// joinRoots concatenates the response items of each root, returning an empty slice rather than nil
+// so that no roots still encode as an empty list.
+func joinRoots(perRoot [][]handleActiveResponseItem) []handleActiveResponseItem {
+	total := 0
+	for _, items := range perRoot {
+		total += len(items)
+	}
+
+	joined := make([]handleActiveResponseItem, 0, total)
+	for _, items := range perRoot {
+		joined = append(joined, items...)
+	}
+
+	return joined
+}
+
+// activeRoots returns the response items of each snapshot root in order. Roots are processed
+// concurrently by up to GOMAXPROCS workers, so the items of one root can be handled while later
+// roots are still being flattened.
+func activeRoots(
+	snapshot *activeSnapshot,
+	formatter *textFormatter,
+	now time.Time,
+	opts activeItemOptions,
+) iter.Seq[[]handleActiveResponseItem] {
+	return func(yield func([]handleActiveResponseItem) bool) {
+		activeAfter := now.Add(-snapshot.Params.Window)
+
+		// each result channel is buffered so workers never wait on yield; once yield stops, stopped
+		// lets the remaining workers skip their roots
+		results := make([]chan []handleActiveResponseItem, len(snapshot.Roots))
+		for i := range results {
+			results[i] = make(chan []handleActiveResponseItem, 1)
+		}
 
-			if item.ID == root.Item.ID {
-				t = root.Time
-				secondChance = item.Time != root.Time
+		stopped := make(chan struct{})
+		defer close(stopped)
+
+		go func() {
+			var workers errgroup.Group
+
+			workers.SetLimit(runtime.GOMAXPROCS(0))
+
+			for i, root := range snapshot.Roots {
+				workers.Go(func() error {
+					select {
+					case <-stopped:
+						results[i] <- nil
+					default:
+						results[i] <- activeRootItems(snapshot, root, formatter, activeAfter, now, opts)
+					}
+
+					return nil
+				})
 			}
 
-			if ae != 0 {
-				text = formatText(item.Item, textCache)
+			_ = workers.Wait()
+		}()
+
+		for _, result := range results {
+			if !yield(<-result) {
+				return
 			}
+		}
+	}
+}
+
+// activeRootItems returns the response items of the tree under a single snapshot root.
+func activeRootItems(
+	snapshot *activeSnapshot,
+	root handleActiveRoot,
+	formatter *textFormatter,
+	activeAfter time.Time,
+	now time.Time,
+	opts activeItemOptions,
+) []handleActiveResponseItem {
+	flat := unl.FlattenTree(root.Item, snapshot.Tree)
+
+	flatItems := make([]*hn.Item, 0, len(flat))
+	depths := make([]int, 0, len(flat))
+
+	for _, item := range flat {
+		flatItems = append(flatItems, item.Item)
+		depths = append(depths, item.Depth)
+	}
+
+	isActive := threadActivity(flat, root.Item, snapshot.Params.Window, activeAfter, opts)
+	subtrees := activeSubtrees(flatItems, depths, isActive)
+	activeMap := buildActiveMap(flat, activeAfter, opts.Activity, isActive, subtrees)
+	activeMap[root.Item.ID] = unl.ActiveMapChild
+	scores := activityScores(flatItems, depths, isActive, activeAfter, snapshot.Params.Window)
+	windows := itemWindows(flat, root.Item, now, opts)
+	profiles := opts.Profiles.lookup(flatAuthors(flat))
+
+	items := make([]handleActiveResponseItem, 0, len(flat))
+	threadMetrics := newRootMetrics(flatItems, depths, activeAfter, now)
+
+	view := viewThread(flatItems, depths, opts)
+	flat = reorder(flat, view.Order)
+	depths = reorder(depths, view.Order)
+
+	keep, truncated := truncateDepth(depths, opts.MaxDepth)
+
+	for i, item := range flat {
+		if !keep[i] {
+			continue
+		}
+
+		t := item.Time
+		ae := activeMap[item.ID]
+		text := ""
+
+		secondChance := false
+
+		var (
+			story   *storyMetadata
+			metrics *rootMetrics
+		)
 
-			by := item.By
-			if user != 1 {
-				by = ""
+		if item.ID == root.Item.ID {
+			t = root.Time
+			secondChance = item.Time != root.Time
+			story = newStoryMetadata(item.Item)
+			metrics = threadMetrics
+		}
+
+		if ae != 0 && !view.Placeholders[item.ID] {
+			text = formatter.formatAs(item.Item, opts.Text)
+		}
+
+		by := item.By
+		if !opts.ShowUser || view.Placeholders[item.ID] {
+			by = ""
+		}
+
+		age, unix, iso := opts.Format.format(now, t)
+		accountAge, newUser := profiles[by].accountAge(now)
+
+		items = append(items, handleActiveResponseItem{
+			storyMetadata:     story,
+			Metrics:           metrics,
+			By:                by,
+			Text:              text,
+			Age:               age,
+			TimeISO:           iso,
+			Children:          nil,
+			AccountAgeDays:    accountAge,
+			Karma:             profiles[by].karma(opts.Enrich),
+			Time:              unix,
+			Active:            (ae & unl.ActiveMapSelf) > 0,
+			ActiveWindows:     windows[item.ID],
+			ActivityScore:     scores[item.ID],
+			Collapsed:         isCollapsed(item.Item, root.Item, subtrees),
+			ID:                item.ID,
+			Parent:            item.Parent,
+			Root:              root.Item.ID,
+			Depth:             item.Depth,
+			SecondChance:      secondChance,
+			TruncatedChildren: truncated[i],
+			OP:                isOP(item.Item, root.Item),
+			Dead:              item.Dead,
+			Deleted:           item.Deleted,
+			IsNew:             opts.Seen.isNew(root.Item.ID, item.Item, item.Depth),
+			NewUser:           newUser,
+			Conversation:      view.Conversations[item.ID],
+			Followed:          !view.Placeholders[item.ID] && isFollowed(item.Item, opts.Followed),
+		})
+	}
+
+	return items
+}
+
+// threadView is how a flattened thread is returned: the indexes of its entries in the order they
+// are returned, the IDs of placeholders from visibleItems, and the conversation of each item ID.
+type threadView struct {
+	Placeholders  map[int]bool
+	Conversations map[int]int
+	Order         []int
+}
+
+// viewThread removes the entries of a flattened thread with the given items and depths that are
+// hidden by opts, sorts the rest, and finds their conversations.
+func viewThread(items []*hn.Item, depths []int, opts activeItemOptions) threadView {
+	visible, placeholders := visibleItems(items, depths, opts.ShowDead, opts.Blocked)
+
+	order := make([]int, 0, len(items))
+	for i := range items {
+		order = append(order, i)
+	}
+
+	order = keepOnly(order, visible)
+	order = reorder(order, commentOrder(reorder(items, order), reorder(depths, order), opts.Sort))
+
+	ids := conversationIDs(reorder(items, order), reorder(depths, order))
+
+	if opts.Only != onlyAll {
+		only := make([]bool, len(ids))
+
+		for i, id := range ids {
+			if opts.Only == onlyConversations {
+				only[i] = id != 0
+			} else {
+				only[i] = isFollowed(items[order[i]], opts.Followed)
 			}
+		}
+
+		keep := withAncestors(reorder(depths, order), only)
+		order = keepOnly(order, keep)
+		ids = keepOnly(ids, keep)
+	}
 
-			items = append(items, handleActiveResponseItem{
-				By:           by,
-				Text:         text,
-				Age:          unl.PrettyFormatDuration(now.Sub(time.Unix(t, 0))),
-				Active:       (ae & unl.ActiveMapSelf) > 0,
-				ID:           item.ID,
-				Depth:        item.Depth,
-				SecondChance: secondChance,
-			})
+	conversations := make(map[int]int)
+
+	for i, id := range ids {
+		if id != 0 {
+			conversations[items[order[i]].ID] = id
 		}
 	}
 
-	response := handleActiveResponse{
-		Items:              items,
-		SecondChanceFailed: secondChanceFailed,
+	return threadView{Placeholders: placeholders, Conversations: conversations, Order: order}
+}
+
+func nestActiveItems(items []handleActiveResponseItem) []*handleActiveResponseItem {
+	ptrs := make([]*handleActiveResponseItem, 0, len(items))
+	for i := range items {
+		ptrs = append(ptrs, &items[i])
 	}
 
-	c.PureJSON(http.StatusOK, response)
+	return nestByDepth(
+		ptrs,
+		func(item *handleActiveResponseItem) int { return item.Depth },
+		func(item *handleActiveResponseItem) *[]*handleActiveResponseItem { return &item.Children },
+	)
 }
 
 func getActiveRoots(
 	ctx context.Context,
-	client *hn.Client,
+	client *itemClient,
 	now time.Time,
 	activeAfter time.Time,
 	maxAge time.Duration,
@@ -160,7 +975,7 @@ func getActiveRoots(
 ) ([]handleActiveRoot, map[int]hn.ItemSet, bool, error) {
 	var secondChanceFailed bool
 
-	frontPageTimes, err := unl.FetchFrontPageTimes(ctx, now)
+	frontPageTimes, err := fetchFrontPageTimes(ctx, client, now)
 	if err != nil {
 		frontPageTimes = nil
 		secondChanceFailed = true
@@ -168,7 +983,15 @@ func getActiveRoots(
 
 	agedAfter := time.Now().Add(-maxAge)
 
-	items, tree, err := unl.GetActive(ctx, client, frontPageTimes, activeAfter, agedAfter, minBy, 0)
+	spanCtx, span := startSpan(ctx, "GetActive", attribute.Int("unl.min_by", minBy))
+	items, tree, err := unl.GetActive(spanCtx, client.Client, frontPageTimes, activeAfter, agedAfter, minBy, 0)
+
+	if err == nil {
+		items, tree, err = client.activeWithChanges(spanCtx, items, tree)
+	}
+
+	endSpan(span, err)
+
 	if err != nil {
 		return nil, nil, secondChanceFailed, err
 	}
@@ -192,80 +1015,356 @@ func getActiveRoots(
 }
 
 type handleItemDescendantsResponse struct {
-	By    string `json:"by,omitempty"`
-	Text  string `json:"text,omitempty"`
-	Time  int64  `json:"time"`
-	ID    int    `json:"id"`
-	Depth int    `json:"depth"`
+	*storyMetadata
+	AccountAgeDays    *int                             `json:"accountAgeDays,omitempty"`
+	Karma             *int                             `json:"karma,omitempty"`
+	By                string                           `json:"by,omitempty"`
+	Text              string                           `json:"text,omitempty"`
+	Children          []*handleItemDescendantsResponse `json:"children,omitempty"`
+	Time              int64                            `json:"time"`
+	ID                int                              `json:"id"`
+	Parent            int                              `json:"parent,omitempty"`
+	Root              int                              `json:"root,omitempty"`
+	Depth             int                              `json:"depth"`
+	TruncatedChildren int                              `json:"truncatedChildren,omitempty"`
+	OP                bool                             `json:"op,omitempty"`
+	Dead              bool                             `json:"dead,omitempty"`
+	Deleted           bool                             `json:"deleted,omitempty"`
+	IsNew             bool                             `json:"isNew,omitempty"`
+	NewUser           bool                             `json:"newUser,omitempty"`
+}
+
+type handleItemDescendantsPageResponse struct {
+	NextCursor string                          `json:"nextCursor,omitempty"`
+	Items      []handleItemDescendantsResponse `json:"items"`
+}
+
+type handleItemDescendantsNestedPageResponse struct {
+	NextCursor string                           `json:"nextCursor,omitempty"`
+	Items      []*handleItemDescendantsResponse `json:"items"`
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageBounds returns the bounds of up to limit entries of ids starting at the entry equal to
+// cursor, and the cursor for the following page. Cursors are item IDs so pages remain stable
+// when new comments are added elsewhere in the tree.
+func pageBounds(ids []int, cursor string, limit int) (int, int, string, error) {
+	start := 0
+
+	if cursor != "" {
+		cursorID, err := strconv.Atoi(cursor)
+		if err != nil {
+			return 0, 0, "", errInvalidCursor
+		}
+
+		start = slices.Index(ids, cursorID)
+		if start < 0 {
+			return 0, 0, "", errInvalidCursor
+		}
+	}
+
+	end := min(start+limit, len(ids))
+
+	next := ""
+	if end < len(ids) {
+		next = strconv.Itoa(ids[end])
+	}
+
+	return start, end, next, nil
 }
 
-func handleItemDescendants(c *gin.Context, client *hn.Client, textCache *core.MapCache[*hn.Item, string]) {
+//nolint:cyclop // need parsing helper
+func handleItemDescendants(
+	c *gin.Context,
+	client *itemClient,
+	formatter *textFormatter,
+	profiles *profileFetcher,
+	defaultBlock []string,
+) {
 	ctx := c.Request.Context()
 
 	idParam := c.Param("id")
 
 	itemID, err := strconv.Atoi(idParam)
 	if err != nil {
-		c.PureJSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondParamError(c, codeInvalidID, "id", "invalid id")
 		return
 	}
 
-	items, err := client.GetItems(ctx, []int{itemID})
+	streaming, ok := parseStreaming(c)
+	if !ok {
+		respondParamError(c, codeInvalidFormat, "format", "invalid format")
+		return
+	}
+
+	if streaming {
+		handleItemDescendantsNDJSON(c, client, formatter, profiles, defaultBlock, itemID)
+		return
+	}
+
+	partial, resume, ok := parsePartial(c)
+	if !ok {
+		return
+	}
+
+	items, item, err := getItem(ctx, client, itemID)
 	if err != nil {
-		c.PureJSON(http.StatusBadRequest, gin.H{"error": "failed to retrieve item"})
+		respondItemError(c, err)
 		return
 	}
 
-	item := items[itemID]
+	storyID, err := getStoryID(ctx, client, item)
+	if err != nil {
+		respondItemError(c, err)
+		return
+	}
+
+	var all hn.ItemSet
+
+	if partial {
+		all, resume, err = getPartialDescendants(ctx, client, item, resume)
+	} else {
+		all, err = fetchDescendants(ctx, client, items)
+	}
+
+	if errors.Is(err, errInvalidResume) {
+		respondParamError(c, codeInvalidResume, "resume", "resume is not a top-level comment of the item")
+		return
+	}
 
-	all, err := client.GetDescendants(ctx, items)
 	if err != nil {
-		c.PureJSON(http.StatusBadRequest, gin.H{"error": "failed to retrieve item descendants"})
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve item descendants")
 		return
 	}
 
+	_, span := startSpan(ctx, "FlattenTree", attribute.Int("hn.items", len(all)))
+
 	allByParent, _, err := all.GroupByParent()
 	if err != nil {
-		c.PureJSON(http.StatusBadRequest, gin.H{"error": "failed to group item descendants by parent"})
+		endSpan(span, err)
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to group item descendants by parent")
+
 		return
 	}
 
 	flat := unl.FlattenTree(item, allByParent)
 
-	response := make([]handleItemDescendantsResponse, 0, len(flat))
+	endSpan(span, nil)
 
-	user, err := strconv.Atoi(c.DefaultQuery("user", "1"))
-	if err != nil {
-		c.PureJSON(http.StatusBadRequest, gin.H{"error": "invalid user"})
+	showUser, ok := parseShowUser(c)
+	if !ok {
+		respondParamError(c, codeInvalidUser, "show-user", "invalid show-user")
 		return
 	}
 
+	maxDepth, ok := parseMaxDepth(c)
+	if !ok {
+		respondParamError(c, codeInvalidMaxDepth, "max-depth", "invalid max-depth")
+		return
+	}
+
+	showDead, ok := parseShowDead(c)
+	if !ok {
+		respondParamError(c, codeInvalidShowDead, "show-dead", "invalid show-dead")
+		return
+	}
+
+	sort, ok := parseCommentSort(c)
+	if !ok {
+		respondParamError(c, codeInvalidCommentSort, "comment-sort", "invalid comment-sort")
+		return
+	}
+
+	var errs paramErrors
+
+	seen := parseSeen(c, &errs)
+	if errs.respond(c) {
+		return
+	}
+
+	flatItems := make([]*hn.Item, 0, len(flat))
+	depths := make([]int, 0, len(flat))
+
+	for _, f := range flat {
+		flatItems = append(flatItems, f.Item)
+		depths = append(depths, f.Depth)
+	}
+
+	visible, placeholders := visibleItems(flatItems, depths, showDead, parseBlocked(c, defaultBlock))
+	flat = keepOnly(flat, visible)
+	depths = keepOnly(depths, visible)
+
+	order := commentOrder(keepOnly(flatItems, visible), depths, sort)
+	flat = reorder(flat, order)
+	depths = reorder(depths, order)
+
+	keep, truncated := truncateDepth(depths, maxDepth)
+	kept := flat[:0]
+	truncatedByID := make(map[int]int, len(flat))
+
+	for i, f := range flat {
+		if keep[i] {
+			kept = append(kept, f)
+			truncatedByID[f.ID] = truncated[i]
+		}
+	}
+
+	flat = kept
+
+	nested, ok := parseNested(c)
+	if !ok {
+		respondParamError(c, codeInvalidShape, "shape", "invalid shape")
+		return
+	}
+
+	textMode, ok := parseTextMode(c)
+	if !ok {
+		respondParamError(c, codeInvalidText, "text", "invalid text")
+		return
+	}
+
+	fields, ok := parseFields(c, (*handleItemDescendantsResponse)(nil))
+	if !ok {
+		respondParamError(c, codeInvalidFields, "fields", "invalid fields")
+		return
+	}
+
+	enrich, ok := parseEnrich(c)
+	if !ok {
+		respondParamError(c, codeInvalidEnrich, "enrich", "invalid enrich")
+		return
+	}
+
+	limitParam, hasLimit := c.GetQuery("limit")
+	cursor, hasCursor := c.GetQuery("cursor")
+	paged := hasLimit || hasCursor
+	nextCursor := ""
+
+	if paged {
+		limit := len(flat)
+
+		if hasLimit {
+			limit, err = strconv.Atoi(limitParam)
+			if err != nil || limit < 1 {
+				respondParamError(c, codeInvalidLimit, "limit", "invalid limit")
+				return
+			}
+		}
+
+		ids := make([]int, 0, len(flat))
+		for _, f := range flat {
+			ids = append(ids, f.ID)
+		}
+
+		var start, end int
+
+		start, end, nextCursor, err = pageBounds(ids, cursor, limit)
+		if err != nil {
+			respondParamError(c, codeInvalidCursor, "cursor", err.Error())
+			return
+		}
+
+		flat = flat[start:end]
+	}
+
+	response := make([]handleItemDescendantsResponse, 0, len(flat))
+	known := profiles.lookup(flatAuthors(flat))
+	now := time.Now()
+
 	for _, f := range flat {
 		by := f.By
-		if user != 1 {
+		if !showUser || placeholders[f.ID] {
 			by = ""
 		}
 
+		text := ""
+		if !placeholders[f.ID] {
+			text = formatter.formatAs(f.Item, textMode)
+		}
+
+		var story *storyMetadata
+		if f.ID == itemID {
+			story = newStoryMetadata(f.Item)
+		}
+
+		accountAge, newUser := known[by].accountAge(now)
+
 		response = append(response, handleItemDescendantsResponse{
-			By:    by,
-			Text:  formatText(f.Item, textCache),
-			Time:  f.Time,
-			ID:    f.ID,
-			Depth: f.Depth,
+			storyMetadata:     story,
+			By:                by,
+			Text:              text,
+			Children:          nil,
+			AccountAgeDays:    accountAge,
+			Karma:             known[by].karma(enrich),
+			Time:              f.Time,
+			ID:                f.ID,
+			Parent:            f.Parent,
+			Root:              storyID,
+			Depth:             f.Depth,
+			TruncatedChildren: truncatedByID[f.ID],
+			OP:                isOP(f.Item, item),
+			Dead:              f.Dead,
+			Deleted:           f.Deleted,
+			IsNew:             seen.isNew(itemID, f.Item, f.Depth),
+			NewUser:           newUser,
 		})
 	}
 
-	c.PureJSON(http.StatusOK, response)
-}
+	if len(response) > 0 && response[0].ID == itemID && maxDepth != 0 {
+		options, err := getPollOptions(ctx, client, item)
+		if err != nil {
+			respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve poll options")
+			return
+		}
+
+		response = slices.Insert(response, 1, pollOptionResponses(options, formatter, textMode, showUser)...)
+	}
+
+	if partial {
+		respondPartialDescendants(c, response, nested, fields, resume)
+		return
+	}
+
+	if fields != nil {
+		picked := fields.pick(response)
+		if nested {
+			picked = fields.pick(nestItemDescendants(response))
+		}
+
+		if paged {
+			respond(c, http.StatusOK, sparsePageResponse{Items: picked, NextCursor: nextCursor})
+		} else {
+			respond(c, http.StatusOK, picked)
+		}
 
-func formatText(item *hn.Item, textCache *core.MapCache[*hn.Item, string]) string {
-	found, _ := textCache.Get([]*hn.Item{item})
-	if len(found) > 0 {
-		return found[0].Value
+		return
+	}
+
+	switch {
+	case paged && nested:
+		respond(c, http.StatusOK, handleItemDescendantsNestedPageResponse{
+			NextCursor: nextCursor,
+			Items:      nestItemDescendants(response),
+		})
+	case paged:
+		respond(c, http.StatusOK, handleItemDescendantsPageResponse{NextCursor: nextCursor, Items: response})
+	case nested:
+		respond(c, http.StatusOK, nestItemDescendants(response))
+	default:
+		respond(c, http.StatusOK, response)
 	}
+}
 
-	text := unl.PrettyFormatTitle(item, true)
-	textCache.Put(item, text)
+func nestItemDescendants(items []handleItemDescendantsResponse) []*handleItemDescendantsResponse {
+	ptrs := make([]*handleItemDescendantsResponse, 0, len(items))
+	for i := range items {
+		ptrs = append(ptrs, &items[i])
+	}
 
-	return text
+	return nestByDepth(
+		ptrs,
+		func(item *handleItemDescendantsResponse) int { return item.Depth },
+		func(item *handleItemDescendantsResponse) *[]*handleItemDescendantsResponse { return &item.Children },
+	)
 }
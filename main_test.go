@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPageBounds(t *testing.T) {
+	t.Parallel()
+
+	ids := []int{10, 20, 30, 40, 50}
+
+	tests := []struct {
+		err    error
+		name   string
+		cursor string
+		next   string
+		ids    []int
+		limit  int
+		start  int
+		end    int
+	}{
+		{name: "first page", ids: ids, cursor: "", limit: 2, start: 0, end: 2, next: "30", err: nil},
+		{name: "middle page", ids: ids, cursor: "30", limit: 2, start: 2, end: 4, next: "50", err: nil},
+		{name: "last page", ids: ids, cursor: "50", limit: 2, start: 4, end: 5, next: "", err: nil},
+		{name: "exact fit", ids: ids, cursor: "", limit: 5, start: 0, end: 5, next: "", err: nil},
+		{name: "limit past end", ids: ids, cursor: "20", limit: 100, start: 1, end: 5, next: "", err: nil},
+		{name: "no ids", ids: nil, cursor: "", limit: 2, start: 0, end: 0, next: "", err: nil},
+		{name: "unknown cursor", ids: ids, cursor: "35", limit: 2, start: 0, end: 0, next: "", err: errInvalidCursor},
+		{name: "non-numeric cursor", ids: ids, cursor: "x", limit: 2, start: 0, end: 0, next: "", err: errInvalidCursor},
+		{name: "cursor with no ids", ids: nil, cursor: "10", limit: 2, start: 0, end: 0, next: "", err: errInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			start, end, next, err := pageBounds(tt.ids, tt.cursor, tt.limit)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error %v, want %v", err, tt.err)
+			}
+
+			if start != tt.start || end != tt.end || next != tt.next {
+				t.Errorf("bounds %d, %d, %q, want %d, %d, %q", start, end, next, tt.start, tt.end, tt.next)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+// rootMetrics summarizes the activity in the whole thread under a root, regardless of which of
+// its items are returned, so clients can rank threads by how busy they are.
+type rootMetrics struct {
+	ActiveComments          int     `json:"activeComments"`
+	ActiveCommenters        int     `json:"activeCommenters"`
+	CommentsPerHour         float64 `json:"commentsPerHour"`
+	LifetimeCommentsPerHour float64 `json:"lifetimeCommentsPerHour"`
+	MaxDepth                int     `json:"maxDepth"`
+	Controversial           bool    `json:"controversial"`
+}
+
+// newRootMetrics computes the metrics of the thread flattened into items and depths, where the
+// first item is the root. Active comments are those after activeAfter, and commentsPerHour counts
+// the comments of the last hour. Threads younger than an hour are treated as an hour old so their
+// lifetime rate is comparable. Dead and deleted comments are not counted. Controversial is set by
+// isControversial.
+func newRootMetrics(items []*hn.Item, depths []int, activeAfter time.Time, now time.Time) *rootMetrics {
+	var metrics rootMetrics
+
+	if len(items) == 0 {
+		return &metrics
+	}
+
+	lastHour := now.Add(-time.Hour).Unix()
+	commenters := make(map[string]bool)
+	comments := 0
+
+	for i, item := range items {
+		metrics.MaxDepth = max(metrics.MaxDepth, depths[i])
+
+		if depths[i] == 0 || item.Dead || item.Deleted {
+			continue
+		}
+
+		comments++
+
+		if item.Time > activeAfter.Unix() {
+			metrics.ActiveComments++
+			commenters[item.By] = true
+		}
+
+		if item.Time > lastHour {
+			metrics.CommentsPerHour++
+		}
+	}
+
+	metrics.ActiveCommenters = len(commenters)
+
+	lifetime := max(now.Sub(time.Unix(items[0].Time, 0)), time.Hour)
+	metrics.LifetimeCommentsPerHour = roundRate(float64(comments) / lifetime.Hours())
+	metrics.Controversial = isControversial(items, depths)
+
+	return &metrics
+}
+
+// flattenRoot flattens the tree under root into the items and depths newRootMetrics expects.
+func flattenRoot(root *hn.Item, tree map[int]hn.ItemSet) ([]*hn.Item, []int) {
+	flat := unl.FlattenTree(root, tree)
+	items := make([]*hn.Item, 0, len(flat))
+	depths := make([]int, 0, len(flat))
+
+	for _, f := range flat {
+		items = append(items, f.Item)
+		depths = append(depths, f.Depth)
+	}
+
+	return items, depths
+}
+
+// roundRate rounds a rate to one decimal place.
+func roundRate(rate float64) float64 {
+	const scale = 10
+
+	return math.Round(rate*scale) / scale
+}
+
+const (
+	// controversyMinComments is how many comments a thread needs before its ratio of comments to
+	// score counts, so a few early replies to a new story are not enough.
+	controversyMinComments = 40
+	// controversyCommentsPerPoint is the ratio of comments to score that suggests an argument
+	// rather than a well-received story.
+	controversyCommentsPerPoint = 1.5
+	// controversyChainDepth and controversyChainTime describe a rapid deep reply chain: a reply
+	// this deep posted within this time of the top-level comment it descends from.
+	controversyChainDepth = 6
+	controversyChainTime  = time.Hour
+	// controversyRepliers is how many distinct authors replying directly to one comment suggests a
+	// pile-on.
+	controversyRepliers = 8
+	// controversySignals is how many of the signals above make a thread controversial.
+	controversySignals = 2
+)
+
+// isControversial guesses whether the thread flattened into items and depths, where the first item
+// is the root, is a flame war. It counts three signals: many comments relative to the score of the
+// story, rapid deep reply chains, and many distinct authors replying to one comment.
+func isControversial(items []*hn.Item, depths []int) bool {
+	if len(items) == 0 {
+		return false
+	}
+
+	signals := 0
+	comments := 0
+
+	for i, item := range items {
+		if depths[i] > 0 && !item.Dead && !item.Deleted {
+			comments++
+		}
+	}
+
+	if comments >= controversyMinComments &&
+		float64(comments) >= controversyCommentsPerPoint*float64(max(items[0].Score, 1)) {
+		signals++
+	}
+
+	parent := parentIndexes(depths)
+
+	if hasRapidChain(items, depths, parent) {
+		signals++
+	}
+
+	if hasPileOn(items, parent) {
+		signals++
+	}
+
+	return signals >= controversySignals
+}
+
+// hasRapidChain reports whether any reply at controversyChainDepth or deeper was posted within
+// controversyChainTime of the top-level comment it descends from.
+func hasRapidChain(items []*hn.Item, depths []int, parent []int) bool {
+	top := make([]int, len(items))
+
+	for i := range items {
+		switch {
+		case depths[i] <= 1 || parent[i] < 0:
+			top[i] = i
+		default:
+			top[i] = top[parent[i]]
+		}
+
+		if depths[i] >= controversyChainDepth &&
+			time.Duration(items[i].Time-items[top[i]].Time)*time.Second <= controversyChainTime {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasPileOn reports whether any comment has direct replies from at least controversyRepliers
+// distinct authors.
+func hasPileOn(items []*hn.Item, parent []int) bool {
+	repliers := make(map[int]map[string]bool)
+
+	for i, item := range items {
+		p := parent[i]
+		if p <= 0 || item.By == "" {
+			continue
+		}
+
+		if repliers[p] == nil {
+			repliers[p] = make(map[string]bool)
+		}
+
+		repliers[p][item.By] = true
+
+		if len(repliers[p]) >= controversyRepliers {
+			return true
+		}
+	}
+
+	return false
+}
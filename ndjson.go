@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+
+	// ndjsonPrefetch is how many top-level subtrees are fetched ahead of the one being written.
+	ndjsonPrefetch = 4
+)
+
+type subtreeResult struct {
+	err     error
+	entries []treeEntry
+}
+
+// parseStreaming reads the format query parameter, which is either "json" (the default) or
+// "ndjson".
+func parseStreaming(c *gin.Context) (bool, bool) {
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		return false, true
+	case "ndjson":
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// handleItemDescendantsNDJSON streams the tree under an item with one flattened item per line.
+// Each subtree under a top-level comment is fetched separately, a few ahead of the one being
+// written, so the first lines are sent long before a large thread has been retrieved. Once the
+// first line is sent the status cannot change, so a later failure ends the stream with an error
+// object line instead of an item.
+//
+//nolint:cyclop // need parsing helper
+func handleItemDescendantsNDJSON(
+	c *gin.Context,
+	client *itemClient,
+	formatter *textFormatter,
+	profiles *profileFetcher,
+	defaultBlock []string,
+	itemID int,
+) {
+	ctx := c.Request.Context()
+
+	showUser, ok := parseShowUser(c)
+	if !ok {
+		respondParamError(c, codeInvalidUser, "show-user", "invalid show-user")
+		return
+	}
+
+	maxDepth, ok := parseMaxDepth(c)
+	if !ok {
+		respondParamError(c, codeInvalidMaxDepth, "max-depth", "invalid max-depth")
+		return
+	}
+
+	nested, ok := parseNested(c)
+	if !ok || nested {
+		respondParamError(c, codeInvalidShape, "shape", "format=ndjson requires shape=flat")
+		return
+	}
+
+	textMode, ok := parseTextMode(c)
+	if !ok {
+		respondParamError(c, codeInvalidText, "text", "invalid text")
+		return
+	}
+
+	fields, ok := parseFields(c, (*handleItemDescendantsResponse)(nil))
+	if !ok {
+		respondParamError(c, codeInvalidFields, "fields", "invalid fields")
+		return
+	}
+
+	enrich, ok := parseEnrich(c)
+	if !ok {
+		respondParamError(c, codeInvalidEnrich, "enrich", "invalid enrich")
+		return
+	}
+
+	showDead, ok := parseShowDead(c)
+	if !ok {
+		respondParamError(c, codeInvalidShowDead, "show-dead", "invalid show-dead")
+		return
+	}
+
+	blocked := parseBlocked(c, defaultBlock)
+
+	var errs paramErrors
+
+	seen := parseSeen(c, &errs)
+	if errs.respond(c) {
+		return
+	}
+
+	sort, ok := parseCommentSort(c)
+	if !ok || sort == commentSortLongest {
+		respondParamError(c, codeInvalidCommentSort, "comment-sort", "format=ndjson cannot sort by longest-subthread")
+		return
+	}
+
+	_, hasLimit := c.GetQuery("limit")
+	_, hasCursor := c.GetQuery("cursor")
+
+	if hasLimit || hasCursor {
+		respondParamError(c, codeInvalidFormat, "format", "format=ndjson cannot be paged")
+		return
+	}
+
+	_, root, err := getItem(ctx, client, itemID)
+	if err != nil {
+		respondItemError(c, err)
+		return
+	}
+
+	storyID, err := getStoryID(ctx, client, root)
+	if err != nil {
+		respondItemError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", ndjsonContentType)
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	enc.SetEscapeHTML(false)
+
+	encode := func(line handleItemDescendantsResponse) bool {
+		var err error
+		if fields != nil {
+			err = enc.Encode(fields.pick([]handleItemDescendantsResponse{line})[0])
+		} else {
+			err = enc.Encode(line)
+		}
+
+		if err != nil {
+			return false
+		}
+
+		c.Writer.Flush()
+
+		return true
+	}
+
+	now := time.Now()
+
+	write := func(entry treeEntry, truncated int, placeholder bool) bool {
+		by := entry.Item.By
+		if !showUser || placeholder {
+			by = ""
+		}
+
+		text := ""
+		if !placeholder {
+			text = formatter.formatAs(entry.Item, textMode)
+		}
+
+		var story *storyMetadata
+		if entry.Depth == 0 {
+			story = newStoryMetadata(entry.Item)
+		}
+
+		profile := profiles.lookup([]string{by})[by]
+		accountAge, newUser := profile.accountAge(now)
+
+		line := handleItemDescendantsResponse{
+			storyMetadata:     story,
+			By:                by,
+			Text:              text,
+			Children:          nil,
+			AccountAgeDays:    accountAge,
+			Karma:             profile.karma(enrich),
+			Time:              entry.Item.Time,
+			ID:                entry.Item.ID,
+			Parent:            entry.Item.Parent,
+			Root:              storyID,
+			Depth:             entry.Depth,
+			TruncatedChildren: truncated,
+			OP:                isOP(entry.Item, root),
+			Dead:              entry.Item.Dead,
+			Deleted:           entry.Item.Deleted,
+			IsNew:             seen.isNew(itemID, entry.Item, entry.Depth),
+			NewUser:           newUser,
+		}
+
+		return encode(line)
+	}
+
+	rootTruncated := 0
+	if maxDepth == 0 {
+		rootTruncated = len(root.Kids)
+	}
+
+	if !write(treeEntry{Item: root, Depth: 0}, rootTruncated, false) || maxDepth == 0 {
+		return
+	}
+
+	options, err := getPollOptions(ctx, client, root)
+	if err != nil {
+		log.Printf("failed to stream poll options of item %d: %v", itemID, err)
+
+		_ = enc.Encode(errorResponse{
+			Details: nil,
+			Errors:  nil,
+			Code:    codeHNUpstreamError,
+			Message: "failed to retrieve poll options",
+		})
+
+		return
+	}
+
+	for _, line := range pollOptionResponses(options, formatter, textMode, showUser) {
+		if !encode(line) {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	opts := subtreeOptions{Blocked: blocked, MaxDepth: maxDepth, Sort: sort, ShowDead: showDead}
+
+	for result := range fetchSubtrees(ctx, client, kidsInOrder(root.Kids, sort)) {
+		if result.err != nil {
+			log.Printf("failed to stream item %d: %v", itemID, result.err)
+
+			_ = enc.Encode(errorResponse{
+				Details: nil,
+				Errors:  nil,
+				Code:    codeHNUpstreamError,
+				Message: "failed to retrieve item descendants",
+			})
+
+			return
+		}
+
+		if !writeSubtree(result.entries, opts, write) {
+			return
+		}
+	}
+}
+
+type subtreeOptions struct {
+	Blocked  map[string]bool
+	MaxDepth int
+	Sort     commentSort
+	ShowDead bool
+}
+
+// writeSubtree writes the entries of a subtree that are kept by visibleItems, in the requested
+// order and after truncating it at the maximum depth, stopping early if a write fails.
+func writeSubtree(
+	entries []treeEntry,
+	opts subtreeOptions,
+	write func(entry treeEntry, truncated int, placeholder bool) bool,
+) bool {
+	items := make([]*hn.Item, 0, len(entries))
+	depths := make([]int, 0, len(entries))
+
+	for _, entry := range entries {
+		items = append(items, entry.Item)
+		depths = append(depths, entry.Depth)
+	}
+
+	visible, placeholders := visibleItems(items, depths, opts.ShowDead, opts.Blocked)
+	entries = keepOnly(entries, visible)
+	depths = keepOnly(depths, visible)
+
+	order := commentOrder(keepOnly(items, visible), depths, opts.Sort)
+	entries = reorder(entries, order)
+	depths = reorder(depths, order)
+
+	keep, truncated := truncateDepth(depths, opts.MaxDepth)
+
+	for i, entry := range entries {
+		if keep[i] && !write(entry, truncated[i], placeholders[entry.Item.ID]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// kidsInOrder returns the top-level comments in the order they are streamed. Their times are not
+// known before they are fetched, but HN assigns IDs in increasing order so IDs stand in for them.
+func kidsInOrder(kids []int, sort commentSort) []int {
+	//nolint:exhaustive // longest-subthread is rejected for streaming
+	switch sort {
+	case commentSortTime:
+		return slices.Sorted(slices.Values(kids))
+	case commentSortTimeDesc:
+		sorted := slices.Sorted(slices.Values(kids))
+		slices.Reverse(sorted)
+
+		return sorted
+	default:
+		return kids
+	}
+}
+
+// fetchSubtrees fetches the subtree under each of ids, up to ndjsonPrefetch at a time, and sends
+// them in order with depths relative to their parent. The channel is closed after the last
+// subtree, after an error, or when ctx is done.
+func fetchSubtrees(ctx context.Context, client *itemClient, ids []int) <-chan subtreeResult {
+	out := make(chan subtreeResult)
+	pending := make(chan chan subtreeResult, ndjsonPrefetch)
+
+	go func() {
+		defer close(pending)
+
+		for _, id := range ids {
+			result := make(chan subtreeResult, 1)
+
+			select {
+			case pending <- result:
+			case <-ctx.Done():
+				return
+			}
+
+			go func() {
+				entries, err := fetchSubtree(ctx, client, id)
+				result <- subtreeResult{err: err, entries: entries}
+			}()
+		}
+	}()
+
+	go func() {
+		defer close(out)
+
+		for result := range pending {
+			var r subtreeResult
+
+			select {
+			case r = <-result:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case out <- r:
+			case <-ctx.Done():
+				return
+			}
+
+			if r.err != nil {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// fetchSubtree returns the item with the given ID and its descendants with depths relative to its
+// parent, or nothing if HN has no such item. Deleted and dead items are included like getTree
+// includes them, for visibleItems to turn into placeholders or remove.
+func fetchSubtree(ctx context.Context, client *itemClient, id int) ([]treeEntry, error) {
+	items, err := fetchItems(ctx, client, []int{id})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUpstream, err)
+	}
+
+	item := items[id]
+	if item == nil {
+		return nil, nil
+	}
+
+	all, err := fetchDescendants(ctx, client, items)
+	if err != nil {
+		return nil, err
+	}
+
+	allByParent, _, err := all.GroupByParent()
+	if err != nil {
+		return nil, err
+	}
+
+	flat := unl.FlattenTree(item, allByParent)
+	entries := make([]treeEntry, 0, len(flat))
+
+	for _, f := range flat {
+		entries = append(entries, treeEntry{Item: f.Item, Depth: f.Depth + 1})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const msgpackContentType = binding.MIMEMSGPACK2
+
+// respond writes obj as MessagePack when the client prefers it and as JSON otherwise.
+func respond(c *gin.Context, status int, obj any) {
+	if wantsMsgPack(c) {
+		respondMsgPack(c, status, obj)
+		return
+	}
+
+	c.PureJSON(status, obj)
+}
+
+// wantsMsgPack reports whether the Accept header prefers MessagePack to JSON.
+func wantsMsgPack(c *gin.Context) bool {
+	switch c.NegotiateFormat(gin.MIMEJSON, binding.MIMEMSGPACK2, binding.MIMEMSGPACK) {
+	case binding.MIMEMSGPACK2, binding.MIMEMSGPACK:
+		return true
+	default:
+		return false
+	}
+}
+
+// respondMsgPack encodes with the json tags so the MessagePack and JSON field names match. Gin's
+// own MessagePack renderer skips the fields of embedded unexported structs such as storyMetadata.
+func respondMsgPack(c *gin.Context, status int, obj any) {
+	var buf bytes.Buffer
+
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+
+	err := enc.Encode(obj)
+	if err != nil {
+		log.Printf("failed to encode MessagePack response: %v", err)
+		c.PureJSON(http.StatusInternalServerError, errorResponse{
+			Details: nil,
+			Errors:  nil,
+			Code:    codeInternalError,
+			Message: "failed to encode response",
+		})
+
+		return
+	}
+
+	c.Data(status, msgpackContentType, buf.Bytes())
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultNewestLimit = 30
+	maxNewestLimit     = 500
+)
+
+type handleNewestResponse struct {
+	Items []handleActiveResponseItem `json:"items"`
+}
+
+func handleNewest(c *gin.Context, client *itemClient, httpClient *http.Client, formatter *textFormatter) {
+	ctx := c.Request.Context()
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultNewestLimit)))
+	if err != nil || limit < 1 || limit > maxNewestLimit {
+		respondParamError(c, codeInvalidLimit, "limit", "invalid limit")
+		return
+	}
+
+	var errs paramErrors
+
+	opts := parseStoryListOptions(c, &errs)
+
+	if errs.respond(c) {
+		return
+	}
+
+	ids, err := fetchStoryIDs(ctx, httpClient, "newstories")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeHNUpstreamError, "failed to retrieve newest stories")
+		return
+	}
+
+	items, err := hydrateStories(ctx, client, formatter, ids[:min(limit, len(ids))], opts)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeHNUpstreamError, "failed to retrieve items")
+		return
+	}
+
+	respond(c, http.StatusOK, handleNewestResponse{Items: items})
+}
@@ -0,0 +1,885 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+)
+
+const openAPIVersion = "3.0.3"
+
+type apiParam struct {
+	Name        string
+	In          string
+	Type        string
+	Default     string
+	Description string
+}
+
+type apiOperation struct {
+	Response    any
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Params      []apiParam
+}
+
+func queryParam(name string, typ string, def string, description string) apiParam {
+	return apiParam{Name: name, In: "query", Type: typ, Default: def, Description: description}
+}
+
+func pathParam(name string, typ string, description string) apiParam {
+	return apiParam{Name: name, In: "path", Type: typ, Default: "", Description: description}
+}
+
+// apiOperations documents every route. Keep it in sync with the routes registered in main.
+func apiOperations() []apiOperation {
+	user := queryParam("show-user", "boolean", "true", "false to omit authors; user is accepted as an alias")
+	timeFormat := queryParam("time-format", "string", "pretty", "pretty, unix, iso, or all")
+	text := queryParam("text", "string", "html", "html, plain, or markdown; 0 omits the text")
+	maxDepth := queryParam("max-depth", "integer", "", "omit items deeper than this and count them in truncatedChildren")
+	shape := queryParam("shape", "string", "flat", "flat, or nested for a recursive children array")
+	id := pathParam("id", "integer", "HN item ID")
+	fields := queryParam("fields", "string", "", "comma-separated item fields to return, such as id,age,depth")
+	block := queryParam("block", "string", "",
+		"comma-separated authors whose comments are removed with their replies; defaults to the server's list")
+	commentSort := queryParam("comment-sort", "string", "hn-rank",
+		"order of sibling comments: hn-rank, time, time-desc, or longest-subthread")
+	seenMaxID := queryParam("seen-max-id", "integer", "0", "mark comments with higher IDs with isNew")
+	showDead := queryParam("show-dead", "boolean", "false",
+		"include dead and deleted comments; otherwise they appear only as placeholders for their replies")
+	enrich := queryParam("enrich", "string", "",
+		"karma to add the karma of each author whose profile has been fetched, cached like accountAgeDays")
+
+	window := queryParam("window", "string", defaultWindow,
+		"duration within which a comment counts as active; 1m to 24h unless configured otherwise. /active also "+
+			"takes up to 5 comma-separated durations, such as 15m,1h,6h, to use the longest and list the ones each "+
+			"item is active within in activeWindows")
+	maxAge := queryParam("max-age", "string", defaultMaxAge,
+		"maximum age of a story; at most 168h unless configured otherwise")
+	minBy := queryParam("min-by", "integer", strconv.Itoa(defaultMinBy),
+		"minimum distinct active commenters; 1 to 50 unless configured otherwise")
+
+	const adminDescription = "Requires Authorization: Bearer with the server's admin token; " +
+		"not available unless one is configured."
+
+	const watchDescription = " Send a JSON body like {\"kind\": \"keyword\", \"value\": \"rust\", " +
+		"\"scope\": \"comments\", \"webhookUrl\": \"https://example.com/hook\"}. The kind is keyword, author, " +
+		"or domain, and the scope is titles, the default, or comments; domain rules only match titles."
+
+	active := []apiParam{
+		window, maxAge, minBy,
+		queryParam("min-score", "integer", "0", "minimum story score"),
+		queryParam("limit", "integer", "0", "maximum stories, each with all of its items; 0 for all"),
+		queryParam("offset", "integer", "0", "stories to skip"),
+		queryParam("types", "string", "", "comma-separated story types to keep: story, ask, show, job, or poll"),
+		queryParam("q", "string", "", "keep stories whose title, URL, or domain contains this, ignoring case"),
+		queryParam("domains", "string", "", "comma-separated domains; keep stories linking to them or their subdomains"),
+		queryParam("exclude", "string", "", "comma-separated terms; drop stories whose title, URL, or domain contains any"),
+		queryParam("only", "string", "", "conversations to keep only back-and-forth exchanges and their ancestors, "+
+			"or followed to keep only comments by followed users and their ancestors"),
+		queryParam("follow", "string", "", "comma-separated users to follow along with the server's follow list"),
+		queryParam("at", "string", "", "RFC 3339 time in the past to recompute the active set from archived items"),
+		queryParam("activity", "string", activityWindow, "which comments are active: window, the ones within the "+
+			"window; velocity, those that followed their parent within a quarter of it; replies-to-me, replies to me; "+
+			"or op-engaged, those by the story's author or replying to their comments"),
+		queryParam("me", "string", "", "user whose replies activity=replies-to-me marks"),
+		user, maxDepth, text, timeFormat, showDead, block, commentSort, seenMaxID, enrich,
+	}
+
+	return []apiOperation{
+		{
+			Response: (*handleActiveResponse)(nil),
+			Method:   http.MethodGet,
+			Path:     "/active",
+			Summary:  "Stories with recent comment activity",
+			Description: "Flattened trees of stories with recent comments. Send Accept: application/feed+json " +
+				"for a JSON Feed. With group-by=domain the items are returned as groups with a domain and items. " +
+				"While the HN API is failing, or while a newer one is computed in the background, the last good " +
+				"response is returned with stale set to true. Stories take the configured item and size budget in " +
+				"order; a story that does not fit what is left leaves out its lowest-activity comments, counting " +
+				"them in their parents' truncatedChildren, and the response sets truncated with the total, " +
+				"returned, and omitted item counts. Each item has the IDs of its " +
+				"parent and of its story in parent and root. Comments heading a subtree with nothing within the " +
+				"window are marked collapsed as a hint to fold them by default. Active comments and their " +
+				"ancestors have an activityScore from 0 to 1, relative to the busiest comment of the thread, for " +
+				"shading them by recency, reply speed, and activity below them. Authors whose profiles have been " +
+				"fetched have accountAgeDays, and newUser if their account is under 14 days old; profiles are " +
+				"fetched in the background, so they appear in later responses. Comments by users named in follow " +
+				"or on the server's follow list are marked followed; changes to the list apply to the next " +
+				"response.",
+			Params: append([]apiParam{
+				queryParam("group-by", "string", "", "domain to return groups of items by story domain"),
+				shape, fields,
+			}, active...),
+		},
+		{
+			Response: (*handleActiveResponse)(nil),
+			Method:   http.MethodPost,
+			Path:     "/active",
+			Summary:  "Stories with recent comment activity, with per-thread watermarks",
+			Description: "Like GET /active, with a JSON body like {\"seenMaxIds\": {\"<story ID>\": <max ID>}} giving " +
+				"the highest comment ID already seen in each thread. Other threads use seen-max-id.",
+			Params: append([]apiParam{shape, fields}, active...),
+		},
+		{
+			Response: (*handleActiveChangesResponse)(nil),
+			Method:   http.MethodGet,
+			Path:     "/active/changes",
+			Summary:  "Changes to /active since a previous response",
+			Description: "Items that were added or changed their active state since the response that returned the " +
+				"since token, with their parent IDs, and the IDs of items no longer returned. Responds with " +
+				"410 if the token has expired.",
+			Params: append([]apiParam{
+				queryParam("since", "string", "", "token from a previous response; omit to get every item"),
+			}, active...),
+		},
+		{
+			Response:    (*handleActiveUsersResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/active/users",
+			Summary:     "Users ranked by comments within the window across the active stories",
+			Description: "Each user has links to their comments within the window, newest first.",
+			Params:      active,
+		},
+		{
+			Response:    (*jsonFeed)(nil),
+			Method:      http.MethodGet,
+			Path:        "/active.json-feed",
+			Summary:     "Stories with recent comment activity as a JSON Feed",
+			Description: "",
+			Params:      []apiParam{user},
+		},
+		{
+			Response: []handleItemDescendantsResponse{},
+			Method:   http.MethodGet,
+			Path:     "/item/{id}/tree",
+			Summary:  "An item and all of its descendants",
+			Description: "When limit or cursor is set the response is a page object with nextCursor instead of an array. " +
+				"With format=ndjson each line is one item, or an error object if the stream fails. " +
+				"Each item has the ID of its parent and of the story at the root of its thread, even when the tree " +
+				"starts below it. Authors have accountAgeDays and newUser as in /active. The options of a poll " +
+				"follow it at depth 1 with type pollopt. With partial=true the response is an object with items, " +
+				"partial, and resume, and running out of time returns the top-level subtrees fetched so far " +
+				"instead of a 504.",
+			Params: []apiParam{
+				id, user, maxDepth, shape, text, fields, showDead, block, commentSort, seenMaxID, enrich,
+				queryParam("limit", "integer", "", "maximum items per page"),
+				queryParam("cursor", "string", "", "nextCursor from the previous page"),
+				queryParam("format", "string", "json", "json, or ndjson to stream one item per line"),
+				queryParam("partial", "boolean", "false", "return what was fetched before the timeout"),
+				queryParam("resume", "integer", "", "resume from a previous partial response"),
+			},
+		},
+		{
+			Response:    []handleItemDescendantsResponse{},
+			Method:      http.MethodGet,
+			Path:        "/item/{id}/ancestors",
+			Summary:     "The chain from the root story down to an item",
+			Description: "",
+			Params:      []apiParam{id, user, text},
+		},
+		{
+			Response: []handleItemDescendantsResponse{},
+			Method:   http.MethodGet,
+			Path:     "/item/{id}/children",
+			Summary:  "The replies to an item down to a small depth, for expanding a collapsed subtree",
+			Description: "Formatted like /item/{id}/tree without the item itself, with depths relative to it. " +
+				"Replies at the deepest level count their replies in truncatedChildren. op is not set.",
+			Params: []apiParam{
+				id, user, text, showDead, block,
+				queryParam("depth", "integer", "1", "levels of replies to return, from 1 to 3"),
+			},
+		},
+		{
+			Response: (*itemResponse)(nil),
+			Method:   http.MethodGet,
+			Path:     "/item/{id}",
+			Summary:  "A single item without its descendants",
+			Description: "Cached by clients and CDNs for longer than trees, with an ETag for revalidation; a " +
+				"matching If-None-Match responds with 304.",
+			Params: []apiParam{id, user, text},
+		},
+		{
+			Response: (*handleItemsResponse)(nil),
+			Method:   http.MethodPost,
+			Path:     "/items",
+			Summary:  "Several items fetched in one batch, without their descendants",
+			Description: "Send a JSON body like {\"ids\": [1, 2, 3]} with up to the server's maximum batch size " +
+				"(100 by default). Items are returned in the order requested with duplicates removed; IDs of items " +
+				"that do not exist or were deleted are listed in missing.",
+			Params: []apiParam{user, text},
+		},
+		{
+			Response: (*handleItemStatsResponse)(nil),
+			Method:   http.MethodGet,
+			Path:     "/item/{id}/stats",
+			Summary:  "Score and comment counts of a story over time",
+			Description: "Sampled each time the default /active set is precomputed while the story is in it, " +
+				"oldest first. Responds with 501 if the server has no store.",
+			Params: []apiParam{id},
+		},
+		{
+			Response: (*handleRankHistoryResponse)(nil),
+			Method:   http.MethodGet,
+			Path:     "/item/{id}/rank-history",
+			Summary:  "Front-page ranks of a story over time",
+			Description: "Ranks from each front-page sample that included the story, oldest first, and the runs of " +
+				"consecutive samples in which it appeared. A run is marked secondChance if the story was shown with " +
+				"an adjusted time. Responds with 501 if the server has no store.",
+			Params: []apiParam{id},
+		},
+		{
+			Response: (*handleLiveMessage)(nil),
+			Method:   http.MethodGet,
+			Path:     "/item/{id}/live",
+			Summary:  "WebSocket pushing new descendants of an item",
+			Description: "Each message contains the descendants that appeared since the previous message. " +
+				"Responds with 404 instead of upgrading if the item does not exist. Browsers may only connect " +
+				"from the server's origin or one allowed by --cors-origins.",
+			Params: []apiParam{id},
+		},
+		{
+			Response: (*follow)(nil),
+			Method:   http.MethodPost,
+			Path:     "/item/{id}/follow",
+			Summary:  "Follow a thread for new descendants or replies by one user",
+			Description: adminDescription + " Send an optional JSON body like {\"user\": \"pg\", " +
+				"\"webhookUrl\": \"https://example.com/hook\"}; without user every new descendant is sent. New " +
+				"items are streamed from eventsUrl and, if set, posted to webhookUrl signed like /subscriptions " +
+				"webhooks. Follows expire after the server's follow TTL.",
+			Params: []apiParam{id},
+		},
+		{
+			Response:    (*follow)(nil),
+			Method:      http.MethodGet,
+			Path:        "/follows/{id}",
+			Summary:     "A follow created with /item/{id}/follow",
+			Description: "",
+			Params:      []apiParam{pathParam("id", "string", "follow ID")},
+		},
+		{
+			Response:    "",
+			Method:      http.MethodDelete,
+			Path:        "/follows/{id}",
+			Summary:     "Stop a follow and end its event streams",
+			Description: "",
+			Params:      []apiParam{pathParam("id", "string", "follow ID")},
+		},
+		{
+			Response:    (*followEvent)(nil),
+			Method:      http.MethodGet,
+			Path:        "/follows/{id}/events",
+			Summary:     "Server-sent events with the new items of a follow",
+			Description: "Send Accept: text/event-stream so the stream is not cut off by the request timeout.",
+			Params:      []apiParam{pathParam("id", "string", "follow ID")},
+		},
+		{
+			Response: (*streamItem)(nil),
+			Method:   http.MethodGet,
+			Path:     "/items/stream",
+			Summary:  "Server-sent events with each new item as the server ingests it",
+			Description: "Only available when the server polls maxitem. Send Accept: text/event-stream so the " +
+				"stream is not cut off by the request timeout.",
+			Params: []apiParam{
+				queryParam("types", "string", "", "comma-separated item types: story, comment, job, poll, or pollopt"),
+			},
+		},
+		{
+			Response:    (*handleFrontPageResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/front",
+			Summary:     "The current front page",
+			Description: "",
+			Params:      nil,
+		},
+		{
+			Response:    (*handleFrontPageResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/second-chance",
+			Summary:     "Front-page stories from the second-chance pool",
+			Description: "",
+			Params:      nil,
+		},
+		{
+			Response:    (*handleNewestResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/newest",
+			Summary:     "Recently submitted stories",
+			Description: "",
+			Params: []apiParam{
+				queryParam("limit", "integer", strconv.Itoa(defaultNewestLimit), "maximum stories"),
+				user, text, timeFormat,
+			},
+		},
+		{
+			Response: (*handleQuietResponse)(nil),
+			Method:   http.MethodGet,
+			Path:     "/quiet",
+			Summary:  "Recent stories with points but little discussion",
+			Description: "Stories from the new and top lists within max-age that have at least min-score points " +
+				"and are not in the /active set for the same window, max-age, and min-by, newest first.",
+			Params: []apiParam{
+				window, maxAge, minBy,
+				queryParam("min-score", "integer", strconv.Itoa(defaultQuietMinScore), "minimum story score"),
+				queryParam("limit", "integer", strconv.Itoa(defaultListLimit), "maximum stories"),
+				user, text, timeFormat,
+			},
+		},
+		{
+			Response: (*handleDupesResponse)(nil),
+			Method:   http.MethodGet,
+			Path:     "/dupes",
+			Summary:  "Other submissions of the same URL",
+			Description: "URLs match after removing the scheme, www., the fragment, a trailing slash, and tracking " +
+				"parameters. Searches the recent stories and HN Search; searchFailed is set if HN Search fails.",
+			Params: []apiParam{queryParam("url", "string", "", "URL of the story")},
+		},
+		{
+			Response:    (*handleSearchResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/search",
+			Summary:     "Stories and comments from HN Search",
+			Description: "Hits are returned in the same shape as the items of /user/{name}.",
+			Params: []apiParam{
+				queryParam("q", "string", "", "search query"),
+				queryParam("type", "string", "all", "all, story, comment, ask, show, poll, or job"),
+				queryParam("sort", "string", "relevance", "relevance, or date for newest first"),
+				queryParam("page", "integer", "0", "zero-based page"),
+				queryParam("limit", "integer", strconv.Itoa(defaultSearchLimit), "maximum items per page"),
+				user, text,
+			},
+		},
+		{
+			Response: (*handleCacheSearchResponse)(nil),
+			Method:   http.MethodGet,
+			Path:     "/cache/search",
+			Summary:  "Full-text search over the items seen in active threads",
+			Description: "Matches items containing every word of q in their title or text, best match first. " +
+				"Responds with 501 if the server has no store.",
+			Params: []apiParam{
+				queryParam("q", "string", "", "words to search for"),
+				queryParam("limit", "integer", strconv.Itoa(defaultSearchLimit), "maximum items"),
+				user, text,
+			},
+		},
+		{
+			Response: (*handleHistoryActiveResponse)(nil),
+			Method:   http.MethodGet,
+			Path:     "/history/active",
+			Summary:  "Archived snapshots of the default /active set",
+			Description: "Snapshots precomputed between from and to, oldest first, with the roots of each ordered " +
+				"by active comments. Responds with 501 if the server has no store.",
+			Params: []apiParam{
+				queryParam("from", "string", "", "RFC 3339 start time; defaults to a day before to, at most a week"),
+				queryParam("to", "string", "", "RFC 3339 end time; defaults to now"),
+			},
+		},
+		{
+			Response: "",
+			Method:   http.MethodGet,
+			Path:     "/export/active",
+			Summary:  "Archived snapshots of the default /active set as CSV or Parquet",
+			Description: "One row per root per snapshot taken between from and to with columns " +
+				strings.Join(exportColumns(), ", ") + ". Responds with 501 if the server has no store.",
+			Params: []apiParam{
+				queryParam("from", "string", "", "RFC 3339 start time; defaults to a day before to, at most a week"),
+				queryParam("to", "string", "", "RFC 3339 end time; defaults to now"),
+				queryParam("format", "string", "csv", "csv, or parquet with the same columns"),
+			},
+		},
+		{
+			Response:    (*handleCacheStatsResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/admin/cache/stats",
+			Summary:     "Sizes of the item cache and sizes and hit rates of the response and text caches",
+			Description: adminDescription,
+			Params:      []apiParam{},
+		},
+		{
+			Response:    (*handleCachePurgeResponse)(nil),
+			Method:      http.MethodPost,
+			Path:        "/admin/cache/purge",
+			Summary:     "Empty the item cache, the response cache, or both",
+			Description: adminDescription,
+			Params:      []apiParam{queryParam("cache", "string", "all", "all, items, or responses")},
+		},
+		{
+			Response:    (*handleCacheCompactResponse)(nil),
+			Method:      http.MethodPost,
+			Path:        "/admin/cache/compact",
+			Summary:     "Reclaim the disk space of the item cache with VACUUM",
+			Description: adminDescription,
+			Params:      []apiParam{},
+		},
+		{
+			Response:    (*handleAdminConfigResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/admin/config",
+			Summary:     "Current value of every setting by flag name, with secrets redacted",
+			Description: adminDescription,
+			Params:      []apiParam{},
+		},
+		{
+			Response: (*handleAdminConfigResponse)(nil),
+			Method:   http.MethodPatch,
+			Path:     "/admin/config",
+			Summary:  "Change settings without a restart",
+			Description: adminDescription + " Send a JSON body of settings by flag name, like {\"window\": \"2h\"}. " +
+				"Only precompute-interval, window, max-age, min-by, log-level, hn-rate, and hn-burst can be changed; " +
+				"changes last until the configuration is reloaded with SIGHUP or the server restarts.",
+			Params: []apiParam{},
+		},
+		{
+			Response: (*subscription)(nil),
+			Method:   http.MethodPost,
+			Path:     "/subscriptions",
+			Summary:  "Register a webhook for threads entering the default /active set",
+			Description: adminDescription + " Send a JSON body like {\"url\": \"https://example.com/hook\", " +
+				"\"keywords\": [\"rust\"], \"domains\": [\"github.com\"], \"minActiveComments\": 5}; the filters " +
+				"are optional. Matching threads are posted as JSON with an X-Unlurker-Signature header holding " +
+				"sha256= and the hex HMAC-SHA256 of the body keyed by the secret in the response.",
+			Params: []apiParam{},
+		},
+		{
+			Response:    (*handleListSubscriptionsResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/subscriptions",
+			Summary:     "Webhook subscriptions and how their last delivery went",
+			Description: adminDescription,
+			Params:      []apiParam{},
+		},
+		{
+			Response:    "",
+			Method:      http.MethodDelete,
+			Path:        "/subscriptions/{id}",
+			Summary:     "Remove a webhook subscription",
+			Description: adminDescription,
+			Params:      []apiParam{pathParam("id", "string", "subscription ID")},
+		},
+		{
+			Response: (*watchRule)(nil),
+			Method:   http.MethodPost,
+			Path:     "/watches",
+			Summary:  "Add a rule matching stories or comments of the default /active set",
+			Description: adminDescription + watchDescription + " Hits are posted to the optional webhookUrl as " +
+				"JSON with an X-Unlurker-Signature header holding sha256= and the hex HMAC-SHA256 of the body " +
+				"keyed by the secret in the response.",
+			Params: []apiParam{},
+		},
+		{
+			Response:    (*handleListWatchesResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/watches",
+			Summary:     "Watch rules",
+			Description: adminDescription,
+			Params:      []apiParam{},
+		},
+		{
+			Response:    (*watchRule)(nil),
+			Method:      http.MethodGet,
+			Path:        "/watches/{id}",
+			Summary:     "One watch rule",
+			Description: adminDescription,
+			Params:      []apiParam{pathParam("id", "string", "watch ID")},
+		},
+		{
+			Response:    (*watchRule)(nil),
+			Method:      http.MethodPut,
+			Path:        "/watches/{id}",
+			Summary:     "Replace a watch rule, dropping its hits",
+			Description: adminDescription + watchDescription,
+			Params:      []apiParam{pathParam("id", "string", "watch ID")},
+		},
+		{
+			Response:    "",
+			Method:      http.MethodDelete,
+			Path:        "/watches/{id}",
+			Summary:     "Remove a watch rule and its hits",
+			Description: adminDescription,
+			Params:      []apiParam{pathParam("id", "string", "watch ID")},
+		},
+		{
+			Response:    (*handleWatchHitsResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/watches/{id}/hits",
+			Summary:     "Items that matched a watch rule, most recently matched first",
+			Description: adminDescription,
+			Params: []apiParam{
+				pathParam("id", "string", "watch ID"),
+				queryParam("limit", "integer", strconv.Itoa(defaultWatchHitsLimit), "maximum hits"),
+			},
+		},
+		{
+			Response:    (*handleListFollowedUsersResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/followed-users",
+			Summary:     "The server's follow list, whose comments /active marks followed for every client",
+			Description: adminDescription,
+			Params:      []apiParam{},
+		},
+		{
+			Response:    (*followedUser)(nil),
+			Method:      http.MethodGet,
+			Path:        "/followed-users/{name}",
+			Summary:     "One user of the server's follow list",
+			Description: adminDescription,
+			Params:      []apiParam{pathParam("name", "string", "HN user name")},
+		},
+		{
+			Response: (*followedUser)(nil),
+			Method:   http.MethodPut,
+			Path:     "/followed-users/{name}",
+			Summary:  "Add a user to the server's follow list",
+			Description: adminDescription + " Responds with 201 if the user was added and 200 if they were " +
+				"already followed. The list holds up to 1000 users.",
+			Params: []apiParam{pathParam("name", "string", "HN user name")},
+		},
+		{
+			Response:    "",
+			Method:      http.MethodDelete,
+			Path:        "/followed-users/{name}",
+			Summary:     "Remove a user from the server's follow list",
+			Description: adminDescription,
+			Params:      []apiParam{pathParam("name", "string", "HN user name")},
+		},
+		{
+			Response: (*digestRecipient)(nil),
+			Method:   http.MethodPost,
+			Path:     "/digests",
+			Summary:  "Email someone the busiest threads of each digest interval",
+			Description: adminDescription + " Send a JSON body like {\"email\": \"me@example.com\", " +
+				"\"keywords\": [\"rust\"], \"minActiveComments\": 5, \"limit\": 10}; the filters and limit " +
+				"are optional. Digests are only sent when the server has an SMTP server configured.",
+			Params: []apiParam{},
+		},
+		{
+			Response:    (*handleListDigestRecipientsResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/digests",
+			Summary:     "Digest recipients and how their last digest went",
+			Description: adminDescription,
+			Params:      []apiParam{},
+		},
+		{
+			Response:    "",
+			Method:      http.MethodDelete,
+			Path:        "/digests/{id}",
+			Summary:     "Stop sending digests to a recipient",
+			Description: adminDescription,
+			Params:      []apiParam{pathParam("id", "string", "recipient ID")},
+		},
+		{
+			Response:    "",
+			Method:      http.MethodGet,
+			Path:        "/digests/{id}/preview",
+			Summary:     "HTML of the digest the recipient would be sent now",
+			Description: adminDescription,
+			Params:      []apiParam{pathParam("id", "string", "recipient ID")},
+		},
+		{
+			Response:    (*handleListResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/lists/{name}",
+			Summary:     "One of the HN story lists: top, best, new, ask, show, or job",
+			Description: "",
+			Params: []apiParam{
+				pathParam("name", "string", "top, best, new, ask, show, or job"),
+				queryParam("limit", "integer", strconv.Itoa(defaultListLimit), "maximum stories"),
+				queryParam("offset", "integer", "0", "stories to skip"),
+				queryParam("hydrate", "integer", "0", "1 to return items instead of IDs"),
+				user, text, timeFormat,
+			},
+		},
+		{
+			Response: (*graphql.Result)(nil),
+			Method:   http.MethodPost,
+			Path:     "/graphql",
+			Summary:  "GraphQL queries over items, trees, active stories, and users",
+			Description: "Send query, variables, and operationName as a JSON body, or as query parameters with GET. " +
+				"The trees in a result share the configured item and size budget of /active, in the order they are " +
+				"resolved; the deepest comments are left out of a tree that does not fit what is left, and " +
+				"extensions.truncation has the total, returned, and omitted item counts.",
+			Params: nil,
+		},
+		{
+			Response:    (*handleUserResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/user/{name}",
+			Summary:     "A user's profile and recent submissions",
+			Description: "",
+			Params: []apiParam{
+				pathParam("name", "string", "HN username"),
+				queryParam("limit", "integer", strconv.Itoa(defaultUserLimit), "maximum submissions"),
+			},
+		},
+		{
+			Response:    (*handleRuntimeResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/debug/runtime",
+			Summary:     "Goroutine count, heap usage, and recent GC pauses",
+			Description: adminDescription,
+			Params:      []apiParam{},
+		},
+		{
+			Response: "",
+			Method:   http.MethodGet,
+			Path:     "/debug/pprof/{profile}",
+			Summary:  "pprof profiles, such as heap, goroutine, or profile for CPU",
+			Description: adminDescription + " Use go tool pprof with the same header. CPU profiles and traces are " +
+				"cut off by the server's maximum request duration.",
+			Params: []apiParam{pathParam("profile", "string", "name of the profile")},
+		},
+		{
+			Response:    (*handleVersionResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/version",
+			Summary:     "Version, commit, and build date of the server",
+			Description: "Every response also carries the version in an X-Unlurker-Version header.",
+			Params:      nil,
+		},
+		{
+			Response:    (*handleHealthzResponse)(nil),
+			Method:      http.MethodGet,
+			Path:        "/healthz",
+			Summary:     "Liveness probe",
+			Description: "Responds while the process can serve requests.",
+			Params:      nil,
+		},
+		{
+			Response: (*handleReadyzResponse)(nil),
+			Method:   http.MethodGet,
+			Path:     "/readyz",
+			Summary:  "Readiness probe",
+			Description: "Checks that the item cache and store are writable, that the HN API answered recently, and " +
+				"that the default /active snapshot is being refreshed. Responds with 503 if any check failed.",
+			Params: nil,
+		},
+	}
+}
+
+// buildOpenAPI generates the OpenAPI document, deriving response schemas from the Go types.
+func buildOpenAPI() map[string]any {
+	schemas := openAPISchemas{components: map[string]any{}}
+	paths := map[string]any{}
+
+	errorSchema := schemas.schemaFor(reflect.TypeFor[errorResponse]())
+	timeout := queryParam("timeout", "string", "",
+		"duration after which the request fails with a 504, capped by the server's maximum")
+
+	for _, op := range apiOperations() {
+		params := make([]any, 0, len(op.Params)+1)
+
+		for _, p := range slices.Concat(op.Params, []apiParam{timeout}) {
+			schema := map[string]any{"type": p.Type}
+			if p.Default != "" {
+				schema["default"] = p.Default
+			}
+
+			params = append(params, map[string]any{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.In == "path",
+				"description": p.Description,
+				"schema":      schema,
+			})
+		}
+
+		operation := map[string]any{
+			"summary":    op.Summary,
+			"parameters": params,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     openAPIContent(schemas.schemaFor(reflect.TypeOf(op.Response))),
+				},
+				"default": map[string]any{
+					"description": "Error",
+					"content":     openAPIContent(errorSchema),
+				},
+			},
+		}
+
+		if op.Description != "" {
+			operation["description"] = op.Description
+		}
+
+		pathItem, _ := paths[op.Path].(map[string]any)
+		if pathItem == nil {
+			pathItem = map[string]any{}
+			paths[op.Path] = pathItem
+		}
+
+		pathItem[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title": "Unlurker API",
+			"description": "Find active discussions on Hacker News. Query parameters are documented by their " +
+				"canonical kebab-case names; snake_case spellings such as max_age are accepted too.",
+			"version": "1",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas.components},
+	}
+}
+
+// openAPIContent lists the media types every response can be negotiated to.
+func openAPIContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		gin.MIMEJSON:       map[string]any{"schema": schema},
+		msgpackContentType: map[string]any{"schema": schema},
+	}
+}
+
+type openAPISchemas struct {
+	components map[string]any
+}
+
+func (s *openAPISchemas) schemaFor(t reflect.Type) map[string]any {
+	//nolint:exhaustive // remaining kinds do not appear in responses
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schemaFor(t.Elem())
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schemaFor(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Struct:
+		return s.structRef(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+func (s *openAPISchemas) structRef(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	name := schemaName(t)
+
+	_, ok := s.components[name]
+	if !ok {
+		// reserve the name first so recursive types refer to themselves
+		s.components[name] = map[string]any{}
+		s.components[name] = s.structSchema(t)
+	}
+
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (s *openAPISchemas) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+
+	s.addFields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+func (s *openAPISchemas) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+
+			// fields of embedded pointers are only present when the pointer is set
+			var ignored []string
+
+			if field.Type.Kind() == reflect.Pointer {
+				s.addFields(embedded, properties, &ignored)
+			} else {
+				s.addFields(embedded, properties, required)
+			}
+
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = s.schemaFor(field.Type)
+
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// schemaName turns a Go type name like handleActiveResponse into ActiveResponse.
+func schemaName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "handle")
+
+	r := []rune(name)
+	if len(r) > 0 {
+		r[0] = unicode.ToUpper(r[0])
+	}
+
+	return string(r)
+}
+
+func handleOpenAPI(doc map[string]any) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		respond(c, http.StatusOK, doc)
+	}
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Unlurker API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });</script>
+</body>
+</html>
+`
+
+func handleDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errNonPositiveDuration is returned for a duration parameter that is zero or negative, which
+// time.ParseDuration accepts but no parameter means anything with.
+var errNonPositiveDuration = errors.New("duration must be positive")
+
+// paramAliases maps the older names of query parameters to their canonical names.
+//
+//nolint:gochecknoglobals // constant table
+var paramAliases = map[string]string{
+	"user": "show-user",
+}
+
+// canonicalParams rewrites the query of each request to the canonical kebab-case parameter names,
+// so handlers, the response cache, and request logs see max_age as max-age and user as show-user. A
+// parameter given under its canonical name wins over its aliases. It must run before anything
+// reads the query, since gin caches the parsed query on first use.
+func canonicalParams() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		changed := false
+
+		for name, values := range query {
+			canonical := canonicalParam(name)
+			if canonical == name {
+				continue
+			}
+
+			changed = true
+
+			delete(query, name)
+
+			if _, ok := query[canonical]; !ok {
+				query[canonical] = values
+			}
+		}
+
+		if changed {
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		c.Next()
+	}
+}
+
+// canonicalParam returns the canonical name of the query parameter name: the name it is an alias
+// of, or name in kebab-case.
+func canonicalParam(name string) string {
+	if canonical, ok := paramAliases[name]; ok {
+		return canonical
+	}
+
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// paramError is one invalid parameter of a request, with the range it must be in if it is outside
+// one.
+type paramError struct {
+	Param  string    `json:"param"`
+	Code   errorCode `json:"code"`
+	Reason string    `json:"reason"`
+	Min    string    `json:"min,omitempty"`
+	Max    string    `json:"max,omitempty"`
+}
+
+// paramErrors collects the invalid parameters of a request so that every one of them is reported
+// in a single response instead of only the first.
+type paramErrors []paramError
+
+func (e *paramErrors) add(code errorCode, param string, reason string) {
+	*e = append(*e, paramError{Param: param, Code: code, Reason: reason, Min: "", Max: ""})
+}
+
+// addRange adds err if it is a *paramRangeError, so a nil err adds nothing.
+func (e *paramErrors) addRange(err error) {
+	var rangeErr *paramRangeError
+	if errors.As(err, &rangeErr) {
+		*e = append(*e, paramError{
+			Param:  rangeErr.Param,
+			Code:   rangeErr.Code,
+			Reason: rangeErr.Error(),
+			Min:    rangeErr.Min,
+			Max:    rangeErr.Max,
+		})
+	}
+}
+
+// respond aborts the request with a 400 listing every collected error in errors and reports
+// whether there were any. The code, message, and details are those of the first error, as they
+// were when only the first was reported.
+func (e paramErrors) respond(c *gin.Context) bool {
+	if len(e) == 0 {
+		return false
+	}
+
+	first := e[0]
+	details := map[string]string{"param": first.Param}
+
+	if first.Min != "" || first.Max != "" {
+		details["min"] = first.Min
+		details["max"] = first.Max
+	}
+
+	respond(c, http.StatusBadRequest, errorResponse{
+		Details: details,
+		Errors:  e,
+		Code:    first.Code,
+		Message: first.Reason,
+	})
+	c.Abort()
+
+	return true
+}
+
+// parsePositiveDuration parses s as a duration rounded down to a multiple of unit, so equivalent
+// values like 1h and 60m0.5s are the same, and returns errNonPositiveDuration if the result is not
+// positive.
+func parsePositiveDuration(s string, unit time.Duration) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse duration: %w", err)
+	}
+
+	d = d.Truncate(unit)
+	if d <= 0 {
+		return 0, fmt.Errorf("%w: %q", errNonPositiveDuration, s)
+	}
+
+	return d, nil
+}
+
+// parseDurationParam reads the duration query parameter param, which defaults to def, to a whole
+// number of seconds. If it is invalid it adds an error to errs, with code and message unless the
+// problem is that it is not positive, and returns false.
+func parseDurationParam(
+	c *gin.Context,
+	errs *paramErrors,
+	param string,
+	def time.Duration,
+	code errorCode,
+	message string,
+) (time.Duration, bool) {
+	return parseDurationValue(errs, param, c.DefaultQuery(param, def.String()), code, message)
+}
+
+// parseDurationValue is parseDurationParam for a value s of param that is already read, such as one
+// entry of a list.
+func parseDurationValue(
+	errs *paramErrors,
+	param string,
+	s string,
+	code errorCode,
+	message string,
+) (time.Duration, bool) {
+	d, err := parsePositiveDuration(s, time.Second)
+	if errors.Is(err, errNonPositiveDuration) {
+		errs.add(codeDurationNotPositive, param, param+" must be positive")
+		return 0, false
+	}
+
+	if err != nil {
+		errs.add(code, param, message)
+		return 0, false
+	}
+
+	return d, true
+}
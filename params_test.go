@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCanonicalParams(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "empty", query: "", want: ""},
+		{name: "canonical", query: "max-age=1h&window=5m", want: "max-age=1h&window=5m"},
+		{name: "unchanged order", query: "window=5m&max-age=1h", want: "window=5m&max-age=1h"},
+		{name: "snake case", query: "max_age=1h", want: "max-age=1h"},
+		{name: "several underscores", query: "min_by_x=2", want: "min-by-x=2"},
+		{name: "alias", query: "user=1", want: "show-user=1"},
+		{name: "canonical wins over snake case", query: "max_age=2h&max-age=1h", want: "max-age=1h"},
+		{name: "canonical wins over alias", query: "user=0&show-user=1", want: "show-user=1"},
+		{name: "repeated values", query: "block_user=a&block_user=b", want: "block-user=a&block-user=b"},
+		{name: "mixed", query: "max_age=1h&window=5m", want: "max-age=1h&window=5m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+
+			r := gin.New()
+			r.Use(canonicalParams())
+			r.GET("/", func(c *gin.Context) { got = c.Request.URL.RawQuery })
+
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/?"+tt.query, nil)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("query %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePositiveDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err  error
+		name string
+		s    string
+		want time.Duration
+		unit time.Duration
+		ok   bool
+	}{
+		{err: nil, name: "hours", s: "1h", want: time.Hour, unit: time.Second, ok: true},
+		{err: nil, name: "equivalent", s: "60m0.5s", want: time.Hour, unit: time.Second, ok: true},
+		{err: nil, name: "rounded down", s: "90s", want: time.Minute, unit: time.Minute, ok: true},
+		{err: nil, name: "exactly one unit", s: "1s", want: time.Second, unit: time.Second, ok: true},
+		{err: errNonPositiveDuration, name: "zero", s: "0s", want: 0, unit: time.Second, ok: false},
+		{err: errNonPositiveDuration, name: "negative", s: "-5m", want: 0, unit: time.Second, ok: false},
+		{err: errNonPositiveDuration, name: "below unit", s: "500ms", want: 0, unit: time.Second, ok: false},
+		{err: nil, name: "no unit", s: "5", want: 0, unit: time.Second, ok: false},
+		{err: nil, name: "empty", s: "", want: 0, unit: time.Second, ok: false},
+		{err: nil, name: "garbage", s: "soon", want: 0, unit: time.Second, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parsePositiveDuration(tt.s, tt.unit)
+			if (err == nil) != tt.ok {
+				t.Fatalf("error %v, want ok %v", err, tt.ok)
+			}
+
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("error %v, want %v", err, tt.err)
+			}
+
+			if tt.err == nil && errors.Is(err, errNonPositiveDuration) {
+				t.Errorf("error %v, want a parse error", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("duration %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	parquetMagic = "PAR1"
+
+	// parquetRowGroupRows is how many rows are buffered before they are written as a row group.
+	parquetRowGroupRows = 10_000
+
+	parquetVersion       = 1
+	parquetTypeInt64     = 2
+	parquetTypeByteArray = 6
+	parquetRequired      = 0
+	parquetConvertedUTF8 = 0
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+	parquetCodecNone     = 0
+	parquetPageData      = 0
+
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+
+	// thriftShortList is the smallest list size that does not fit in the list header byte, which
+	// is then thriftLongList with the element type and followed by the size.
+	thriftShortList = 15
+	thriftLongList  = 0xf0
+	// thriftMaxDelta is the largest field ID delta that fits in the field header byte.
+	thriftMaxDelta = 15
+)
+
+var (
+	errParquetValue = errors.New("parquet values must be int64 or string")
+	errParquetSize  = errors.New("parquet page too large")
+)
+
+// parquetColumn is a required column of a Parquet file: INT64, or BYTE_ARRAY holding UTF-8.
+type parquetColumn struct {
+	Name string
+	Type int32
+}
+
+type parquetChunk struct {
+	Offset int64
+	Size   int64
+}
+
+type parquetRowGroup struct {
+	Chunks []parquetChunk
+	Rows   int64
+	Size   int64
+}
+
+// parquetWriter writes rows as an uncompressed, PLAIN-encoded Parquet file with one data page per
+// column per row group. Rows are buffered only until a row group is full, so a large file streams
+// with bounded memory; the footer describing the row groups is written by Close.
+type parquetWriter struct {
+	w       io.Writer
+	columns []parquetColumn
+	pages   []bytes.Buffer
+	groups  []parquetRowGroup
+	offset  int64
+	rows    int64
+	total   int64
+}
+
+// newParquetWriter writes the header of a Parquet file with columns to w.
+func newParquetWriter(w io.Writer, columns []parquetColumn) (*parquetWriter, error) {
+	p := &parquetWriter{
+		w:       w,
+		columns: columns,
+		pages:   make([]bytes.Buffer, len(columns)),
+		groups:  nil,
+		offset:  0,
+		rows:    0,
+		total:   0,
+	}
+
+	err := p.write([]byte(parquetMagic))
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Write adds a row with one int64 or string value per column.
+func (p *parquetWriter) Write(values []any) error {
+	for i, v := range values {
+		switch v := v.(type) {
+		case int64:
+			p.pages[i].Write(binary.LittleEndian.AppendUint64(nil, uint64(v))) //nolint:gosec // two's complement
+		case string:
+			p.pages[i].Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v)))) //nolint:gosec // short titles
+			p.pages[i].WriteString(v)
+		default:
+			return fmt.Errorf("%w: %T", errParquetValue, v)
+		}
+	}
+
+	p.rows++
+
+	if p.rows < parquetRowGroupRows {
+		return nil
+	}
+
+	return p.flush()
+}
+
+// Close writes the buffered rows and the footer. It does not close the underlying writer.
+func (p *parquetWriter) Close() error {
+	err := p.flush()
+	if err != nil {
+		return err
+	}
+
+	footer := p.footer()
+
+	err = p.write(footer)
+	if err != nil {
+		return err
+	}
+
+	size := binary.LittleEndian.AppendUint32(nil, uint32(len(footer))) //nolint:gosec // footers are small
+
+	return p.write(append(size, parquetMagic...))
+}
+
+// flush writes the buffered rows as a row group.
+func (p *parquetWriter) flush() error {
+	if p.rows == 0 {
+		return nil
+	}
+
+	group := parquetRowGroup{Chunks: make([]parquetChunk, 0, len(p.columns)), Rows: p.rows, Size: 0}
+
+	for i := range p.pages {
+		page := p.pages[i].Bytes()
+		if len(page) > math.MaxInt32 {
+			return errParquetSize
+		}
+
+		header := p.pageHeader(int32(len(page)))
+		chunk := parquetChunk{Offset: p.offset, Size: int64(len(header) + len(page))}
+
+		err := p.write(append(header, page...))
+		if err != nil {
+			return err
+		}
+
+		p.pages[i].Reset()
+
+		group.Chunks = append(group.Chunks, chunk)
+		group.Size += chunk.Size
+	}
+
+	p.groups = append(p.groups, group)
+	p.total += p.rows
+	p.rows = 0
+
+	return nil
+}
+
+func (p *parquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("failed to write parquet: %w", err)
+	}
+
+	return nil
+}
+
+// pageHeader encodes the PageHeader of a data page of size bytes holding the buffered rows.
+// Columns are required and not nested, so the page has no repetition or definition levels.
+//
+//nolint:mnd // field IDs from parquet.thrift
+func (p *parquetWriter) pageHeader(size int32) []byte {
+	var t thriftWriter
+
+	t.begin()
+	t.i32(1, parquetPageData)
+	t.i32(2, size)
+	t.i32(3, size)
+	t.field(5, thriftStruct)
+	t.begin()
+	t.i32(1, int32(p.rows)) //nolint:gosec // at most parquetRowGroupRows
+	t.i32(2, parquetEncodingPlain)
+	t.i32(3, parquetEncodingRLE)
+	t.i32(4, parquetEncodingRLE)
+	t.end()
+	t.end()
+
+	return t.buf
+}
+
+// footer encodes the FileMetaData of the row groups written.
+//
+//nolint:mnd // field IDs from parquet.thrift
+func (p *parquetWriter) footer() []byte {
+	var t thriftWriter
+
+	t.begin()
+	t.i32(1, parquetVersion)
+	t.list(2, thriftStruct, len(p.columns)+1)
+	t.begin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(p.columns))) //nolint:gosec // a handful of columns
+	t.end()
+
+	for _, column := range p.columns {
+		t.begin()
+		t.i32(1, column.Type)
+		t.i32(3, parquetRequired)
+		t.binary(4, column.Name)
+
+		if column.Type == parquetTypeByteArray {
+			t.i32(6, parquetConvertedUTF8)
+		}
+
+		t.end()
+	}
+
+	t.i64(3, p.total)
+	t.list(4, thriftStruct, len(p.groups))
+
+	for _, group := range p.groups {
+		t.begin()
+		t.list(1, thriftStruct, len(group.Chunks))
+
+		for i, chunk := range group.Chunks {
+			p.columnChunk(&t, p.columns[i], chunk, group.Rows)
+		}
+
+		t.i64(2, group.Size)
+		t.i64(3, group.Rows)
+		t.end()
+	}
+
+	t.binary(6, "unlurker")
+	t.end()
+
+	return t.buf
+}
+
+// columnChunk encodes the ColumnChunk of one column of a row group as an element of a list.
+//
+//nolint:mnd // field IDs from parquet.thrift
+func (p *parquetWriter) columnChunk(t *thriftWriter, column parquetColumn, chunk parquetChunk, rows int64) {
+	t.begin()
+	t.i64(2, chunk.Offset)
+	t.field(3, thriftStruct)
+	t.begin()
+	t.i32(1, column.Type)
+	t.list(2, thriftI32, 2)
+	t.varint(zigzag(parquetEncodingPlain))
+	t.varint(zigzag(parquetEncodingRLE))
+	t.list(3, thriftBinary, 1)
+	t.str(column.Name)
+	t.i32(4, parquetCodecNone)
+	t.i64(5, rows)
+	t.i64(6, chunk.Size)
+	t.i64(7, chunk.Size)
+	t.i64(9, chunk.Offset)
+	t.end()
+	t.end()
+}
+
+// thriftWriter encodes structs with the Thrift compact protocol, which Parquet uses for its
+// metadata. Each struct, including the outermost, is written between begin and end.
+type thriftWriter struct {
+	buf  []byte
+	last []int16
+	id   int16
+}
+
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, t.id)
+	t.id = 0
+}
+
+func (t *thriftWriter) end() {
+	t.buf = append(t.buf, 0)
+	t.id = t.last[len(t.last)-1]
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.id; delta > 0 && delta <= thriftMaxDelta {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(zigzag(int64(id)))
+	}
+
+	t.id = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.str(s)
+}
+
+// list writes the header of a list field of n elements of type elem, which the caller writes next.
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+
+	if n < thriftShortList {
+		t.buf = append(t.buf, byte(n)<<4|elem)
+		return
+	}
+
+	t.buf = append(t.buf, thriftLongList|elem)
+	t.varint(uint64(n))
+}
+
+func (t *thriftWriter) str(s string) {
+	t.varint(uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf = binary.AppendUvarint(t.buf, v)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63)) //nolint:gosec // zigzag encoding
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+const (
+	// partialFallbackShare is the fraction of the remaining time, as a divisor, kept for collecting
+	// the subtrees fetched so far when fetching the whole tree runs out of time.
+	partialFallbackShare = 4
+	// partialRenderShare is the fraction of the remaining time, as a divisor, kept for rendering.
+	partialRenderShare = 20
+)
+
+var errInvalidResume = errors.New("invalid resume")
+
+type handleItemDescendantsPartialResponse struct {
+	Items   any    `json:"items"`
+	Resume  string `json:"resume,omitempty"`
+	Partial bool   `json:"partial"`
+}
+
+// parsePartial reads the partial and resume query parameters. Partial results cannot be combined
+// with paging, and resume, the ID of a top-level comment to continue from, needs partial.
+func parsePartial(c *gin.Context) (bool, int, bool) {
+	partial, err := strconv.ParseBool(c.DefaultQuery("partial", "false"))
+	if err != nil {
+		respondParamError(c, codeInvalidPartial, "partial", "invalid partial")
+		return false, 0, false
+	}
+
+	_, hasLimit := c.GetQuery("limit")
+	_, hasCursor := c.GetQuery("cursor")
+
+	if partial && (hasLimit || hasCursor) {
+		respondParamError(c, codeInvalidPartial, "partial", "partial cannot be combined with limit or cursor")
+		return false, 0, false
+	}
+
+	param, ok := c.GetQuery("resume")
+	if !ok {
+		return partial, 0, true
+	}
+
+	resume, err := strconv.Atoi(param)
+	if err != nil || resume <= 0 || !partial {
+		respondParamError(c, codeInvalidResume, "resume", "invalid resume")
+		return false, 0, false
+	}
+
+	return partial, resume, true
+}
+
+// getPartialDescendants returns the descendants of item under its top-level comments from resume
+// on, or all of them if resume is 0. The whole tree is fetched at once while there is time; if
+// that runs into the deadline of ctx, the subtrees of the top-level comments are collected in order,
+// mostly from what was cached by then, until just before the deadline. The ID of the first
+// top-level comment whose subtree is missing is returned to resume from, or 0 if none is.
+func getPartialDescendants(
+	ctx context.Context, client *itemClient, item *hn.Item, resume int,
+) (hn.ItemSet, int, error) {
+	root := *item
+
+	if resume != 0 {
+		i := slices.Index(root.Kids, resume)
+		if i < 0 {
+			return nil, 0, errInvalidResume
+		}
+
+		root.Kids = root.Kids[i:]
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		all, err := fetchDescendants(ctx, client, hn.ItemSet{root.ID: &root})
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", errUpstream, err)
+		}
+
+		return all, 0, nil
+	}
+
+	remaining := time.Until(deadline)
+
+	whole, cancel := context.WithDeadline(ctx, deadline.Add(-remaining/partialFallbackShare))
+	defer cancel()
+
+	all, err := fetchDescendants(whole, client, hn.ItemSet{root.ID: &root})
+	if err == nil {
+		return all, 0, nil
+	}
+
+	if ctx.Err() != nil || !errors.Is(whole.Err(), context.DeadlineExceeded) {
+		return nil, 0, fmt.Errorf("%w: %w", errUpstream, err)
+	}
+
+	all, resume = collectSubtrees(ctx, client, &root, deadline.Add(-remaining/partialRenderShare))
+
+	return all, resume, nil
+}
+
+// collectSubtrees fetches the subtrees of the kids of root in order until one fails or deadline
+// passes, returning the items fetched and the first kid whose subtree is missing, or 0.
+func collectSubtrees(ctx context.Context, client *itemClient, root *hn.Item, deadline time.Time) (hn.ItemSet, int) {
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	all := hn.ItemSet{root.ID: root}
+	fetched := 0
+
+	for result := range fetchSubtrees(ctx, client, root.Kids) {
+		if result.err != nil {
+			break
+		}
+
+		// a kid only counts once its own item is there, so resume never moves past a missing one
+		if len(result.entries) == 0 || result.entries[0].Item.ID != root.Kids[fetched] {
+			break
+		}
+
+		for _, entry := range result.entries {
+			all[entry.Item.ID] = entry.Item
+		}
+
+		fetched++
+	}
+
+	if fetched == len(root.Kids) {
+		return all, 0
+	}
+
+	return all, root.Kids[fetched]
+}
+
+// respondPartialDescendants responds with the items of a tree in the shape they would have had
+// without partial, along with whether top-level subtrees are missing and where to resume.
+func respondPartialDescendants(
+	c *gin.Context,
+	response []handleItemDescendantsResponse,
+	nested bool,
+	fields fieldSet,
+	resume int,
+) {
+	var items any = response
+
+	switch {
+	case nested && fields != nil:
+		items = fields.pick(nestItemDescendants(response))
+	case nested:
+		items = nestItemDescendants(response)
+	case fields != nil:
+		items = fields.pick(response)
+	}
+
+	result := handleItemDescendantsPartialResponse{Items: items, Resume: "", Partial: resume != 0}
+	if resume != 0 {
+		result.Resume = strconv.Itoa(resume)
+	}
+
+	respond(c, http.StatusOK, result)
+}
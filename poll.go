@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// getPollOptions returns the options of a poll in display order, or nil if item is not a poll.
+// Options are not descendants of the poll, so they have to be fetched separately from its parts.
+func getPollOptions(ctx context.Context, client *itemClient, item *hn.Item) ([]*hn.Item, error) {
+	if item.Type != "poll" || len(item.Parts) == 0 {
+		return nil, nil
+	}
+
+	items, err := fetchItems(ctx, client, item.Parts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUpstream, err)
+	}
+
+	options := make([]*hn.Item, 0, len(item.Parts))
+
+	for _, id := range item.Parts {
+		option, ok := items[id]
+		if ok && option != nil && !option.Deleted {
+			options = append(options, option)
+		}
+	}
+
+	return options, nil
+}
+
+// pollOptionResponses converts poll options to tree entries that follow the poll at depth 1. The
+// pollopt type and score tell them apart from comments.
+func pollOptionResponses(
+	options []*hn.Item,
+	formatter *textFormatter,
+	mode textMode,
+	showUser bool,
+) []handleItemDescendantsResponse {
+	response := make([]handleItemDescendantsResponse, 0, len(options))
+
+	for _, option := range options {
+		by := option.By
+		if !showUser {
+			by = ""
+		}
+
+		response = append(response, handleItemDescendantsResponse{
+			storyMetadata:     newStoryMetadata(option),
+			By:                by,
+			Text:              formatter.formatAs(option, mode),
+			Children:          nil,
+			AccountAgeDays:    nil,
+			Karma:             nil,
+			Time:              option.Time,
+			ID:                option.ID,
+			Parent:            option.Poll,
+			Root:              option.Poll,
+			Depth:             1,
+			TruncatedChildren: 0,
+			OP:                false,
+			Dead:              option.Dead,
+			Deleted:           option.Deleted,
+			IsNew:             false,
+			NewUser:           false,
+		})
+	}
+
+	return response
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/unl"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+)
+
+const (
+	day = 24 * time.Hour
+
+	// newUserAge is how long HN shows the names of new accounts in green.
+	newUserAge = 14 * day
+
+	profileEntries     = 100_000
+	profileQueueSize   = 10_000
+	profileBatch       = 50
+	profileConcurrency = 4
+)
+
+// userProfile is the part of an HN user profile kept for enriching items. It is zero for users
+// that do not exist.
+type userProfile struct {
+	Created int64
+	Karma   int
+}
+
+// accountAge returns the age in whole days of the account with profile p and whether it is new
+// enough for HN to show its name in green, or nil if the profile is not known.
+func (p userProfile) accountAge(now time.Time) (*int, bool) {
+	if p.Created == 0 {
+		return nil, false
+	}
+
+	age := now.Sub(time.Unix(p.Created, 0))
+	days := int(age / day)
+
+	return &days, age < newUserAge
+}
+
+// karma returns the karma of the account with profile p if enrich asks for it, or nil if it does
+// not or the profile is not known.
+func (p userProfile) karma(enrich enrichment) *int {
+	if !enrich.Karma || p.Created == 0 {
+		return nil
+	}
+
+	karma := p.Karma
+
+	return &karma
+}
+
+// enrichment is the optional author details the enrich query parameter adds to items.
+type enrichment struct {
+	Karma bool
+}
+
+// parseEnrich reads the enrich query parameter, a comma-separated list of author details to add to
+// items; karma is the only one.
+func parseEnrich(c *gin.Context) (enrichment, bool) {
+	var enrich enrichment
+
+	for _, name := range splitList(c.Query("enrich")) {
+		switch name {
+		case "karma":
+			enrich.Karma = true
+		default:
+			return enrich, false
+		}
+	}
+
+	return enrich, true
+}
+
+// flatAuthors returns the authors of the items of a flattened tree.
+func flatAuthors(flat []unl.FlatItem) []string {
+	names := make([]string, 0, len(flat))
+	for _, f := range flat {
+		names = append(names, f.By)
+	}
+
+	return names
+}
+
+// profileFetcher fetches the profiles of commenters in the background as they are looked up, so
+// enriching a response never waits on the HN API: a profile that is not cached yet is queued and
+// shows up in later responses. Queued profiles are fetched in batches, a few at a time and at a
+// limited rate on top of the limits on every HN API request, and cached for ttl.
+type profileFetcher struct {
+	cache      *lruCache[string, userProfile]
+	httpClient *http.Client
+	limiter    *rate.Limiter
+	queue      chan string
+	queued     map[string]bool
+	ttl        time.Duration
+	mu         sync.Mutex
+}
+
+// newProfileFetcher creates a fetcher that caches profiles for ttl and fetches at most perSecond of
+// them per second; a perSecond of 0 does not limit the rate.
+func newProfileFetcher(httpClient *http.Client, ttl time.Duration, perSecond float64) *profileFetcher {
+	limit := rate.Limit(perSecond)
+	if perSecond <= 0 {
+		limit = rate.Inf
+	}
+
+	return &profileFetcher{
+		cache:      newLRUCache[string, userProfile](profileEntries, 0, nil),
+		httpClient: httpClient,
+		limiter:    rate.NewLimiter(limit, profileConcurrency),
+		queue:      make(chan string, profileQueueSize),
+		queued:     make(map[string]bool),
+		ttl:        ttl,
+		mu:         sync.Mutex{},
+	}
+}
+
+// lookup returns the cached profiles of the users named and queues the others to be fetched. A nil
+// fetcher knows no profiles.
+func (f *profileFetcher) lookup(names []string) map[string]userProfile {
+	if f == nil {
+		return nil
+	}
+
+	profiles := make(map[string]userProfile)
+
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		profile, ok := f.cache.Get(name)
+		if ok {
+			profiles[name] = profile
+			continue
+		}
+
+		f.enqueue(name)
+	}
+
+	return profiles
+}
+
+// enqueue queues the profile of the user named to be fetched unless it already is. If the queue is
+// full the name is dropped; it is queued again the next time it is looked up.
+func (f *profileFetcher) enqueue(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.queued[name] {
+		return
+	}
+
+	select {
+	case f.queue <- name:
+		f.queued[name] = true
+	default:
+	}
+}
+
+// run fetches queued profiles until ctx is done, taking up to profileBatch of them at a time.
+func (f *profileFetcher) run(ctx context.Context) {
+	for {
+		var batch []string
+
+		select {
+		case <-ctx.Done():
+			return
+		case name := <-f.queue:
+			batch = append(batch, name)
+		}
+
+	collect:
+		for len(batch) < profileBatch {
+			select {
+			case name := <-f.queue:
+				batch = append(batch, name)
+			default:
+				break collect
+			}
+		}
+
+		f.fetch(ctx, batch)
+	}
+}
+
+// fetch fetches and caches the profiles of the users named, profileConcurrency at a time. Failures
+// are logged and the names are queued again by a later lookup.
+func (f *profileFetcher) fetch(ctx context.Context, names []string) {
+	var workers errgroup.Group
+
+	workers.SetLimit(profileConcurrency)
+
+	for _, name := range names {
+		workers.Go(func() error {
+			defer f.dequeue(name)
+
+			err := f.limiter.Wait(ctx)
+			if err != nil {
+				return nil //nolint:nilerr // shutting down
+			}
+
+			user, err := fetchUser(ctx, f.httpClient, name)
+			if err != nil {
+				log.Printf("failed to fetch profile of %s: %v", name, err)
+				return nil
+			}
+
+			var profile userProfile
+			if user != nil {
+				profile = userProfile{Created: user.Created, Karma: user.Karma}
+			}
+
+			f.cache.Put(name, profile, f.ttl)
+
+			return nil
+		})
+	}
+
+	_ = workers.Wait()
+}
+
+func (f *profileFetcher) dequeue(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.queued, name)
+}
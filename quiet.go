@@ -0,0 +1,134 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+const defaultQuietMinScore = 10
+
+type handleQuietResponse struct {
+	Items              []handleActiveResponseItem `json:"items"`
+	SecondChanceFailed bool                       `json:"secondChanceFailed"`
+}
+
+// handleQuiet responds with the recent stories that have at least min-score points but are not
+// in the /active set for the same window, max-age, and min-by, newest first. These are the
+// discussions waiting to be started rather than joined.
+//
+//nolint:cyclop // need parsing helper
+func handleQuiet(
+	c *gin.Context,
+	client *itemClient,
+	httpClient *http.Client,
+	source *activeSource,
+	formatter *textFormatter,
+) {
+	ctx := c.Request.Context()
+
+	var errs paramErrors
+
+	params, windows := parseActiveParams(c, source.Params(), source.Bounds(), &errs)
+	if len(windows) > 1 {
+		errs.add(codeInvalidWindow, "window", "/quiet takes a single window")
+	}
+
+	minScore, err := strconv.Atoi(c.DefaultQuery("min-score", strconv.Itoa(defaultQuietMinScore)))
+	if err != nil {
+		errs.add(codeInvalidMinScore, "min-score", "invalid min-score")
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultListLimit)))
+	if err != nil || limit < 1 || limit > maxNewestLimit {
+		errs.add(codeInvalidLimit, "limit", "invalid limit")
+	}
+
+	opts := parseStoryListOptions(c, &errs)
+
+	if errs.respond(c) {
+		return
+	}
+
+	snapshot, err := source.Get(ctx, params)
+	if err != nil {
+		respondSnapshotError(c, err)
+		return
+	}
+
+	candidates, err := recentStories(ctx, client, httpClient)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve stories")
+		return
+	}
+
+	active := make(map[int]bool, len(snapshot.Roots))
+	for _, root := range snapshot.Roots {
+		active[root.Item.ID] = true
+	}
+
+	agedAfter := time.Now().Add(-params.MaxAge).Unix()
+
+	quiet := make([]*hn.Item, 0, len(candidates))
+
+	for _, item := range candidates {
+		if item.Type == "story" && !active[item.ID] && item.Score >= minScore && item.Time > agedAfter {
+			quiet = append(quiet, item)
+		}
+	}
+
+	slices.SortFunc(quiet, func(a, b *hn.Item) int { return cmp.Compare(b.Time, a.Time) })
+
+	quietIDs := make([]int, 0, min(limit, len(quiet)))
+	for _, item := range quiet[:min(limit, len(quiet))] {
+		quietIDs = append(quietIDs, item.ID)
+	}
+
+	items, err := hydrateStories(ctx, client, formatter, quietIDs, opts)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve items")
+		return
+	}
+
+	respond(c, http.StatusOK, handleQuietResponse{Items: items, SecondChanceFailed: snapshot.SecondChanceFailed})
+}
+
+// recentStories retrieves the live stories on the new and top lists, which between them cover
+// the recent stories that have had a chance to collect points.
+func recentStories(ctx context.Context, client *itemClient, httpClient *http.Client) ([]*hn.Item, error) {
+	var ids []int
+
+	for _, list := range []string{"newstories", "topstories"} {
+		listIDs, err := fetchStoryIDs(ctx, httpClient, list)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, listIDs...)
+	}
+
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	stories, err := fetchItems(ctx, client, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*hn.Item, 0, len(ids))
+
+	for _, id := range ids {
+		item, ok := stories[id]
+		if ok && item != nil && !item.Deleted && !item.Dead {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
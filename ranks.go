@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type rankSample struct {
+	Time         int64 `json:"time"`
+	Rank         int   `json:"rank"`
+	Score        int   `json:"score"`
+	SecondChance bool  `json:"secondChance"`
+}
+
+// frontPageAppearance is a run of consecutive front-page samples that included a story.
+type frontPageAppearance struct {
+	From         int64 `json:"from"`
+	To           int64 `json:"to"`
+	BestRank     int   `json:"bestRank"`
+	SecondChance bool  `json:"secondChance"`
+}
+
+type handleRankHistoryResponse struct {
+	Ranks       []rankSample          `json:"ranks"`
+	Appearances []frontPageAppearance `json:"appearances"`
+	ID          int                   `json:"id"`
+}
+
+// recordRanks samples the front page every interval until ctx is done, storing the rank of each
+// story. It returns immediately if there is no store or interval is not positive.
+func recordRanks(ctx context.Context, client *itemClient, httpClient *http.Client, st *store, interval time.Duration) {
+	if st == nil || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+
+		stories, err := getFrontPage(ctx, client, httpClient, now)
+		if err == nil {
+			err = st.recordRanks(ctx, now, stories)
+		}
+
+		if err != nil {
+			log.Printf("failed to record front-page ranks: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordRanks stores one front-page sample. The sample is stored even when it is empty so that
+// gaps between appearances can be told apart from gaps in sampling.
+func (s *store) recordRanks(ctx context.Context, now time.Time, stories []frontPageStory) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin recording ranks: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO front_page_samples (time) VALUES (?)`, now.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record front-page sample: %w", err)
+	}
+
+	for _, story := range stories {
+		_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO front_page_ranks
+			(id, time, rank, score, second_chance) VALUES (?, ?, ?, ?, ?)`,
+			story.ID, now.Unix(), story.Rank, story.Score, story.DisplayTime != story.SubmitTime)
+		if err != nil {
+			return fmt.Errorf("failed to record rank of %d: %w", story.ID, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit ranks: %w", err)
+	}
+
+	return nil
+}
+
+// rankSamples returns the front-page samples that included a story, oldest first.
+func (s *store) rankSamples(ctx context.Context, id int) ([]rankSample, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT time, rank, score, second_chance FROM front_page_ranks WHERE id = ? ORDER BY time`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ranks: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	ranks := make([]rankSample, 0)
+
+	for rows.Next() {
+		var sample rankSample
+
+		err = rows.Scan(&sample.Time, &sample.Rank, &sample.Score, &sample.SecondChance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rank: %w", err)
+		}
+
+		ranks = append(ranks, sample)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ranks: %w", err)
+	}
+
+	return ranks, nil
+}
+
+// sampleTimes returns the times of the front-page samples from from to to, oldest first.
+func (s *store) sampleTimes(ctx context.Context, from int64, to int64) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT time FROM front_page_samples WHERE time >= ? AND time <= ? ORDER BY time`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read samples: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	var times []int64
+
+	for rows.Next() {
+		var t int64
+
+		err = rows.Scan(&t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sample: %w", err)
+		}
+
+		times = append(times, t)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read samples: %w", err)
+	}
+
+	return times, nil
+}
+
+// frontPageAppearances splits the samples that included a story into runs, ending a run at each
+// sample that did not include it. A run is a second-chance appearance if the story was shown
+// with an adjusted time during it.
+func frontPageAppearances(ranks []rankSample, times []int64) []frontPageAppearance {
+	appearances := make([]frontPageAppearance, 0)
+	next := 0
+	inRun := false
+
+	for _, t := range times {
+		if next >= len(ranks) || ranks[next].Time != t {
+			inRun = false
+			continue
+		}
+
+		sample := ranks[next]
+		next++
+
+		if !inRun {
+			appearances = append(appearances, frontPageAppearance{
+				From:         t,
+				To:           t,
+				BestRank:     sample.Rank,
+				SecondChance: false,
+			})
+			inRun = true
+		}
+
+		last := &appearances[len(appearances)-1]
+		last.To = t
+		last.SecondChance = last.SecondChance || sample.SecondChance
+
+		if sample.Rank != 0 && (last.BestRank == 0 || sample.Rank < last.BestRank) {
+			last.BestRank = sample.Rank
+		}
+	}
+
+	return appearances
+}
+
+// handleRankHistory responds with the front-page rank of a story in each sample that included
+// it and the separate runs in which it appeared, which shows how long it stayed on the front
+// page and whether it came back through the second-chance pool.
+func handleRankHistory(c *gin.Context, st *store) {
+	ctx := c.Request.Context()
+
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondParamError(c, codeInvalidID, "id", "invalid id")
+		return
+	}
+
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	ranks, err := st.rankSamples(ctx, itemID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read ranks")
+		return
+	}
+
+	var times []int64
+
+	if len(ranks) > 0 {
+		times, err = st.sampleTimes(ctx, ranks[0].Time, ranks[len(ranks)-1].Time)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read samples")
+			return
+		}
+	}
+
+	respond(c, http.StatusOK, handleRankHistoryResponse{
+		Ranks:       ranks,
+		Appearances: frontPageAppearances(ranks, times),
+		ID:          itemID,
+	})
+}
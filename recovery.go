@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const sentryTimeout = 5 * time.Second
+
+var errInvalidSentryDSN = errors.New("--sentry-dsn must look like https://<key>@<host>/<project>")
+
+// panicReport describes a panic recovered while serving a request.
+type panicReport struct {
+	Value     any
+	RequestID string
+	Method    string
+	Route     string
+	URL       string
+	Stack     []byte
+}
+
+// panicReporter forwards recovered panics to an error tracker.
+type panicReporter interface {
+	ReportPanic(ctx context.Context, report panicReport)
+}
+
+// recoverPanics turns a panic in a handler into the standard 500 error body, logs it with its
+// stack and request ID, and passes it to reporter unless reporter is nil. If the response was
+// already partly written, the connection is left to be closed without an error body.
+func recoverPanics(reporter panicReporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+
+			report := panicReport{
+				Value:     value,
+				RequestID: requestID(c.Request.Context()),
+				Method:    c.Request.Method,
+				Route:     c.FullPath(),
+				URL:       c.Request.URL.String(),
+				Stack:     debug.Stack(),
+			}
+
+			slog.ErrorContext(c.Request.Context(), "panic",
+				slog.String("requestId", report.RequestID),
+				slog.String("route", report.Route),
+				slog.String("panic", fmt.Sprint(value)),
+				slog.String("stack", string(report.Stack)),
+			)
+
+			if reporter != nil {
+				reporter.ReportPanic(context.WithoutCancel(c.Request.Context()), report)
+			}
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			respondError(c, http.StatusInternalServerError, codeInternalError, "internal error")
+		}()
+
+		c.Next()
+	}
+}
+
+// sentryReporter sends panics to Sentry, or anything accepting its store API, as error events.
+type sentryReporter struct {
+	client   *http.Client
+	endpoint string
+	auth     string
+	release  string
+}
+
+// newPanicReporter returns a reporter sending panics to the Sentry project of dsn, or nil if dsn is
+// empty.
+func newPanicReporter(dsn string, release string) (panicReporter, error) {
+	if dsn == "" {
+		return nil, nil //nolint:nilnil // no reporter is configured
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, errInvalidSentryDSN
+	}
+
+	path, project, ok := cutLast(strings.TrimSuffix(u.Path, "/"), "/")
+	if !ok || project == "" {
+		return nil, errInvalidSentryDSN
+	}
+
+	return &sentryReporter{
+		client:   &http.Client{Timeout: sentryTimeout},
+		endpoint: u.Scheme + "://" + u.Host + path + "/api/" + project + "/store/",
+		auth:     "Sentry sentry_version=7, sentry_client=unlurker-web/" + release + ", sentry_key=" + u.User.Username(),
+		release:  release,
+	}, nil
+}
+
+func cutLast(s string, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+len(sep):], true
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sentryEvent struct {
+	Tags      map[string]string            `json:"tags"`
+	Extra     map[string]string            `json:"extra"`
+	Request   map[string]string            `json:"request"`
+	Exception map[string][]sentryException `json:"exception"`
+	EventID   string                       `json:"event_id"` //nolint:tagliatelle // external API
+	Timestamp string                       `json:"timestamp"`
+	Level     string                       `json:"level"`
+	Platform  string                       `json:"platform"`
+	Release   string                       `json:"release"`
+	Culprit   string                       `json:"culprit"`
+}
+
+// ReportPanic sends the panic in the background, logging failures, so the request is not held up.
+func (r *sentryReporter) ReportPanic(ctx context.Context, report panicReport) {
+	id := make([]byte, 16) //nolint:mnd // Sentry event IDs are UUIDs without dashes
+	_, _ = rand.Read(id)
+
+	event := sentryEvent{
+		Tags:    map[string]string{"request_id": report.RequestID},
+		Extra:   map[string]string{"stack": string(report.Stack)},
+		Request: map[string]string{"method": report.Method, "url": report.URL},
+		Exception: map[string][]sentryException{"values": {{
+			Type:  fmt.Sprintf("%T", report.Value),
+			Value: fmt.Sprint(report.Value),
+		}}},
+		EventID:   hex.EncodeToString(id),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Level:     "error",
+		Platform:  "go",
+		Release:   r.release,
+		Culprit:   report.Route,
+	}
+
+	go func() {
+		err := r.send(ctx, event)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to report panic", slog.String("error", err.Error()))
+		}
+	}()
+}
+
+func (r *sentryReporter) send(ctx context.Context, event sentryEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w sending event: %d", errUnexpectedStatus, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// liveConfig holds the configuration while the server runs so it can be replaced on reload or
+// through /admin/config. Only the settings read on each request, the defaults and interval of the
+// precomputed /active snapshot, the log level, and the HN API rate take effect when it is replaced;
+// changing the rest needs a restart.
+type liveConfig struct {
+	upstream *limitedTransport
+	source   *activeSource
+	current  atomic.Pointer[config]
+	level    slog.LevelVar
+	mu       sync.Mutex
+}
+
+func newLiveConfig(cfg config, upstream *limitedTransport, source *activeSource) *liveConfig {
+	live := &liveConfig{
+		upstream: upstream,
+		source:   source,
+		current:  atomic.Pointer[config]{},
+		level:    slog.LevelVar{},
+		mu:       sync.Mutex{},
+	}
+
+	live.Store(cfg)
+
+	return live
+}
+
+// Load returns the current configuration.
+func (l *liveConfig) Load() config {
+	return *l.current.Load()
+}
+
+// Store replaces the configuration and applies the settings that can change while running.
+func (l *liveConfig) Store(cfg config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.store(cfg)
+}
+
+// Update stores a copy of the current configuration changed by fn, unless fn fails, and returns
+// it.
+func (l *liveConfig) Update(fn func(*config) error) (config, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cfg := l.Load()
+	cfg.Effective = maps.Clone(cfg.Effective)
+
+	err := fn(&cfg)
+	if err != nil {
+		return config{}, err
+	}
+
+	l.store(cfg)
+
+	return cfg, nil
+}
+
+func (l *liveConfig) store(cfg config) {
+	l.current.Store(&cfg)
+	l.level.Set(cfg.LogLevel)
+	l.upstream.SetRate(cfg.HNRate, cfg.HNBurst)
+	l.source.SetParams(cfg.activeParams())
+	l.source.SetBounds(cfg.activeBounds())
+	l.source.SetInterval(cfg.PrecomputeInterval)
+	l.source.SetFreshness(cfg.ActiveFreshFor, cfg.ActiveStaleFor)
+}
+
+// Level returns the configured log level for a slog handler, which follows changes to it.
+func (l *liveConfig) Level() slog.Leveler {
+	return &l.level
+}
+
+// reloadOnHangup reloads the configuration from args, the environment, and the config file each
+// time the process receives SIGHUP, until ctx is done. An invalid configuration is logged and the
+// current one is kept. Changes made through /admin/config are replaced.
+func reloadOnHangup(ctx context.Context, args []string, live *liveConfig) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+
+		cfg, err := loadConfig(args)
+		if err != nil {
+			log.Printf("failed to reload configuration, keeping the current one: %v", err)
+			continue
+		}
+
+		if !reflect.DeepEqual(cfg.restartOnly(), live.Load().restartOnly()) {
+			log.Printf("reloaded configuration changes settings that only take effect on restart")
+		}
+
+		live.Store(cfg)
+
+		log.Printf("reloaded configuration")
+	}
+}
+
+// restartOnly returns cfg without the settings that take effect while running.
+func (cfg config) restartOnly() config {
+	cfg.Effective = nil
+	cfg.Block = nil
+	cfg.SlackWebhookURL = ""
+	cfg.DiscordWebhookURL = ""
+	cfg.NotifyKeywords = nil
+	cfg.NotifyCommentsPerHour = 0
+	cfg.SMTPAddr = ""
+	cfg.SMTPUsername = ""
+	cfg.SMTPPassword = ""
+	cfg.DigestFrom = ""
+	cfg.DigestInterval = 0
+	cfg.DigestLimit = 0
+	cfg.CORSOrigins = nil
+	cfg.CORSMethods = nil
+	cfg.CORSHeaders = nil
+	cfg.CORSMaxAge = 0
+	cfg.CacheControl = nil
+	cfg.MaxRequestDuration = 0
+	cfg.PrecomputeInterval = 0
+	cfg.ActiveFreshFor = 0
+	cfg.ActiveStaleFor = 0
+	cfg.LogLevel = 0
+	cfg.HNRetryBudget = 0
+	cfg.HNRate = 0
+	cfg.HNBurst = 0
+	cfg.Window = 0
+	cfg.MaxAge = 0
+	cfg.MinBy = 0
+	cfg.MinWindow = 0
+	cfg.MaxWindow = 0
+	cfg.MaxMaxAge = 0
+	cfg.MinMinBy = 0
+	cfg.MaxMinBy = 0
+	cfg.MaxResponseItems = 0
+	cfg.MaxResponseBytes = 0
+
+	return cfg
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type cachedResponse struct {
+	ContentType string
+	Body        []byte
+	Status      int
+}
+
+// size is the memory the response takes up in the cache, not counting its key.
+func (r cachedResponse) size() int64 {
+	return int64(len(r.ContentType) + len(r.Body))
+}
+
+// cachingWriter buffers a response so it can be cached, remembering the first write error since
+// a response that did not reach the client completely is not one to reuse.
+type cachingWriter struct {
+	gin.ResponseWriter
+	err      error
+	body     bytes.Buffer
+	streamed bool
+}
+
+func (w *cachingWriter) Write(b []byte) (int, error) {
+	if !w.streamed {
+		w.body.Write(b)
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+
+	return n, err //nolint:wrapcheck // plain wrapper
+}
+
+func (w *cachingWriter) WriteString(s string) (int, error) {
+	if !w.streamed {
+		w.body.WriteString(s)
+	}
+
+	n, err := w.ResponseWriter.WriteString(s)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+
+	return n, err //nolint:wrapcheck // plain wrapper
+}
+
+// Flush marks the response as streamed; streamed responses are not buffered or cached.
+func (w *cachingWriter) Flush() {
+	w.streamed = true
+	w.body.Reset()
+	w.ResponseWriter.Flush()
+}
+
+// cacheable reports whether the handler allowed its response to be reused without revalidation.
+func cacheable(header http.Header) bool {
+	cc := header.Get("Cache-Control")
+	return !strings.Contains(cc, "no-cache") && !strings.Contains(cc, "no-store")
+}
+
+// cacheResponses serves successful responses from cache for ttl, keyed by the request path and
+// its normalized query parameters, and reports whether the cache was used in an X-Cache header.
+// Responses are not cached if writing them failed or the handler recorded an error with c.Error,
+// which is how a handler that already sent its status reports that the body is incomplete.
+func cacheResponses(cache *lruCache[string, cachedResponse], ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ttl <= 0 {
+			c.Next()
+			return
+		}
+
+		// Encode sorts by parameter name so equivalent queries share an entry; the Accept header is
+		// part of the key because it can select a different representation
+		key := c.Request.URL.Path + "?" + c.Request.URL.Query().Encode() + "|" + c.GetHeader("Accept")
+
+		cached, ok := cache.Get(key)
+		if ok {
+			c.Header("X-Cache", "HIT")
+			c.Header("Vary", "Accept")
+			c.Data(cached.Status, cached.ContentType, cached.Body)
+			c.Abort()
+
+			return
+		}
+
+		c.Header("X-Cache", "MISS")
+		c.Header("Vary", "Accept")
+
+		w := &cachingWriter{ResponseWriter: c.Writer, err: nil, body: bytes.Buffer{}, streamed: false}
+		c.Writer = w
+
+		c.Next()
+
+		complete := w.err == nil && len(c.Errors) == 0
+		if w.Status() == http.StatusOK && complete && !w.streamed && cacheable(w.Header()) {
+			cache.Put(key, cachedResponse{
+				ContentType: w.Header().Get("Content-Type"),
+				Body:        w.body.Bytes(),
+				Status:      w.Status(),
+			}, ttl)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errClientGone = errors.New("client gone")
+
+// failingRecorder accepts limit bytes of body and fails every write after that, like a client
+// that disconnects partway through a response.
+type failingRecorder struct {
+	*httptest.ResponseRecorder
+	limit int
+}
+
+func (r *failingRecorder) Write(b []byte) (int, error) {
+	if r.Body.Len()+len(b) > r.limit {
+		return 0, errClientGone
+	}
+
+	return r.ResponseRecorder.Write(b) //nolint:wrapcheck // plain wrapper
+}
+
+func (r *failingRecorder) WriteString(s string) (int, error) {
+	return r.Write([]byte(s))
+}
+
+func TestCacheResponsesSkipsIncomplete(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		handler gin.HandlerFunc
+		name    string
+		limit   int
+		cached  bool
+	}{
+		{
+			name:  "complete",
+			limit: 100,
+			handler: func(c *gin.Context) {
+				c.Status(http.StatusOK)
+				_, _ = c.Writer.WriteString(`{"items":[`)
+				_, _ = c.Writer.WriteString(`]}`)
+			},
+			cached: true,
+		},
+		{
+			name:  "write fails partway",
+			limit: 10,
+			handler: func(c *gin.Context) {
+				c.Status(http.StatusOK)
+				_, _ = c.Writer.WriteString(`{"items":[`)
+				_, _ = c.Writer.WriteString(`{"id":1}]}`)
+			},
+			cached: false,
+		},
+		{
+			name:  "handler reports an error",
+			limit: 100,
+			handler: func(c *gin.Context) {
+				c.Status(http.StatusOK)
+				_, _ = c.Writer.WriteString(`{"items":[`)
+				_ = c.Error(errClientGone)
+			},
+			cached: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cache := newLRUCache[string](10, 0, cachedResponse.size)
+
+			r := gin.New()
+			r.GET("/active", cacheResponses(cache, time.Minute), tt.handler)
+
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/active", nil)
+			r.ServeHTTP(&failingRecorder{ResponseRecorder: httptest.NewRecorder(), limit: tt.limit}, req)
+
+			if got := cache.Stats().Entries > 0; got != tt.cached {
+				t.Errorf("cached %v, want %v", got, tt.cached)
+			}
+		})
+	}
+}
+
+func TestStreamActiveResponseWriteFailureNotCached(t *testing.T) {
+	t.Parallel()
+
+	cache := newLRUCache[string](10, 0, cachedResponse.size)
+	snapshot := &activeSnapshot{
+		Time:               time.Now(),
+		Tree:               nil,
+		Roots:              nil,
+		Params:             activeParams{Window: time.Hour, MaxAge: time.Hour, MinBy: 1},
+		SecondChanceFailed: false,
+		Historical:         false,
+		Stale:              false,
+	}
+
+	r := gin.New()
+	r.GET("/active", cacheResponses(cache, time.Minute), func(c *gin.Context) {
+		roots := slices.Values([][]handleActiveResponseItem{{budgetItem(1, 0, true), budgetItem(2, 1, true)}})
+		streamActiveResponse(c, snapshot, roots, func() *responseTruncation { return nil })
+	})
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/active", nil)
+	r.ServeHTTP(&failingRecorder{ResponseRecorder: httptest.NewRecorder(), limit: 20}, req)
+
+	if entries := cache.Stats().Entries; entries != 0 {
+		t.Errorf("%d cached responses, want none", entries)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	retryBaseDelay = 100 * time.Millisecond
+	retryMaxDelay  = 2 * time.Second
+	// retryMaxShift doubles retryBaseDelay past retryMaxDelay without overflowing.
+	retryMaxShift = 16
+)
+
+// retryBudget is the number of retries left for all the upstream fetches made on behalf of one
+// request, so a request for a huge thread against a failing API gives up instead of multiplying
+// the load.
+type retryBudget struct {
+	remaining atomic.Int64
+}
+
+type retryBudgetKey struct{}
+
+// limitRetries gives each request a budget of retries for its upstream fetches.
+func limitRetries(live *liveConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		budget := &retryBudget{remaining: atomic.Int64{}}
+		budget.remaining.Store(int64(live.Load().HNRetryBudget))
+
+		ctx := context.WithValue(c.Request.Context(), retryBudgetKey{}, budget)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// takeRetry reports whether the request of ctx may retry a fetch, using up one retry of its budget.
+// Fetches made outside of a request, like precomputing /active, are not limited.
+func takeRetry(ctx context.Context) bool {
+	budget, ok := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	if !ok {
+		return true
+	}
+
+	return budget.remaining.Add(-1) >= 0
+}
+
+// retryingTransport retries GET requests to one host that fail with a network error, 429, or 5xx,
+// waiting an exponential backoff with full jitter between attempts. Requests to other hosts pass
+// through unchanged.
+type retryingTransport struct {
+	next    http.RoundTripper
+	host    string
+	retries int
+}
+
+func (t *retryingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host || req.Method != http.MethodGet {
+		return t.next.RoundTrip(req) //nolint:wrapcheck // plain wrapper
+	}
+
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if !retryable(resp, err) || ctx.Err() != nil || attempt >= t.retries || !takeRetry(ctx) {
+			return resp, err //nolint:wrapcheck // plain wrapper
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		err = sleep(ctx, retryDelay(attempt))
+		if err != nil {
+			return nil, fmt.Errorf("failed waiting to retry %s: %w", req.URL, err)
+		}
+	}
+}
+
+// retryFetch calls fetch until it succeeds, waiting between attempts as retryingTransport does, for
+// fetches made by libraries that send their own requests. Their responses are not available, so
+// any error is taken as retryable.
+func retryFetch[T any](ctx context.Context, retries int, fetch func(context.Context) (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		v, err := fetch(ctx)
+		if err == nil || ctx.Err() != nil || attempt >= retries || !takeRetry(ctx) {
+			return v, err
+		}
+
+		err = sleep(ctx, retryDelay(attempt))
+		if err != nil {
+			return v, fmt.Errorf("failed waiting to retry: %w", err)
+		}
+	}
+}
+
+// retryable reports whether a failed fetch may succeed if tried again.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryDelay returns a random delay up to an exponentially growing cap so clients that failed
+// together do not retry together. The shift is limited so that many retries cannot overflow it.
+func retryDelay(attempt int) time.Duration {
+	ceiling := min(retryBaseDelay<<min(attempt, retryMaxShift), retryMaxDelay)
+	return rand.N(ceiling) //nolint:gosec // jitter does not need a secure source
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck // wrapped by callers
+	case <-timer.C:
+		return nil
+	}
+}
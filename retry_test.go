@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errFetch = errors.New("fetch failed")
+
+func TestRetryFetch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err      error
+		name     string
+		failures int
+		retries  int
+		budget   int
+		attempts int
+	}{
+		{err: nil, name: "first attempt", failures: 0, retries: 2, budget: -1, attempts: 1},
+		{err: nil, name: "after failures", failures: 2, retries: 2, budget: -1, attempts: 3},
+		{err: errFetch, name: "out of retries", failures: 3, retries: 2, budget: -1, attempts: 3},
+		{err: errFetch, name: "no retries", failures: 1, retries: 0, budget: -1, attempts: 1},
+		{err: errFetch, name: "out of request budget", failures: 3, retries: 2, budget: 1, attempts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := t.Context()
+
+			if tt.budget >= 0 {
+				budget := &retryBudget{remaining: atomic.Int64{}}
+				budget.remaining.Store(int64(tt.budget))
+				ctx = context.WithValue(ctx, retryBudgetKey{}, budget)
+			}
+
+			attempts := 0
+
+			v, err := retryFetch(ctx, tt.retries, func(context.Context) (int, error) {
+				attempts++
+				if attempts <= tt.failures {
+					return 0, errFetch
+				}
+
+				return attempts, nil
+			})
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error %v, want %v", err, tt.err)
+			}
+
+			if err == nil && v != attempts {
+				t.Errorf("value %d, want %d", v, attempts)
+			}
+
+			if attempts != tt.attempts {
+				t.Errorf("%d attempts, want %d", attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err    error
+		name   string
+		status int
+		want   bool
+	}{
+		{err: errFetch, name: "network error", status: 0, want: true},
+		{err: nil, name: "ok", status: http.StatusOK, want: false},
+		{err: nil, name: "not found", status: http.StatusNotFound, want: false},
+		{err: nil, name: "too many requests", status: http.StatusTooManyRequests, want: true},
+		{err: nil, name: "internal server error", status: http.StatusInternalServerError, want: true},
+		{err: nil, name: "bad gateway", status: http.StatusBadGateway, want: true},
+		{err: nil, name: "service unavailable", status: http.StatusServiceUnavailable, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resp *http.Response
+			if tt.err == nil {
+				resp = new(http.Response)
+				resp.StatusCode = tt.status
+			}
+
+			if got := retryable(resp, tt.err); got != tt.want {
+				t.Errorf("retryable %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		attempt int
+		ceiling time.Duration
+	}{
+		{name: "first", attempt: 0, ceiling: retryBaseDelay},
+		{name: "second", attempt: 1, ceiling: 2 * retryBaseDelay},
+		{name: "fourth", attempt: 3, ceiling: 8 * retryBaseDelay},
+		{name: "capped", attempt: 5, ceiling: retryMaxDelay},
+		{name: "past overflow", attempt: 40, ceiling: retryMaxDelay},
+		{name: "past shift width", attempt: 100, ceiling: retryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			for range 1000 {
+				if d := retryDelay(tt.attempt); d < 0 || d >= tt.ceiling {
+					t.Fatalf("delay %v, want in [0, %v)", d, tt.ceiling)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+const (
+	defaultSanitizeTags    = "p,i,em,pre,code,a"
+	defaultSanitizeAttrs   = "a:href"
+	defaultSanitizeSchemes = "http,https,mailto"
+
+	// anyElement in an element:attribute pair allows the attribute on every allowed element.
+	anyElement = "*"
+)
+
+// newSanitizePolicy builds the allowlist applied to comment text. Elements that are not allowed
+// are removed but their content is kept, except for elements like script whose content is
+// dropped too. Links to other sites get rel="nofollow" like they do on HN.
+func newSanitizePolicy(cfg config) *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements(cfg.SanitizeTags...)
+
+	for _, pair := range cfg.SanitizeAttrs {
+		element, attr, _ := strings.Cut(pair, ":")
+
+		if element == anyElement {
+			p.AllowAttrs(attr).Globally()
+		} else {
+			p.AllowAttrs(attr).OnElements(element)
+		}
+	}
+
+	p.AllowURLSchemes(cfg.SanitizeSchemes...)
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnFullyQualifiedLinks(true)
+
+	return p
+}
+
+// sanitizeFingerprint identifies the sanitize policy built from cfg so text formatted under a
+// different policy is not reused.
+func sanitizeFingerprint(cfg config) string {
+	return strings.Join([]string{
+		strings.Join(cfg.SanitizeTags, ","),
+		strings.Join(cfg.SanitizeAttrs, ","),
+		strings.Join(cfg.SanitizeSchemes, ","),
+	}, ";")
+}
+
+// validateSanitizeAttrs checks that each entry is an element:attribute pair.
+func validateSanitizeAttrs(attrs []string) error {
+	for _, pair := range attrs {
+		element, attr, ok := strings.Cut(pair, ":")
+		if !ok || element == "" || attr == "" {
+			return fmt.Errorf("%w: %q", errInvalidSanitizeAttr, pair)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// descendantWeight is how much the score of each reply adds to the score of its parent, so busy
+// subthreads stand out without burying the comments that started them.
+const descendantWeight = 0.5
+
+// scorePrecision rounds scores to three decimal places, which is plenty for shading.
+const scorePrecision = 1000
+
+// activityScores returns a score from 0 to 1 for each comment of a flattened tree, in depth-first
+// order with the given depths, for shading comments by activity instead of only flagging them. A
+// comment scores for being active under isActive, more the more recent it is within the window and
+// the sooner it followed its parent, plus a share of the scores of its replies. Scores are relative
+// to the highest in the thread and rounded to three decimal places; the root is not scored.
+func activityScores(
+	items []*hn.Item,
+	depths []int,
+	isActive func(*hn.Item) bool,
+	activeAfter time.Time,
+	window time.Duration,
+) map[int]float64 {
+	parents := parentIndexes(depths)
+	raw := ownScores(items, parents, isActive, activeAfter, window)
+
+	// replies come after their parents, so walking backwards adds each subtree before its head
+	highest := 0.0
+
+	for i := len(items) - 1; i >= 0; i-- {
+		if parents[i] < 0 {
+			continue
+		}
+
+		highest = max(highest, raw[i])
+
+		if parents[parents[i]] >= 0 {
+			raw[parents[i]] += descendantWeight * raw[i]
+		}
+	}
+
+	scores := make(map[int]float64, len(items))
+	if highest == 0 {
+		return scores
+	}
+
+	for i, item := range items {
+		if parents[i] >= 0 && raw[i] > 0 {
+			scores[item.ID] = math.Round(raw[i]/highest*scorePrecision) / scorePrecision
+		}
+	}
+
+	return scores
+}
+
+// ownScores returns the score of each comment of a flattened tree, with the given parent indexes,
+// without its replies.
+func ownScores(
+	items []*hn.Item,
+	parents []int,
+	isActive func(*hn.Item) bool,
+	activeAfter time.Time,
+	window time.Duration,
+) []float64 {
+	scores := make([]float64, len(items))
+
+	for i, item := range items {
+		if !isActive(item) {
+			continue
+		}
+
+		speed := 0.0
+		if parents[i] >= 0 {
+			speed = replySpeed(item.Time-items[parents[i]].Time, window)
+		}
+
+		scores[i] = recency(item.Time, activeAfter, window) * (1 + speed) / 2
+	}
+
+	return scores
+}
+
+// recency is 1 for a comment posted now and falls to 0 for one posted at the start of the window.
+func recency(t int64, activeAfter time.Time, window time.Duration) float64 {
+	return min(max(float64(t-activeAfter.Unix())/window.Seconds(), 0), 1)
+}
+
+// replySpeed is 1 for a reply posted as soon as its parent, a half for one posted the share of the
+// window of the velocity rule later, and falls towards 0 after that.
+func replySpeed(gap int64, window time.Duration) float64 {
+	return 1 / (1 + max(float64(gap), 0)/(window.Seconds()/velocityShare))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// searchTypes maps the type query parameter of /search to HN Search tags.
+func searchTypes() map[string]string {
+	return map[string]string{
+		"all":     "",
+		"story":   "story",
+		"comment": "comment",
+		"ask":     "ask_hn",
+		"show":    "show_hn",
+		"poll":    "poll",
+		"job":     "job",
+	}
+}
+
+// searchSorts maps the sort query parameter of /search to HN Search endpoints.
+func searchSorts() map[string]string {
+	return map[string]string{
+		"relevance": "search",
+		"date":      "search_by_date",
+	}
+}
+
+type handleSearchResponse struct {
+	Items []handleItemDescendantsResponse `json:"items"`
+	Total int                             `json:"total"`
+	Page  int                             `json:"page"`
+	Pages int                             `json:"pages"`
+}
+
+// handleSearch proxies a query to HN Search and returns the hits in the same shape as the items
+// of /user, so clients can render live and historical items the same way.
+//
+//nolint:cyclop // need parsing helper
+func handleSearch(c *gin.Context, formatter *textFormatter) {
+	q := c.Query("q")
+	if q == "" {
+		respondParamError(c, codeInvalidQuery, "q", "missing q")
+		return
+	}
+
+	tags, ok := searchTypes()[c.DefaultQuery("type", "all")]
+	if !ok {
+		respondParamError(c, codeInvalidTypes, "type", "invalid type")
+		return
+	}
+
+	endpoint, ok := searchSorts()[c.DefaultQuery("sort", "relevance")]
+	if !ok {
+		respondParamError(c, codeInvalidSort, "sort", "invalid sort")
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "0"))
+	if err != nil || page < 0 {
+		respondParamError(c, codeInvalidPage, "page", "invalid page")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSearchLimit)))
+	if err != nil || limit < 1 || limit > maxSearchLimit {
+		respondParamError(c, codeInvalidLimit, "limit", "invalid limit")
+		return
+	}
+
+	var errs paramErrors
+
+	opts := parseStoryListOptions(c, &errs)
+
+	if errs.respond(c) {
+		return
+	}
+
+	params := url.Values{"query": {q}, "page": {strconv.Itoa(page)}, "hitsPerPage": {strconv.Itoa(limit)}}
+	if tags != "" {
+		params.Set("tags", tags)
+	}
+
+	result, err := searchHN(c.Request.Context(), endpoint, params)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to search")
+		return
+	}
+
+	items := make([]handleItemDescendantsResponse, 0, len(result.Hits))
+
+	for _, hit := range result.Hits {
+		item, ok := searchHitResponse(hit, formatter, opts)
+		if ok {
+			items = append(items, item)
+		}
+	}
+
+	respond(c, http.StatusOK, handleSearchResponse{
+		Items: items,
+		Total: result.NbHits,
+		Page:  result.Page,
+		Pages: result.NbPages,
+	})
+}
+
+// searchHitResponse converts a hit to an item response, returning false if the hit has no item
+// ID.
+func searchHitResponse(
+	hit hnSearchHit,
+	formatter *textFormatter,
+	opts storyListOptions,
+) (handleItemDescendantsResponse, bool) {
+	var invalid handleItemDescendantsResponse
+
+	id, err := strconv.Atoi(hit.ObjectID)
+	if err != nil {
+		return invalid, false
+	}
+
+	return searchItemResponse(hit.item(id), formatter, opts), true
+}
+
+// searchItemResponse converts a search result to an item response without its tree. Stories
+// include their metadata and comments include the text of the comment.
+func searchItemResponse(
+	item *hn.Item,
+	formatter *textFormatter,
+	opts storyListOptions,
+) handleItemDescendantsResponse {
+	var story *storyMetadata
+	if item.Type != "comment" {
+		story = newStoryMetadata(item)
+	}
+
+	by := item.By
+	if !opts.ShowUser {
+		by = ""
+	}
+
+	return handleItemDescendantsResponse{
+		storyMetadata:     story,
+		By:                by,
+		Text:              formatter.formatAs(item, opts.Text),
+		Children:          nil,
+		AccountAgeDays:    nil,
+		Karma:             nil,
+		Time:              item.Time,
+		ID:                item.ID,
+		Parent:            item.Parent,
+		Root:              0,
+		Depth:             0,
+		TruncatedChildren: 0,
+		OP:                false,
+		Dead:              item.Dead,
+		Deleted:           item.Deleted,
+		IsNew:             false,
+		NewUser:           false,
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// seenWatermarks holds the highest item IDs a client has already seen, per thread and overall.
+// HN assigns IDs in increasing order, so any higher ID is a newer item.
+type seenWatermarks struct {
+	ByRoot  map[int]int
+	Default int
+}
+
+type seenRequest struct {
+	SeenMaxIDs map[int]int `json:"seenMaxIds"`
+}
+
+// parseSeen reads the seen-max-id query parameter and, for POST requests, per-thread watermarks
+// from a JSON body like {"seenMaxIds": {"<root ID>": <max ID>}}, adding an error to errs for each
+// that is invalid.
+func parseSeen(c *gin.Context, errs *paramErrors) seenWatermarks {
+	seenMaxID, err := strconv.Atoi(c.DefaultQuery("seen-max-id", "0"))
+	if err != nil || seenMaxID < 0 {
+		errs.add(codeInvalidSeenMaxID, "seen-max-id", "invalid seen-max-id")
+	}
+
+	var req seenRequest
+
+	if c.Request.Method == http.MethodPost {
+		err = json.NewDecoder(c.Request.Body).Decode(&req)
+		if err != nil {
+			errs.add(codeInvalidBody, "body", "invalid request body")
+		}
+	}
+
+	return seenWatermarks{ByRoot: req.SeenMaxIDs, Default: seenMaxID}
+}
+
+// isNew reports whether item, at the given depth in the thread under rootID, is a comment newer
+// than the watermark for that thread.
+func (s seenWatermarks) isNew(rootID int, item *hn.Item, depth int) bool {
+	watermark, ok := s.ByRoot[rootID]
+	if !ok {
+		watermark = s.Default
+	}
+
+	return depth > 0 && watermark > 0 && item.ID > watermark
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+type commentSort int
+
+const (
+	commentSortRank commentSort = iota
+	commentSortTime
+	commentSortTimeDesc
+	commentSortLongest
+)
+
+// parseCommentSort reads the comment-sort query parameter: hn-rank (the default, the order HN
+// shows), time, time-desc, or longest-subthread.
+func parseCommentSort(c *gin.Context) (commentSort, bool) {
+	switch c.DefaultQuery("comment-sort", "hn-rank") {
+	case "hn-rank":
+		return commentSortRank, true
+	case "time":
+		return commentSortTime, true
+	case "time-desc":
+		return commentSortTimeDesc, true
+	case "longest-subthread":
+		return commentSortLongest, true
+	default:
+		return 0, false
+	}
+}
+
+// commentOrder returns the indexes of the entries of a flattened tree with the given items and
+// depths in the order they appear when the children of every item are sorted. Sorting is stable,
+// so ties keep the HN order.
+func commentOrder(items []*hn.Item, depths []int, sort commentSort) []int {
+	order := make([]int, 0, len(items))
+
+	if sort == commentSortRank {
+		for i := range items {
+			order = append(order, i)
+		}
+
+		return order
+	}
+
+	roots, children, size := commentTree(depths)
+
+	compare := func(a int, b int) int {
+		switch sort {
+		case commentSortTime:
+			return cmp.Compare(items[a].Time, items[b].Time)
+		case commentSortTimeDesc:
+			return cmp.Compare(items[b].Time, items[a].Time)
+		default:
+			return cmp.Compare(size[b], size[a])
+		}
+	}
+
+	var visit func(i int)
+
+	visit = func(i int) {
+		order = append(order, i)
+
+		slices.SortStableFunc(children[i], compare)
+
+		for _, child := range children[i] {
+			visit(child)
+		}
+	}
+
+	slices.SortStableFunc(roots, compare)
+
+	for _, root := range roots {
+		visit(root)
+	}
+
+	return order
+}
+
+// commentTree recovers the structure of a flattened tree from its depths, returning the indexes of
+// the entries without a parent, the children of each entry, and the number of descendants of each.
+func commentTree(depths []int) ([]int, [][]int, []int) {
+	var roots []int
+
+	children := make([][]int, len(depths))
+	size := make([]int, len(depths))
+	ancestors := make([]int, 0, len(depths))
+
+	for i, depth := range depths {
+		for len(ancestors) > 0 && depths[ancestors[len(ancestors)-1]] >= depth {
+			ancestors = ancestors[:len(ancestors)-1]
+		}
+
+		if len(ancestors) == 0 {
+			roots = append(roots, i)
+		} else {
+			parent := ancestors[len(ancestors)-1]
+			children[parent] = append(children[parent], i)
+		}
+
+		for _, a := range ancestors {
+			size[a]++
+		}
+
+		ancestors = append(ancestors, i)
+	}
+
+	return roots, children, size
+}
+
+// reorder returns the elements of s in the given order of indexes.
+func reorder[T any](s []T, order []int) []T {
+	result := make([]T, 0, len(order))
+	for _, i := range order {
+		result = append(result, s[i])
+	}
+
+	return result
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+func TestCommentOrder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		depths []int
+		times  []int64
+		want   []int
+		sort   commentSort
+	}{
+		{name: "empty", depths: []int{}, times: []int64{}, want: []int{}, sort: commentSortTime},
+		{
+			name:   "rank",
+			depths: []int{0, 1, 2, 1, 1},
+			times:  []int64{10, 30, 5, 20, 40},
+			want:   []int{0, 1, 2, 3, 4},
+			sort:   commentSortRank,
+		},
+		{
+			name:   "time",
+			depths: []int{0, 1, 2, 1, 1},
+			times:  []int64{10, 30, 5, 20, 40},
+			want:   []int{0, 3, 1, 2, 4},
+			sort:   commentSortTime,
+		},
+		{
+			name:   "time descending",
+			depths: []int{0, 1, 2, 1, 1},
+			times:  []int64{10, 30, 5, 20, 40},
+			want:   []int{0, 4, 1, 2, 3},
+			sort:   commentSortTimeDesc,
+		},
+		{
+			name:   "time ties keep rank",
+			depths: []int{0, 1, 1, 1},
+			times:  []int64{0, 5, 5, 1},
+			want:   []int{0, 3, 1, 2},
+			sort:   commentSortTime,
+		},
+		{
+			name:   "longest subthread",
+			depths: []int{0, 1, 1, 2, 2, 1, 2},
+			times:  []int64{0, 0, 0, 0, 0, 0, 0},
+			want:   []int{0, 2, 3, 4, 5, 6, 1},
+			sort:   commentSortLongest,
+		},
+		{
+			name:   "nested replies sorted",
+			depths: []int{0, 1, 2, 2},
+			times:  []int64{0, 1, 30, 20},
+			want:   []int{0, 1, 3, 2},
+			sort:   commentSortTime,
+		},
+		{
+			name:   "several roots",
+			depths: []int{1, 2, 1},
+			times:  []int64{20, 0, 10},
+			want:   []int{2, 0, 1},
+			sort:   commentSortTime,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			items := make([]*hn.Item, 0, len(tt.times))
+
+			for i, at := range tt.times {
+				item := treeItem(i+1, "", false, false)
+				item.Time = at
+				items = append(items, item)
+			}
+
+			if got := commentOrder(items, tt.depths, tt.sort); !slices.Equal(got, tt.want) {
+				t.Errorf("order %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
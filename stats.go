@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type storySample struct {
+	Time             int64 `json:"time"`
+	Score            int   `json:"score"`
+	Descendants      int   `json:"descendants"`
+	ActiveComments   int   `json:"activeComments"`
+	ActiveCommenters int   `json:"activeCommenters"`
+}
+
+type handleItemStatsResponse struct {
+	Samples []storySample `json:"samples"`
+	ID      int           `json:"id"`
+}
+
+// storySamples returns the score and activity of a story in each archived snapshot it was part
+// of, oldest first.
+func (s *store) storySamples(ctx context.Context, id int) ([]storySample, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT s.time, r.score, r.descendants, r.active_comments, r.active_commenters
+		FROM snapshot_roots r JOIN snapshots s ON s.id = r.snapshot
+		WHERE r.id = ?
+		ORDER BY s.time`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read samples: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	samples := make([]storySample, 0)
+
+	for rows.Next() {
+		var sample storySample
+
+		err = rows.Scan(&sample.Time, &sample.Score, &sample.Descendants,
+			&sample.ActiveComments, &sample.ActiveCommenters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sample: %w", err)
+		}
+
+		samples = append(samples, sample)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read samples: %w", err)
+	}
+
+	return samples, nil
+}
+
+// handleItemStats responds with the score and comment counts of a story sampled each time the
+// default /active set was precomputed while the story was in it.
+func handleItemStats(c *gin.Context, st *store) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondParamError(c, codeInvalidID, "id", "invalid id")
+		return
+	}
+
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	samples, err := st.storySamples(c.Request.Context(), itemID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read samples")
+		return
+	}
+
+	respond(c, http.StatusOK, handleItemStatsResponse{Samples: samples, ID: itemID})
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+var errNoStore = errors.New("no store")
+
+// store persists what the server derives from the items it sees in its own SQLite database, next
+// to the item cache of the HN client.
+type store struct {
+	db *sql.DB
+}
+
+// storeSchema creates the tables of the store if they do not exist yet. Full-text search needs
+// SQLite built with the sqlite_fts5 tag.
+func storeSchema() []string {
+	return []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS item_text USING fts5(title, text, tokenize = 'porter unicode61')`,
+		`CREATE TABLE IF NOT EXISTS items (
+			id INTEGER PRIMARY KEY,
+			parent INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			data TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS items_time ON items (time)`,
+		`CREATE TABLE IF NOT EXISTS snapshots (
+			id INTEGER PRIMARY KEY,
+			time INTEGER NOT NULL,
+			second_chance_failed INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS snapshots_time ON snapshots (time)`,
+		`CREATE TABLE IF NOT EXISTS snapshot_roots (
+			snapshot INTEGER NOT NULL REFERENCES snapshots (id),
+			id INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			title TEXT NOT NULL,
+			score INTEGER NOT NULL,
+			descendants INTEGER NOT NULL,
+			active_comments INTEGER NOT NULL,
+			active_commenters INTEGER NOT NULL,
+			PRIMARY KEY (snapshot, id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS snapshot_roots_id ON snapshot_roots (id)`,
+		`CREATE TABLE IF NOT EXISTS front_page_samples (time INTEGER PRIMARY KEY)`,
+		`CREATE TABLE IF NOT EXISTS front_page_ranks (
+			id INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			rank INTEGER NOT NULL,
+			score INTEGER NOT NULL,
+			second_chance INTEGER NOT NULL,
+			PRIMARY KEY (id, time)
+		)`,
+		`CREATE TABLE IF NOT EXISTS formatted_text (
+			key TEXT PRIMARY KEY,
+			html TEXT NOT NULL,
+			stored INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS subscriptions (
+			id TEXT PRIMARY KEY,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			keywords TEXT NOT NULL,
+			domains TEXT NOT NULL,
+			min_active_comments INTEGER NOT NULL,
+			created INTEGER NOT NULL,
+			last_delivered INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE TABLE IF NOT EXISTS watches (
+			id TEXT PRIMARY KEY,
+			kind TEXT NOT NULL,
+			value TEXT NOT NULL,
+			scope TEXT NOT NULL,
+			webhook_url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			created INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS watch_hits (
+			watch TEXT NOT NULL,
+			id INTEGER NOT NULL,
+			story INTEGER NOT NULL,
+			title TEXT NOT NULL,
+			by TEXT NOT NULL,
+			time INTEGER NOT NULL,
+			matched INTEGER NOT NULL,
+			PRIMARY KEY (watch, id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS digest_recipients (
+			id TEXT PRIMARY KEY,
+			email TEXT NOT NULL,
+			keywords TEXT NOT NULL,
+			min_active_comments INTEGER NOT NULL,
+			max_threads INTEGER NOT NULL,
+			created INTEGER NOT NULL,
+			last_sent INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE TABLE IF NOT EXISTS follows (
+			id TEXT PRIMARY KEY,
+			item INTEGER NOT NULL,
+			user TEXT NOT NULL,
+			webhook_url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			last_id INTEGER NOT NULL,
+			created INTEGER NOT NULL,
+			expires INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS follows_expires ON follows (expires)`,
+		`CREATE TABLE IF NOT EXISTS followed_users (
+			name TEXT PRIMARY KEY,
+			created INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS watch_hits_matched ON watch_hits (watch, matched)`,
+	}
+}
+
+// openStore opens the store at path, creating it if needed, or returns nil if path is empty.
+func openStore(ctx context.Context, path string) (*store, error) {
+	if path == "" {
+		return nil, nil //nolint:nilnil // no store is configured
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", path, err)
+	}
+
+	for _, statement := range storeSchema() {
+		_, err = db.ExecContext(ctx, statement)
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to create store schema: %w", err)
+		}
+	}
+
+	return &store{db: db}, nil
+}
+
+// closeStore closes st unless it is nil, logging any error since it is only called on shutdown.
+func closeStore(st *store) {
+	if st == nil {
+		return
+	}
+
+	err := st.db.Close()
+	if err != nil {
+		log.Printf("error closing store: %v", err)
+	}
+}
+
+// recordSnapshot persists what the store keeps about a freshly computed active snapshot. Errors
+// are logged rather than failing the computation.
+func (s *store) recordSnapshot(ctx context.Context, snapshot *activeSnapshot) {
+	items := snapshotItems(snapshot)
+
+	err := s.indexItems(ctx, items)
+	if err != nil {
+		log.Printf("failed to index active items: %v", err)
+	}
+
+	err = s.archiveItems(ctx, items)
+	if err != nil {
+		log.Printf("failed to archive active items: %v", err)
+	}
+}
+
+// snapshotItems returns the roots of a snapshot and all of the items in their trees.
+func snapshotItems(snapshot *activeSnapshot) []*hn.Item {
+	items := make([]*hn.Item, 0, len(snapshot.Roots))
+
+	for _, root := range snapshot.Roots {
+		items = append(items, root.Item)
+	}
+
+	for _, children := range snapshot.Tree {
+		for _, item := range children {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// storyMetadata describes a root item so a story row can be rendered without another request.
+type storyMetadata struct {
+	Type        string `json:"type"`
+	URL         string `json:"url,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	Score       int    `json:"score"`
+	Descendants int    `json:"descendants"`
+}
+
+func newStoryMetadata(item *hn.Item) *storyMetadata {
+	return &storyMetadata{
+		Type:        item.Type,
+		URL:         item.URL,
+		Domain:      storyDomain(item.URL),
+		Score:       item.Score,
+		Descendants: item.Descendants,
+	}
+}
+
+// storyDomain returns the host of a story URL without any leading "www.", or "" for text posts.
+func storyDomain(storyURL string) string {
+	if storyURL == "" {
+		return ""
+	}
+
+	u, err := url.Parse(storyURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// isOP reports whether item is a reply by the author of root.
+func isOP(item *hn.Item, root *hn.Item) bool {
+	return item.ID != root.ID && item.By != "" && item.By == root.By
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// subscription is a callback URL that is sent a webhook when a thread matching its filters enters
+// the default /active set. The secret signs the webhooks and is only returned when the
+// subscription is created.
+type subscription struct {
+	ID                string   `json:"id"`
+	URL               string   `json:"url"`
+	Secret            string   `json:"secret,omitempty"`
+	LastError         string   `json:"lastError,omitempty"`
+	Keywords          []string `json:"keywords"`
+	Domains           []string `json:"domains"`
+	MinActiveComments int      `json:"minActiveComments"`
+	Created           int64    `json:"created"`
+	LastDelivered     int64    `json:"lastDelivered,omitempty"`
+}
+
+// saveSubscription stores a new subscription.
+func (s *store) saveSubscription(ctx context.Context, sub subscription) error {
+	keywords, err := json.Marshal(sub.Keywords)
+	if err != nil {
+		return fmt.Errorf("failed to encode keywords: %w", err)
+	}
+
+	domains, err := json.Marshal(sub.Domains)
+	if err != nil {
+		return fmt.Errorf("failed to encode domains: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO subscriptions
+		(id, url, secret, keywords, domains, min_active_comments, created) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		sub.ID, sub.URL, sub.Secret, string(keywords), string(domains), sub.MinActiveComments, sub.Created)
+	if err != nil {
+		return fmt.Errorf("failed to save subscription: %w", err)
+	}
+
+	return nil
+}
+
+// subscriptions returns every subscription, oldest first, including their secrets.
+func (s *store) subscriptions(ctx context.Context) ([]subscription, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, url, secret, keywords, domains, min_active_comments,
+			created, last_delivered, last_error
+		FROM subscriptions ORDER BY created, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	subs := make([]subscription, 0)
+
+	for rows.Next() {
+		var (
+			sub      subscription
+			keywords string
+			domains  string
+		)
+
+		err = rows.Scan(&sub.ID, &sub.URL, &sub.Secret, &keywords, &domains, &sub.MinActiveComments,
+			&sub.Created, &sub.LastDelivered, &sub.LastError)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read subscription: %w", err)
+		}
+
+		err = errors.Join(json.Unmarshal([]byte(keywords), &sub.Keywords), json.Unmarshal([]byte(domains), &sub.Domains))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode subscription %s: %w", sub.ID, err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// deleteSubscription removes a subscription, reporting whether it existed.
+func (s *store) deleteSubscription(ctx context.Context, id string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM subscriptions WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete subscription %s: %w", id, err)
+	}
+
+	n, _ := result.RowsAffected()
+
+	return n > 0, nil
+}
+
+// recordDelivery stores when a webhook was last sent to a subscription and why it failed, if it
+// did.
+func (s *store) recordDelivery(ctx context.Context, id string, at time.Time, deliveryErr error) error {
+	lastError := ""
+	if deliveryErr != nil {
+		lastError = deliveryErr.Error()
+	}
+
+	_, err := s.db.ExecContext(ctx, `UPDATE subscriptions SET last_delivered = ?, last_error = ? WHERE id = ?`,
+		at.Unix(), lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery to %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// registerSubscriptions adds the endpoints managing webhook subscriptions, which need the admin
+// token since the server posts to whatever URL they name. Nothing is added without a token.
+func registerSubscriptions(r *gin.Engine, cfg config, st *store) {
+	if cfg.AdminToken == "" {
+		return
+	}
+
+	subs := r.Group("/subscriptions", requireAdminToken(cfg.AdminToken))
+	subs.POST("", func(c *gin.Context) { handleCreateSubscription(c, st) })
+	subs.GET("", func(c *gin.Context) { handleListSubscriptions(c, st) })
+	subs.DELETE("/:id", func(c *gin.Context) { handleDeleteSubscription(c, st) })
+}
+
+type subscriptionRequest struct {
+	URL               string   `json:"url"`
+	Keywords          []string `json:"keywords"`
+	Domains           []string `json:"domains"`
+	MinActiveComments int      `json:"minActiveComments"`
+}
+
+// handleCreateSubscription registers a callback URL with optional filters from a JSON body. A
+// thread matches when its title or URL contains any of the keywords, its domain is one of the
+// domains, and it has at least minActiveComments comments within the window; empty filters match
+// every thread.
+func handleCreateSubscription(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	var req subscriptionRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&req)
+	if err != nil || req.MinActiveComments < 0 {
+		respondError(c, http.StatusBadRequest, codeInvalidBody, "invalid request body")
+		return
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		respondParamError(c, codeInvalidURL, "url", "invalid url")
+		return
+	}
+
+	sub := subscription{
+		ID:                newRequestID(),
+		URL:               u.String(),
+		Secret:            newRequestID() + newRequestID(),
+		LastError:         "",
+		Keywords:          normalizeTerms(req.Keywords, ""),
+		Domains:           normalizeTerms(req.Domains, "www."),
+		MinActiveComments: req.MinActiveComments,
+		Created:           time.Now().Unix(),
+		LastDelivered:     0,
+	}
+
+	err = st.saveSubscription(c.Request.Context(), sub)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to save subscription")
+		return
+	}
+
+	respond(c, http.StatusCreated, sub)
+}
+
+// normalizeTerms lowercases terms and trims prefix and surrounding space from them, dropping the
+// empty ones. The result is never nil so it encodes as an empty list.
+func normalizeTerms(terms []string, prefix string) []string {
+	result := make([]string, 0, len(terms))
+
+	for _, term := range terms {
+		term = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(term)), prefix)
+		if term != "" {
+			result = append(result, term)
+		}
+	}
+
+	return result
+}
+
+type handleListSubscriptionsResponse struct {
+	Subscriptions []subscription `json:"subscriptions"`
+}
+
+// handleListSubscriptions responds with every subscription and how its last delivery went, without
+// the secrets.
+func handleListSubscriptions(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	subs, err := st.subscriptions(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read subscriptions")
+		return
+	}
+
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+
+	respond(c, http.StatusOK, handleListSubscriptionsResponse{Subscriptions: subs})
+}
+
+// handleDeleteSubscription stops the webhooks of a subscription.
+func handleDeleteSubscription(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	found, err := st.deleteSubscription(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to delete subscription")
+		return
+	}
+
+	if !found {
+		respondError(c, http.StatusNotFound, codeSubscriptionNotFound, "subscription not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/net/html"
+)
+
+type textMode int
+
+const (
+	textModeHTML textMode = iota
+	textModePlain
+	textModeMarkdown
+	textModeNone
+)
+
+// parseTextMode reads the text query parameter: html (the default), plain, or markdown. For
+// compatibility with the story lists, 1 means html and any other integer omits the text.
+func parseTextMode(c *gin.Context) (textMode, bool) {
+	param := c.DefaultQuery("text", "html")
+
+	switch param {
+	case "html":
+		return textModeHTML, true
+	case "plain":
+		return textModePlain, true
+	case "markdown":
+		return textModeMarkdown, true
+	}
+
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, false
+	}
+
+	if n == 1 {
+		return textModeHTML, true
+	}
+
+	return textModeNone, true
+}
+
+const (
+	// textCacheTTL is how long formatted text is kept in memory. Entries never go stale since they
+	// are keyed by content, so this only lets unused entries be evicted.
+	textCacheTTL = 24 * time.Hour
+	// textFlushInterval is how often newly formatted text is saved to the store.
+	textFlushInterval = 10 * time.Second
+	// textRetention is how long formatted text is kept in the store after it was last saved.
+	textRetention = 30 * 24 * time.Hour
+)
+
+// textFormatter formats and sanitizes the text of items, caching the HTML in memory and, when it
+// has a store, on disk so formatting survives restarts. The memory cache is loaded from the store
+// in bulk at startup, so formatting never waits on the store. Entries are keyed by item ID and a
+// hash of the item content and the sanitize policy, so edits and policy changes are never served
+// stale.
+type textFormatter struct {
+	cache       *lruCache[string, string]
+	policy      *bluemonday.Policy
+	store       *store
+	pending     map[string]string
+	fingerprint string
+	maxEntries  int
+	mu          sync.Mutex
+}
+
+func newTextFormatter(cfg config, st *store) *textFormatter {
+	return &textFormatter{
+		cache:       newLRUCache[string](cfg.TextCacheEntries, cfg.TextCacheBytes, textSize),
+		policy:      newSanitizePolicy(cfg),
+		store:       st,
+		pending:     make(map[string]string),
+		fingerprint: sanitizeFingerprint(cfg),
+		maxEntries:  cfg.TextCacheEntries,
+		mu:          sync.Mutex{},
+	}
+}
+
+func textSize(text string) int64 {
+	return int64(len(text))
+}
+
+// Stats returns the size and hit rate of the in-memory cache of formatted text.
+func (f *textFormatter) Stats() lruStats {
+	return f.cache.Stats()
+}
+
+// format returns the text of an item as HTML that only uses the elements, attributes, and URL
+// schemes allowed by the sanitize policy.
+func (f *textFormatter) format(item *hn.Item) string {
+	key := f.key(item)
+
+	text, ok := f.cache.Get(key)
+	if ok {
+		return text
+	}
+
+	text = f.policy.Sanitize(unl.PrettyFormatTitle(item, true))
+	f.cache.Put(key, text, textCacheTTL)
+
+	if f.store != nil {
+		f.mu.Lock()
+		f.pending[key] = text
+		f.mu.Unlock()
+	}
+
+	return text
+}
+
+// key identifies the formatted text of an item by its ID and a hash of everything that affects
+// the formatting.
+func (f *textFormatter) key(item *hn.Item) string {
+	h := fnv.New64a()
+
+	for _, part := range []string{
+		f.fingerprint, item.Type, item.Title, item.URL, item.Text,
+		strconv.FormatBool(item.Dead), strconv.FormatBool(item.Deleted),
+	} {
+		_, _ = h.Write([]byte(part))
+		_, _ = h.Write([]byte{0})
+	}
+
+	return strconv.Itoa(item.ID) + ":" + strconv.FormatUint(h.Sum64(), 36)
+}
+
+// Run loads the most recently saved text from the store into memory, then saves newly formatted
+// text to the store every interval until ctx is done, and finally saves what is left. It returns
+// immediately if the formatter has no store.
+func (f *textFormatter) Run(ctx context.Context, interval time.Duration) {
+	if f.store == nil {
+		return
+	}
+
+	err := f.store.recentFormattedText(ctx, f.maxEntries, func(key, text string) {
+		f.cache.Put(key, text, textCacheTTL)
+	})
+	if err != nil {
+		log.Printf("failed to load formatted text: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			f.flush(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			f.flush(ctx)
+		}
+	}
+}
+
+func (f *textFormatter) flush(ctx context.Context) {
+	f.mu.Lock()
+	pending := f.pending
+	f.pending = make(map[string]string)
+	f.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	err := f.store.saveFormattedText(ctx, pending)
+	if err != nil {
+		log.Printf("failed to save formatted text: %v", err)
+	}
+}
+
+// recentFormattedText calls fn with up to limit of the most recently saved formatted texts, oldest
+// first.
+func (s *store) recentFormattedText(ctx context.Context, limit int, fn func(key, text string)) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT key, html FROM
+		(SELECT key, html, stored FROM formatted_text ORDER BY stored DESC LIMIT ?)
+		ORDER BY stored`, limit)
+	if err != nil {
+		return fmt.Errorf("failed to read formatted text: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var key, html string
+
+		err = rows.Scan(&key, &html)
+		if err != nil {
+			return fmt.Errorf("failed to read formatted text: %w", err)
+		}
+
+		fn(key, html)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read formatted text: %w", err)
+	}
+
+	return nil
+}
+
+// saveFormattedText stores formatted text by key and removes text not saved within the retention
+// period, which belongs to items that were edited or are no longer requested.
+func (s *store) saveFormattedText(ctx context.Context, texts map[string]string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin saving formatted text: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+
+	for key, html := range texts {
+		_, err = tx.ExecContext(ctx, `INSERT INTO formatted_text (key, html, stored) VALUES (?, ?, ?)
+			ON CONFLICT (key) DO UPDATE SET html = excluded.html, stored = excluded.stored`,
+			key, html, now.Unix())
+		if err != nil {
+			return fmt.Errorf("failed to save formatted text: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM formatted_text WHERE stored < ?`, now.Add(-textRetention).Unix())
+	if err != nil {
+		return fmt.Errorf("failed to prune formatted text: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit formatted text: %w", err)
+	}
+
+	return nil
+}
+
+// formatAs formats the text of an item like format and converts it to the given mode.
+func (f *textFormatter) formatAs(item *hn.Item, mode textMode) string {
+	if mode == textModeNone {
+		return ""
+	}
+
+	return convertText(f.format(item), mode)
+}
+
+// convertText converts the HTML subset used by HN (p, i, pre, code, and a) to plain text or
+// Markdown, decoding entities along the way.
+func convertText(s string, mode textMode) string {
+	if mode != textModePlain && mode != textModeMarkdown {
+		return s
+	}
+
+	conv := textConverter{
+		linkURL:  "",
+		b:        strings.Builder{},
+		linkText: strings.Builder{},
+		mode:     mode,
+		inLink:   false,
+		inPre:    false,
+	}
+
+	z := html.NewTokenizer(strings.NewReader(s))
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF { //nolint:errorlint // the tokenizer returns io.EOF unwrapped
+				return s
+			}
+
+			break
+		}
+
+		tok := z.Token()
+
+		//nolint:exhaustive // comments and doctypes are dropped
+		switch tt {
+		case html.TextToken:
+			conv.text(tok.Data)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			conv.start(tok)
+		case html.EndTagToken:
+			conv.end(tok)
+		}
+	}
+
+	conv.endLink()
+
+	return strings.TrimSpace(conv.b.String())
+}
+
+type textConverter struct {
+	linkURL  string
+	b        strings.Builder
+	linkText strings.Builder
+	mode     textMode
+	inLink   bool
+	inPre    bool
+}
+
+func (t *textConverter) text(s string) {
+	switch {
+	case t.inLink:
+		t.linkText.WriteString(s)
+	case t.mode == textModeMarkdown && !t.inPre:
+		t.b.WriteString(escapeMarkdown(s))
+	default:
+		t.b.WriteString(s)
+	}
+}
+
+func (t *textConverter) start(tok html.Token) {
+	switch tok.Data {
+	case "p":
+		writeParagraphBreak(&t.b)
+	case "i", "em":
+		t.markdown("*")
+	case "pre":
+		t.inPre = true
+
+		writeParagraphBreak(&t.b)
+		t.markdown("```\n")
+	case "a":
+		t.endLink()
+
+		t.inLink = true
+		t.linkURL = attr(tok, "href")
+		t.linkText.Reset()
+	}
+}
+
+func (t *textConverter) end(tok html.Token) {
+	switch tok.Data {
+	case "i", "em":
+		t.markdown("*")
+	case "pre":
+		t.inPre = false
+
+		if t.mode == textModeMarkdown && !strings.HasSuffix(t.b.String(), "\n") {
+			t.b.WriteString("\n")
+		}
+
+		t.markdown("```")
+		writeParagraphBreak(&t.b)
+	case "a":
+		t.endLink()
+	}
+}
+
+func (t *textConverter) markdown(s string) {
+	if t.mode == textModeMarkdown {
+		t.b.WriteString(s)
+	}
+}
+
+func (t *textConverter) endLink() {
+	if t.inLink {
+		t.b.WriteString(formatLink(t.linkText.String(), t.linkURL, t.mode))
+		t.inLink = false
+	}
+}
+
+// writeParagraphBreak ends the text so far with a blank line unless it is empty or already does.
+func writeParagraphBreak(b *strings.Builder) {
+	s := b.String()
+
+	switch {
+	case s == "" || strings.HasSuffix(s, "\n\n"):
+	case strings.HasSuffix(s, "\n"):
+		b.WriteString("\n")
+	default:
+		b.WriteString("\n\n")
+	}
+}
+
+func attr(tok html.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+// formatLink renders a link. HN shortens long link text to a prefix of the URL ending in "...",
+// in which case only the full URL is kept.
+func formatLink(text string, href string, mode textMode) string {
+	if href == "" {
+		return text
+	}
+
+	prefix, shortened := strings.CutSuffix(text, "...")
+	if text == href || shortened && strings.HasPrefix(href, prefix) {
+		text = ""
+	}
+
+	if mode == textModeMarkdown {
+		if text == "" {
+			return "<" + href + ">"
+		}
+
+		return "[" + escapeMarkdown(text) + "](" + href + ")"
+	}
+
+	if text == "" {
+		return href
+	}
+
+	return text + " (" + href + ")"
+}
+
+func escapeMarkdown(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		if strings.ContainsRune("\\`*_[]<>", r) {
+			b.WriteRune('\\')
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package main
+
+import "testing"
+
+func TestConvertText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		html     string
+		plain    string
+		markdown string
+	}{
+		{
+			name:     "empty",
+			html:     "",
+			plain:    "",
+			markdown: "",
+		},
+		{
+			name:     "paragraphs",
+			html:     "first<p>second<p>third",
+			plain:    "first\n\nsecond\n\nthird",
+			markdown: "first\n\nsecond\n\nthird",
+		},
+		{
+			name:     "leading paragraph",
+			html:     "<p>only",
+			plain:    "only",
+			markdown: "only",
+		},
+		{
+			name:     "entities",
+			html:     "a &lt;b&gt; &amp; &#x27;c&#x27; &quot;d&quot;",
+			plain:    `a <b> & 'c' "d"`,
+			markdown: `a \<b\> & 'c' "d"`,
+		},
+		{
+			name:     "italics",
+			html:     "an <i>important</i> point",
+			plain:    "an important point",
+			markdown: "an *important* point",
+		},
+		{
+			name:     "markdown characters",
+			html:     "snake_case * [x] `y` \\",
+			plain:    "snake_case * [x] `y` \\",
+			markdown: "snake\\_case \\* \\[x\\] \\`y\\` \\\\",
+		},
+		{
+			name:     "code block",
+			html:     "before<p><pre><code>  x := a_b *c\n</code></pre>after",
+			plain:    "before\n\n  x := a_b *c\n\nafter",
+			markdown: "before\n\n```\n  x := a_b *c\n```\n\nafter",
+		},
+		{
+			name:     "code block without newline",
+			html:     "<pre><code>x</code></pre>",
+			plain:    "x",
+			markdown: "```\nx\n```",
+		},
+		{
+			name:     "link",
+			html:     `see <a href="https://example.com/a_b" rel="nofollow">the docs</a>`,
+			plain:    "see the docs (https://example.com/a_b)",
+			markdown: "see [the docs](https://example.com/a_b)",
+		},
+		{
+			name:     "link text is the url",
+			html:     `<a href="https://example.com/">https://example.com/</a>`,
+			plain:    "https://example.com/",
+			markdown: "<https://example.com/>",
+		},
+		{
+			name:     "shortened link text",
+			html:     `<a href="https://example.com/a/very/long/path">https://example.com/a/very/...</a>`,
+			plain:    "https://example.com/a/very/long/path",
+			markdown: "<https://example.com/a/very/long/path>",
+		},
+		{
+			name:     "shortened text of another url",
+			html:     `<a href="https://example.org/">https://example.com/...</a>`,
+			plain:    "https://example.com/... (https://example.org/)",
+			markdown: "[https://example.com/...](https://example.org/)",
+		},
+		{
+			name:     "link without href",
+			html:     "<a>text</a>",
+			plain:    "text",
+			markdown: "text",
+		},
+		{
+			name:     "unclosed link",
+			html:     `<a href="https://example.com/">text`,
+			plain:    "text (https://example.com/)",
+			markdown: "[text](https://example.com/)",
+		},
+		{
+			name:     "comments are dropped",
+			html:     "a<!-- hidden -->b",
+			plain:    "ab",
+			markdown: "ab",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := convertText(tt.html, textModePlain); got != tt.plain {
+				t.Errorf("plain %q, want %q", got, tt.plain)
+			}
+
+			if got := convertText(tt.html, textModeMarkdown); got != tt.markdown {
+				t.Errorf("markdown %q, want %q", got, tt.markdown)
+			}
+
+			if got := convertText(tt.html, textModeHTML); got != tt.html {
+				t.Errorf("html %q, want it unchanged", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+type timeFormat int
+
+const (
+	timeFormatPretty timeFormat = 1 << iota
+	timeFormatUnix
+	timeFormatISO
+
+	timeFormatAll = timeFormatPretty | timeFormatUnix | timeFormatISO
+)
+
+// parseTimeFormat reads the time-format query parameter: pretty (the default), unix, iso, or all.
+func parseTimeFormat(c *gin.Context) (timeFormat, bool) {
+	switch c.DefaultQuery("time-format", "pretty") {
+	case "pretty":
+		return timeFormatPretty, true
+	case "unix":
+		return timeFormatUnix, true
+	case "iso":
+		return timeFormatISO, true
+	case "all":
+		return timeFormatAll, true
+	default:
+		return 0, false
+	}
+}
+
+// format returns the pretty age, Unix time, and RFC 3339 time of t relative to now, leaving each
+// representation that was not requested empty.
+func (f timeFormat) format(now time.Time, t int64) (string, int64, string) {
+	var (
+		age  string
+		unix int64
+		iso  string
+	)
+
+	if f&timeFormatPretty != 0 {
+		age = unl.PrettyFormatDuration(now.Sub(time.Unix(t, 0)))
+	}
+
+	if f&timeFormatUnix != 0 {
+		unix = t
+	}
+
+	if f&timeFormatISO != 0 {
+		iso = time.Unix(t, 0).UTC().Format(time.RFC3339)
+	}
+
+	return age, unix, iso
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutKey is the gin context key for the time limit of the request.
+const timeoutKey = "timeout"
+
+// limitDuration cancels the context of each request once it has run for the configured maximum
+// duration, or for the shorter duration in its timeout query parameter. WebSocket connections and
+// server-sent event streams are not limited since they are meant to stay open.
+func limitDuration(live *liveConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		maxDuration := live.Load().MaxRequestDuration
+		if maxDuration <= 0 || c.IsWebsocket() || strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+			c.Next()
+			return
+		}
+
+		timeout := maxDuration
+
+		if param := c.Query("timeout"); param != "" {
+			d, err := parsePositiveDuration(param, time.Millisecond)
+			if err != nil {
+				respondParamError(c, codeInvalidTimeout, "timeout", "invalid timeout")
+				return
+			}
+
+			timeout = min(d, maxDuration)
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Set(timeoutKey, timeout)
+
+		c.Next()
+	}
+}
+
+// timedOut reports whether the request ran out of time, and the time it had.
+func timedOut(c *gin.Context) (time.Duration, bool) {
+	if !errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		return 0, false
+	}
+
+	timeout, _ := c.Get(timeoutKey)
+	d, _ := timeout.(time.Duration)
+
+	return d, true
+}
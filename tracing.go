@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName  = "github.com/jasonthorsness/unlurker-web/backend"
+	serviceName = "unlurker-web"
+)
+
+// setupTracing exports spans to the OTLP/HTTP collector at endpoint, such as
+// http://localhost:4318, and returns a function that flushes and stops the export. With an empty
+// endpoint spans are not recorded.
+func setupTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// traceRequests starts a span for each request named after its route, continuing the trace of the
+// caller if it sent a traceparent header.
+func traceRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx, span := otel.Tracer(tracerName).Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+			))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// startSpan starts a span for a step of handling a request.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends a span, marking it as failed if err is not nil. Running out of time is recorded
+// but is not a failure of the step itself.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+
+		if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	span.End()
+}
+
+// fetchFrontPageTimes is unl.FetchFrontPageTimes in a span, retried as the client retries requests
+// to the HN API since the scrape does not go through its HTTP client.
+func fetchFrontPageTimes(ctx context.Context, client *itemClient, now time.Time) (map[int]int64, error) {
+	ctx, span := startSpan(ctx, "FetchFrontPageTimes")
+
+	times, err := retryFetch(ctx, client.retries, func(ctx context.Context) (map[int]int64, error) {
+		return unl.FetchFrontPageTimes(ctx, now) //nolint:wrapcheck // plain wrapper
+	})
+	endSpan(span, err)
+
+	return times, err //nolint:wrapcheck // plain wrapper
+}
+
+// fetchItems is client.GetItems in a span, with the items that changed since they were cached
+// fetched again.
+func fetchItems(ctx context.Context, client *itemClient, ids []int) (hn.ItemSet, error) {
+	ctx, span := startSpan(ctx, "GetItems", attribute.Int("hn.items.requested", len(ids)))
+
+	items, err := client.GetItems(ctx, ids)
+	if err == nil {
+		client.refresh(ctx, items)
+	}
+
+	span.SetAttributes(attribute.Int("hn.items.returned", len(items)))
+	endSpan(span, err)
+
+	return items, err //nolint:wrapcheck // plain wrapper
+}
+
+// fetchDescendants is client.GetDescendants in a span, with the descendants that changed since
+// they were cached fetched again along with any replies they gained. The roots are expected to be
+// up to date already, as fetchItems returns them.
+func fetchDescendants(ctx context.Context, client *itemClient, items hn.ItemSet) (hn.ItemSet, error) {
+	ctx, span := startSpan(ctx, "GetDescendants", attribute.Int("hn.items.roots", len(items)))
+
+	all, err := client.GetDescendants(ctx, items)
+	if err == nil {
+		_, err = client.withChanges(ctx, all, items)
+	}
+
+	span.SetAttributes(attribute.Int("hn.items.returned", len(all)))
+	endSpan(span, err)
+
+	return all, err //nolint:wrapcheck // plain wrapper
+}
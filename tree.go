@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+var (
+	errItemNotFound = errors.New("item not found")
+	errUpstream     = errors.New("failed to retrieve from HN")
+)
+
+// getItem retrieves a single item, returning errItemNotFound if it does not exist or was deleted
+// and an error wrapping errUpstream if HN could not be reached.
+func getItem(ctx context.Context, client *itemClient, id int) (hn.ItemSet, *hn.Item, error) {
+	items, err := fetchItems(ctx, client, []int{id})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errUpstream, err)
+	}
+
+	item, ok := items[id]
+	if !ok || item == nil || item.Deleted {
+		return nil, nil, errItemNotFound
+	}
+
+	return items, item, nil
+}
+
+// treeEntry is an item of a flattened tree and its depth below the root of the tree.
+type treeEntry struct {
+	Item  *hn.Item
+	Depth int
+}
+
+// getTree returns the item and all of its descendants in display order.
+func getTree(ctx context.Context, client *itemClient, item *hn.Item) ([]treeEntry, error) {
+	items, err := fetchItems(ctx, client, []int{item.ID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUpstream, err)
+	}
+
+	all, err := fetchDescendants(ctx, client, items)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUpstream, err)
+	}
+
+	allByParent, _, err := all.GroupByParent()
+	if err != nil {
+		return nil, err
+	}
+
+	flat := unl.FlattenTree(item, allByParent)
+	entries := make([]treeEntry, 0, len(flat))
+
+	for _, f := range flat {
+		entries = append(entries, treeEntry{Item: f.Item, Depth: f.Depth})
+	}
+
+	return entries, nil
+}
+
+// respondItemError responds to an error from getItem with 404 or 502 as appropriate.
+func respondItemError(c *gin.Context, err error) {
+	if errors.Is(err, errItemNotFound) {
+		respondError(c, http.StatusNotFound, codeItemNotFound, "item not found")
+		return
+	}
+
+	respondError(c, http.StatusBadGateway, codeHNUpstreamError, "failed to retrieve item")
+}
+
+// parseMaxDepth reads the optional max-depth query parameter. A negative result means no limit.
+func parseMaxDepth(c *gin.Context) (int, bool) {
+	param, ok := c.GetQuery("max-depth")
+	if !ok {
+		return -1, true
+	}
+
+	maxDepth, err := strconv.Atoi(param)
+	if err != nil || maxDepth < 0 {
+		return 0, false
+	}
+
+	return maxDepth, true
+}
+
+// truncateDepth reports which entries of a flattened tree with the given depths are kept when
+// subtrees deeper than maxDepth are removed, along with the number of direct children removed
+// below each kept entry. A negative maxDepth keeps everything.
+func truncateDepth(depths []int, maxDepth int) ([]bool, []int) {
+	keep := make([]bool, len(depths))
+	truncated := make([]int, len(depths))
+	last := -1
+
+	for i, depth := range depths {
+		switch {
+		case maxDepth < 0 || depth < maxDepth:
+			keep[i] = true
+		case depth == maxDepth:
+			keep[i] = true
+			last = i
+		case depth == maxDepth+1 && last >= 0:
+			truncated[last]++
+		}
+	}
+
+	return keep, truncated
+}
+
+// parseShowDead reads the show-dead query parameter, which is false by default like showdead on
+// HN.
+func parseShowDead(c *gin.Context) (bool, bool) {
+	showDead, err := strconv.ParseBool(c.DefaultQuery("show-dead", "false"))
+	return showDead, err == nil
+}
+
+// parseShowUser reads the show-user query parameter, which is true by default and accepts the
+// values of strconv.ParseBool like true, false, 1, and 0.
+func parseShowUser(c *gin.Context) (bool, bool) {
+	showUser, err := strconv.ParseBool(c.DefaultQuery("show-user", "true"))
+	return showUser, err == nil
+}
+
+// parseBlocked reads the block query parameter, a comma-separated list of authors whose comments
+// are removed. Without it the configured default list applies; an empty value blocks nobody.
+func parseBlocked(c *gin.Context, defaultBlock []string) map[string]bool {
+	names := defaultBlock
+
+	param, ok := c.GetQuery("block")
+	if ok {
+		names = splitList(param)
+	}
+
+	return blockedSet(names)
+}
+
+// blockedSet returns the set of the blocked authors.
+func blockedSet(names []string) map[string]bool {
+	blocked := make(map[string]bool, len(names))
+	for _, name := range names {
+		blocked[name] = true
+	}
+
+	return blocked
+}
+
+// visibleItems reports which entries of a flattened tree with the given items and depths are
+// kept after removing the comments of blocked authors along with the replies to them, and hiding
+// dead items with hideDead, and the IDs of the placeholders among them.
+func visibleItems(
+	items []*hn.Item,
+	depths []int,
+	showDead bool,
+	blocked map[string]bool,
+) ([]bool, map[int]bool) {
+	unblocked := pruneBlocked(items, depths, blocked)
+	keep, placeholders := hideDead(keepOnly(items, unblocked), keepOnly(depths, unblocked), showDead)
+
+	visible := make([]bool, len(items))
+	k := 0
+
+	for i := range items {
+		if unblocked[i] {
+			visible[i] = keep[k]
+			k++
+		}
+	}
+
+	return visible, placeholders
+}
+
+// pruneBlocked reports which entries of a flattened tree are kept when the items below depth 0 by
+// blocked authors are removed with their subtrees.
+func pruneBlocked(items []*hn.Item, depths []int, blocked map[string]bool) []bool {
+	keep := make([]bool, len(items))
+	prunedDepth := -1
+
+	for i, item := range items {
+		if prunedDepth >= 0 && depths[i] > prunedDepth {
+			continue
+		}
+
+		prunedDepth = -1
+
+		if depths[i] > 0 && blocked[item.By] {
+			prunedDepth = depths[i]
+			continue
+		}
+
+		keep[i] = true
+	}
+
+	return keep
+}
+
+// hideDead reports which entries of a flattened tree with the given items and depths are kept
+// when dead and deleted items are hidden, and the IDs of the placeholders among them: hidden items
+// kept only because they have visible replies, which are shown without their author or text. Items
+// at depth 0 are never hidden, and nothing is hidden when showDead is set.
+func hideDead(items []*hn.Item, depths []int, showDead bool) ([]bool, map[int]bool) {
+	keep := make([]bool, len(items))
+	placeholders := make(map[int]bool)
+	ancestors := make([]int, 0, len(items))
+
+	for i, item := range items {
+		for len(ancestors) > 0 && depths[ancestors[len(ancestors)-1]] >= depths[i] {
+			ancestors = ancestors[:len(ancestors)-1]
+		}
+
+		if !showDead && depths[i] > 0 && (item.Dead || item.Deleted) {
+			placeholders[item.ID] = true
+		} else {
+			keep[i] = true
+
+			for j := len(ancestors) - 1; j >= 0 && !keep[ancestors[j]]; j-- {
+				keep[ancestors[j]] = true
+			}
+		}
+
+		ancestors = append(ancestors, i)
+	}
+
+	return keep, placeholders
+}
+
+// keepOnly returns the elements of s for which keep is true.
+func keepOnly[T any](s []T, keep []bool) []T {
+	kept := make([]T, 0, len(s))
+
+	for i, v := range s {
+		if keep[i] {
+			kept = append(kept, v)
+		}
+	}
+
+	return kept
+}
+
+// parseNested reads the shape query parameter, which is either "flat" (the default) or "nested".
+func parseNested(c *gin.Context) (bool, bool) {
+	switch c.DefaultQuery("shape", "flat") {
+	case "flat":
+		return false, true
+	case "nested":
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// nestByDepth converts items flattened in depth-first order into a forest, appending each item to
+// the children of the nearest preceding item one level shallower. Items without such an ancestor
+// in the list, for example at the start of a page, become roots.
+func nestByDepth[T any](items []*T, depth func(*T) int, children func(*T) *[]*T) []*T {
+	var roots []*T
+
+	stack := make([]*T, 0, len(items))
+
+	for _, item := range items {
+		d := depth(item)
+
+		for len(stack) > 0 && depth(stack[len(stack)-1]) >= d {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			roots = append(roots, item)
+		} else {
+			parent := children(stack[len(stack)-1])
+			*parent = append(*parent, item)
+		}
+
+		stack = append(stack, item)
+	}
+
+	return roots
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+// nestNode is a tree entry for the nestByDepth tests.
+type nestNode struct {
+	children []*nestNode
+	id       int
+	depth    int
+}
+
+// nestString renders a forest of nestNodes as IDs with the children of each in parentheses.
+func nestString(nodes []*nestNode) string {
+	parts := make([]string, 0, len(nodes))
+
+	for _, node := range nodes {
+		part := strconv.Itoa(node.id)
+		if len(node.children) > 0 {
+			part += "(" + nestString(node.children) + ")"
+		}
+
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func TestNestByDepth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		want   string
+		depths []int
+	}{
+		{name: "empty", want: "", depths: []int{}},
+		{name: "single", want: "1", depths: []int{0}},
+		{name: "chain", want: "1(2(3))", depths: []int{0, 1, 2}},
+		{name: "siblings", want: "1(2 3 4)", depths: []int{0, 1, 1, 1}},
+		{name: "back up", want: "1(2(3(4)) 5(6))", depths: []int{0, 1, 2, 3, 1, 2}},
+		{name: "several roots", want: "1(2) 3", depths: []int{0, 1, 0}},
+		{name: "page starting deep", want: "1 2 3(4)", depths: []int{3, 2, 1, 2}},
+		{name: "skipped level", want: "1(2 3)", depths: []int{0, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes := make([]*nestNode, 0, len(tt.depths))
+			for i, depth := range tt.depths {
+				nodes = append(nodes, &nestNode{children: nil, id: i + 1, depth: depth})
+			}
+
+			roots := nestByDepth(
+				nodes,
+				func(n *nestNode) int { return n.depth },
+				func(n *nestNode) *[]*nestNode { return &n.children },
+			)
+
+			if got := nestString(roots); got != tt.want {
+				t.Errorf("forest %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateDepth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		depths    []int
+		keep      []bool
+		truncated []int
+		maxDepth  int
+	}{
+		{name: "empty", depths: []int{}, keep: []bool{}, truncated: []int{}, maxDepth: 1},
+		{
+			name:      "no limit",
+			depths:    []int{0, 1, 2, 1},
+			keep:      []bool{true, true, true, true},
+			truncated: []int{0, 0, 0, 0},
+			maxDepth:  -1,
+		},
+		{
+			name:      "root only",
+			depths:    []int{0, 1, 2, 1},
+			keep:      []bool{true, false, false, false},
+			truncated: []int{2, 0, 0, 0},
+			maxDepth:  0,
+		},
+		{
+			name:      "direct children counted",
+			depths:    []int{0, 1, 2, 3, 2, 1, 2},
+			keep:      []bool{true, true, false, false, false, true, false},
+			truncated: []int{0, 2, 0, 0, 0, 1, 0},
+			maxDepth:  1,
+		},
+		{
+			name:      "shallower than limit",
+			depths:    []int{0, 1, 1},
+			keep:      []bool{true, true, true},
+			truncated: []int{0, 0, 0},
+			maxDepth:  5,
+		},
+		{
+			name:      "starting below limit",
+			depths:    []int{3, 2, 1, 2},
+			keep:      []bool{false, false, true, false},
+			truncated: []int{0, 0, 1, 0},
+			maxDepth:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			keep, truncated := truncateDepth(tt.depths, tt.maxDepth)
+			if !slices.Equal(keep, tt.keep) {
+				t.Errorf("keep %v, want %v", keep, tt.keep)
+			}
+
+			if !slices.Equal(truncated, tt.truncated) {
+				t.Errorf("truncated %v, want %v", truncated, tt.truncated)
+			}
+		})
+	}
+}
+
+// treeItem is a comment for the tree tests.
+func treeItem(id int, by string, dead bool, deleted bool) *hn.Item {
+	return &hn.Item{
+		ID:          id,
+		Deleted:     deleted,
+		Type:        "comment",
+		By:          by,
+		Time:        0,
+		Text:        "",
+		Dead:        dead,
+		Parent:      0,
+		Poll:        0,
+		Kids:        nil,
+		URL:         "",
+		Score:       0,
+		Title:       "",
+		Parts:       nil,
+		Descendants: 0,
+	}
+}
+
+func TestVisibleItems(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		placeholders map[int]bool
+		blocked      map[string]bool
+		name         string
+		items        []*hn.Item
+		depths       []int
+		visible      []bool
+		showDead     bool
+	}{
+		{
+			placeholders: map[int]bool{},
+			blocked:      nil,
+			name:         "all visible",
+			items:        []*hn.Item{treeItem(1, "a", false, false), treeItem(2, "b", false, false)},
+			depths:       []int{0, 1},
+			visible:      []bool{true, true},
+			showDead:     false,
+		},
+		{
+			placeholders: map[int]bool{},
+			blocked:      nil,
+			name:         "dead root",
+			items:        []*hn.Item{treeItem(1, "a", true, false)},
+			depths:       []int{0},
+			visible:      []bool{true},
+			showDead:     false,
+		},
+		{
+			placeholders: map[int]bool{2: true, 3: true},
+			blocked:      nil,
+			name:         "dead and deleted leaves",
+			items: []*hn.Item{
+				treeItem(1, "a", false, false),
+				treeItem(2, "b", true, false),
+				treeItem(3, "", false, true),
+			},
+			depths:   []int{0, 1, 1},
+			visible:  []bool{true, false, false},
+			showDead: false,
+		},
+		{
+			placeholders: map[int]bool{2: true, 3: true},
+			blocked:      nil,
+			name:         "dead with visible reply",
+			items: []*hn.Item{
+				treeItem(1, "a", false, false),
+				treeItem(2, "b", true, false),
+				treeItem(3, "", false, true),
+				treeItem(4, "c", false, false),
+			},
+			depths:   []int{0, 1, 2, 3},
+			visible:  []bool{true, true, true, true},
+			showDead: false,
+		},
+		{
+			placeholders: map[int]bool{},
+			blocked:      nil,
+			name:         "show dead",
+			items: []*hn.Item{
+				treeItem(1, "a", false, false),
+				treeItem(2, "b", true, false),
+				treeItem(3, "", false, true),
+			},
+			depths:   []int{0, 1, 1},
+			visible:  []bool{true, true, true},
+			showDead: true,
+		},
+		{
+			placeholders: map[int]bool{},
+			blocked:      map[string]bool{"troll": true},
+			name:         "blocked with replies",
+			items: []*hn.Item{
+				treeItem(1, "a", false, false),
+				treeItem(2, "troll", false, false),
+				treeItem(3, "b", false, false),
+				treeItem(4, "b", false, false),
+			},
+			depths:   []int{0, 1, 2, 1},
+			visible:  []bool{true, false, false, true},
+			showDead: false,
+		},
+		{
+			placeholders: map[int]bool{},
+			blocked:      map[string]bool{"troll": true},
+			name:         "blocked root",
+			items:        []*hn.Item{treeItem(1, "troll", false, false), treeItem(2, "a", false, false)},
+			depths:       []int{0, 1},
+			visible:      []bool{true, true},
+			showDead:     false,
+		},
+		{
+			placeholders: map[int]bool{2: true},
+			blocked:      map[string]bool{"troll": true},
+			name:         "dead with only blocked replies",
+			items: []*hn.Item{
+				treeItem(1, "a", false, false),
+				treeItem(2, "b", true, false),
+				treeItem(3, "troll", false, false),
+			},
+			depths:   []int{0, 1, 2},
+			visible:  []bool{true, false, false},
+			showDead: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			visible, placeholders := visibleItems(tt.items, tt.depths, tt.showDead, tt.blocked)
+			if !slices.Equal(visible, tt.visible) {
+				t.Errorf("visible %v, want %v", visible, tt.visible)
+			}
+
+			if !maps.Equal(placeholders, tt.placeholders) {
+				t.Errorf("placeholders %v, want %v", placeholders, tt.placeholders)
+			}
+		})
+	}
+}
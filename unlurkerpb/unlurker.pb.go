@@ -0,0 +1,850 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.1
+// 	protoc        v5.27.1
+// source: unlurker.proto
+
+package unlurkerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ActiveRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Window   string `protobuf:"bytes,1,opt,name=window,proto3" json:"window,omitempty"`
+	MaxAge   string `protobuf:"bytes,2,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"`
+	MinBy    *int32 `protobuf:"varint,3,opt,name=min_by,json=minBy,proto3,oneof" json:"min_by,omitempty"`
+	MaxDepth *int32 `protobuf:"varint,4,opt,name=max_depth,json=maxDepth,proto3,oneof" json:"max_depth,omitempty"`
+	HideUser bool   `protobuf:"varint,5,opt,name=hide_user,json=hideUser,proto3" json:"hide_user,omitempty"`
+}
+
+func (x *ActiveRequest) Reset() {
+	*x = ActiveRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_unlurker_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ActiveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActiveRequest) ProtoMessage() {}
+
+func (x *ActiveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_unlurker_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActiveRequest.ProtoReflect.Descriptor instead.
+func (*ActiveRequest) Descriptor() ([]byte, []int) {
+	return file_unlurker_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ActiveRequest) GetWindow() string {
+	if x != nil {
+		return x.Window
+	}
+	return ""
+}
+
+func (x *ActiveRequest) GetMaxAge() string {
+	if x != nil {
+		return x.MaxAge
+	}
+	return ""
+}
+
+func (x *ActiveRequest) GetMinBy() int32 {
+	if x != nil && x.MinBy != nil {
+		return *x.MinBy
+	}
+	return 0
+}
+
+func (x *ActiveRequest) GetMaxDepth() int32 {
+	if x != nil && x.MaxDepth != nil {
+		return *x.MaxDepth
+	}
+	return 0
+}
+
+func (x *ActiveRequest) GetHideUser() bool {
+	if x != nil {
+		return x.HideUser
+	}
+	return false
+}
+
+type ActiveResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Items              []*Item     `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	SecondChanceFailed bool        `protobuf:"varint,2,opt,name=second_chance_failed,json=secondChanceFailed,proto3" json:"second_chance_failed,omitempty"`
+	Truncation         *Truncation `protobuf:"bytes,3,opt,name=truncation,proto3" json:"truncation,omitempty"`
+}
+
+func (x *ActiveResponse) Reset() {
+	*x = ActiveResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_unlurker_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ActiveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActiveResponse) ProtoMessage() {}
+
+func (x *ActiveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_unlurker_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActiveResponse.ProtoReflect.Descriptor instead.
+func (*ActiveResponse) Descriptor() ([]byte, []int) {
+	return file_unlurker_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ActiveResponse) GetItems() []*Item {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *ActiveResponse) GetSecondChanceFailed() bool {
+	if x != nil {
+		return x.SecondChanceFailed
+	}
+	return false
+}
+
+func (x *ActiveResponse) GetTruncation() *Truncation {
+	if x != nil {
+		return x.Truncation
+	}
+	return nil
+}
+
+type Truncation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Truncated     bool  `protobuf:"varint,1,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	TotalItems    int32 `protobuf:"varint,2,opt,name=total_items,json=totalItems,proto3" json:"total_items,omitempty"`
+	ReturnedItems int32 `protobuf:"varint,3,opt,name=returned_items,json=returnedItems,proto3" json:"returned_items,omitempty"`
+	OmittedItems  int32 `protobuf:"varint,4,opt,name=omitted_items,json=omittedItems,proto3" json:"omitted_items,omitempty"`
+}
+
+func (x *Truncation) Reset() {
+	*x = Truncation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_unlurker_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Truncation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Truncation) ProtoMessage() {}
+
+func (x *Truncation) ProtoReflect() protoreflect.Message {
+	mi := &file_unlurker_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Truncation.ProtoReflect.Descriptor instead.
+func (*Truncation) Descriptor() ([]byte, []int) {
+	return file_unlurker_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Truncation) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+func (x *Truncation) GetTotalItems() int32 {
+	if x != nil {
+		return x.TotalItems
+	}
+	return 0
+}
+
+func (x *Truncation) GetReturnedItems() int32 {
+	if x != nil {
+		return x.ReturnedItems
+	}
+	return 0
+}
+
+func (x *Truncation) GetOmittedItems() int32 {
+	if x != nil {
+		return x.OmittedItems
+	}
+	return 0
+}
+
+type ItemTreeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id       int64  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	MaxDepth *int32 `protobuf:"varint,2,opt,name=max_depth,json=maxDepth,proto3,oneof" json:"max_depth,omitempty"`
+	HideUser bool   `protobuf:"varint,3,opt,name=hide_user,json=hideUser,proto3" json:"hide_user,omitempty"`
+}
+
+func (x *ItemTreeRequest) Reset() {
+	*x = ItemTreeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_unlurker_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ItemTreeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ItemTreeRequest) ProtoMessage() {}
+
+func (x *ItemTreeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_unlurker_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ItemTreeRequest.ProtoReflect.Descriptor instead.
+func (*ItemTreeRequest) Descriptor() ([]byte, []int) {
+	return file_unlurker_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ItemTreeRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ItemTreeRequest) GetMaxDepth() int32 {
+	if x != nil && x.MaxDepth != nil {
+		return *x.MaxDepth
+	}
+	return 0
+}
+
+func (x *ItemTreeRequest) GetHideUser() bool {
+	if x != nil {
+		return x.HideUser
+	}
+	return false
+}
+
+type AncestorsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id       int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	HideUser bool  `protobuf:"varint,2,opt,name=hide_user,json=hideUser,proto3" json:"hide_user,omitempty"`
+}
+
+func (x *AncestorsRequest) Reset() {
+	*x = AncestorsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_unlurker_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AncestorsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AncestorsRequest) ProtoMessage() {}
+
+func (x *AncestorsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_unlurker_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AncestorsRequest.ProtoReflect.Descriptor instead.
+func (*AncestorsRequest) Descriptor() ([]byte, []int) {
+	return file_unlurker_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *AncestorsRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *AncestorsRequest) GetHideUser() bool {
+	if x != nil {
+		return x.HideUser
+	}
+	return false
+}
+
+type AncestorsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Items []*Item `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+}
+
+func (x *AncestorsResponse) Reset() {
+	*x = AncestorsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_unlurker_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AncestorsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AncestorsResponse) ProtoMessage() {}
+
+func (x *AncestorsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_unlurker_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AncestorsResponse.ProtoReflect.Descriptor instead.
+func (*AncestorsResponse) Descriptor() ([]byte, []int) {
+	return file_unlurker_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AncestorsResponse) GetItems() []*Item {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type Item struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id                int64  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	By                string `protobuf:"bytes,2,opt,name=by,proto3" json:"by,omitempty"`
+	Text              string `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	Time              int64  `protobuf:"varint,4,opt,name=time,proto3" json:"time,omitempty"`
+	Depth             int32  `protobuf:"varint,5,opt,name=depth,proto3" json:"depth,omitempty"`
+	TruncatedChildren int32  `protobuf:"varint,6,opt,name=truncated_children,json=truncatedChildren,proto3" json:"truncated_children,omitempty"`
+	Story             *Story `protobuf:"bytes,7,opt,name=story,proto3" json:"story,omitempty"`
+	Active            bool   `protobuf:"varint,8,opt,name=active,proto3" json:"active,omitempty"`
+	SecondChance      bool   `protobuf:"varint,9,opt,name=second_chance,json=secondChance,proto3" json:"second_chance,omitempty"`
+}
+
+func (x *Item) Reset() {
+	*x = Item{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_unlurker_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Item) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Item) ProtoMessage() {}
+
+func (x *Item) ProtoReflect() protoreflect.Message {
+	mi := &file_unlurker_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Item.ProtoReflect.Descriptor instead.
+func (*Item) Descriptor() ([]byte, []int) {
+	return file_unlurker_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Item) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Item) GetBy() string {
+	if x != nil {
+		return x.By
+	}
+	return ""
+}
+
+func (x *Item) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Item) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Item) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *Item) GetTruncatedChildren() int32 {
+	if x != nil {
+		return x.TruncatedChildren
+	}
+	return 0
+}
+
+func (x *Item) GetStory() *Story {
+	if x != nil {
+		return x.Story
+	}
+	return nil
+}
+
+func (x *Item) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *Item) GetSecondChance() bool {
+	if x != nil {
+		return x.SecondChance
+	}
+	return false
+}
+
+type Story struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type        string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Url         string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	Domain      string `protobuf:"bytes,3,opt,name=domain,proto3" json:"domain,omitempty"`
+	Score       int32  `protobuf:"varint,4,opt,name=score,proto3" json:"score,omitempty"`
+	Descendants int32  `protobuf:"varint,5,opt,name=descendants,proto3" json:"descendants,omitempty"`
+}
+
+func (x *Story) Reset() {
+	*x = Story{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_unlurker_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Story) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Story) ProtoMessage() {}
+
+func (x *Story) ProtoReflect() protoreflect.Message {
+	mi := &file_unlurker_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Story.ProtoReflect.Descriptor instead.
+func (*Story) Descriptor() ([]byte, []int) {
+	return file_unlurker_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Story) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Story) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Story) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *Story) GetScore() int32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Story) GetDescendants() int32 {
+	if x != nil {
+		return x.Descendants
+	}
+	return 0
+}
+
+var File_unlurker_proto protoreflect.FileDescriptor
+
+var file_unlurker_proto_rawDesc = []byte{
+	0x0a, 0x0e, 0x75, 0x6e, 0x6c, 0x75, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x0b, 0x75, 0x6e, 0x6c, 0x75, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x22, 0xb4, 0x01,
+	0x0a, 0x0d, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x16, 0x0a, 0x06, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x61, 0x78, 0x5f, 0x61,
+	0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d, 0x61, 0x78, 0x41, 0x67, 0x65,
+	0x12, 0x1a, 0x0a, 0x06, 0x6d, 0x69, 0x6e, 0x5f, 0x62, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
+	0x48, 0x00, 0x52, 0x05, 0x6d, 0x69, 0x6e, 0x42, 0x79, 0x88, 0x01, 0x01, 0x12, 0x20, 0x0a, 0x09,
+	0x6d, 0x61, 0x78, 0x5f, 0x64, 0x65, 0x70, 0x74, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x48,
+	0x01, 0x52, 0x08, 0x6d, 0x61, 0x78, 0x44, 0x65, 0x70, 0x74, 0x68, 0x88, 0x01, 0x01, 0x12, 0x1b,
+	0x0a, 0x09, 0x68, 0x69, 0x64, 0x65, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x08, 0x68, 0x69, 0x64, 0x65, 0x55, 0x73, 0x65, 0x72, 0x42, 0x09, 0x0a, 0x07, 0x5f,
+	0x6d, 0x69, 0x6e, 0x5f, 0x62, 0x79, 0x42, 0x0c, 0x0a, 0x0a, 0x5f, 0x6d, 0x61, 0x78, 0x5f, 0x64,
+	0x65, 0x70, 0x74, 0x68, 0x22, 0xa4, 0x01, 0x0a, 0x0e, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x27, 0x0a, 0x05, 0x69, 0x74, 0x65, 0x6d, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x75, 0x6e, 0x6c, 0x75, 0x72, 0x6b, 0x65,
+	0x72, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x74, 0x65, 0x6d, 0x52, 0x05, 0x69, 0x74, 0x65, 0x6d, 0x73,
+	0x12, 0x30, 0x0a, 0x14, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x63,
+	0x65, 0x5f, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x12,
+	0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x43, 0x68, 0x61, 0x6e, 0x63, 0x65, 0x46, 0x61, 0x69, 0x6c,
+	0x65, 0x64, 0x12, 0x37, 0x0a, 0x0a, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x75, 0x6e, 0x6c, 0x75, 0x72, 0x6b, 0x65,
+	0x72, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x0a, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x97, 0x01, 0x0a, 0x0a,
+	0x54, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x72,
+	0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x74,
+	0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x6f, 0x74, 0x61,
+	0x6c, 0x5f, 0x69, 0x74, 0x65, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x74,
+	0x6f, 0x74, 0x61, 0x6c, 0x49, 0x74, 0x65, 0x6d, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x74,
+	0x75, 0x72, 0x6e, 0x65, 0x64, 0x5f, 0x69, 0x74, 0x65, 0x6d, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x0d, 0x72, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x65, 0x64, 0x49, 0x74, 0x65, 0x6d, 0x73,
+	0x12, 0x23, 0x0a, 0x0d, 0x6f, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x5f, 0x69, 0x74, 0x65, 0x6d,
+	0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x6f, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64,
+	0x49, 0x74, 0x65, 0x6d, 0x73, 0x22, 0x6e, 0x0a, 0x0f, 0x49, 0x74, 0x65, 0x6d, 0x54, 0x72, 0x65,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x12, 0x20, 0x0a, 0x09, 0x6d, 0x61, 0x78, 0x5f,
+	0x64, 0x65, 0x70, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x08, 0x6d,
+	0x61, 0x78, 0x44, 0x65, 0x70, 0x74, 0x68, 0x88, 0x01, 0x01, 0x12, 0x1b, 0x0a, 0x09, 0x68, 0x69,
+	0x64, 0x65, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x68,
+	0x69, 0x64, 0x65, 0x55, 0x73, 0x65, 0x72, 0x42, 0x0c, 0x0a, 0x0a, 0x5f, 0x6d, 0x61, 0x78, 0x5f,
+	0x64, 0x65, 0x70, 0x74, 0x68, 0x22, 0x3f, 0x0a, 0x10, 0x41, 0x6e, 0x63, 0x65, 0x73, 0x74, 0x6f,
+	0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x68, 0x69, 0x64,
+	0x65, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x68, 0x69,
+	0x64, 0x65, 0x55, 0x73, 0x65, 0x72, 0x22, 0x3c, 0x0a, 0x11, 0x41, 0x6e, 0x63, 0x65, 0x73, 0x74,
+	0x6f, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x27, 0x0a, 0x05, 0x69,
+	0x74, 0x65, 0x6d, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x75, 0x6e, 0x6c,
+	0x75, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x74, 0x65, 0x6d, 0x52, 0x05, 0x69,
+	0x74, 0x65, 0x6d, 0x73, 0x22, 0xfa, 0x01, 0x0a, 0x04, 0x49, 0x74, 0x65, 0x6d, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x12, 0x0e, 0x0a,
+	0x02, 0x62, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x62, 0x79, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x64, 0x65, 0x70, 0x74, 0x68, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x64, 0x65, 0x70, 0x74, 0x68, 0x12, 0x2d, 0x0a, 0x12, 0x74,
+	0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x72, 0x65,
+	0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x11, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74,
+	0x65, 0x64, 0x43, 0x68, 0x69, 0x6c, 0x64, 0x72, 0x65, 0x6e, 0x12, 0x28, 0x0a, 0x05, 0x73, 0x74,
+	0x6f, 0x72, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x75, 0x6e, 0x6c, 0x75,
+	0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x05, 0x73,
+	0x74, 0x6f, 0x72, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x12, 0x23, 0x0a, 0x0d,
+	0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0c, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x43, 0x68, 0x61, 0x6e, 0x63,
+	0x65, 0x22, 0x7d, 0x0a, 0x05, 0x53, 0x74, 0x6f, 0x72, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79,
+	0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x10,
+	0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c,
+	0x12, 0x16, 0x0a, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x63, 0x6f, 0x72,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x12, 0x20,
+	0x0a, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x65, 0x6e, 0x64, 0x61, 0x6e, 0x74, 0x73, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x65, 0x6e, 0x64, 0x61, 0x6e, 0x74, 0x73,
+	0x32, 0xd8, 0x01, 0x0a, 0x08, 0x55, 0x6e, 0x6c, 0x75, 0x72, 0x6b, 0x65, 0x72, 0x12, 0x41, 0x0a,
+	0x06, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x12, 0x1a, 0x2e, 0x75, 0x6e, 0x6c, 0x75, 0x72, 0x6b,
+	0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x75, 0x6e, 0x6c, 0x75, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76,
+	0x31, 0x2e, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x3d, 0x0a, 0x08, 0x49, 0x74, 0x65, 0x6d, 0x54, 0x72, 0x65, 0x65, 0x12, 0x1c, 0x2e, 0x75,
+	0x6e, 0x6c, 0x75, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x74, 0x65, 0x6d, 0x54,
+	0x72, 0x65, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x75, 0x6e, 0x6c,
+	0x75, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x74, 0x65, 0x6d, 0x30, 0x01, 0x12,
+	0x4a, 0x0a, 0x09, 0x41, 0x6e, 0x63, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x1d, 0x2e, 0x75,
+	0x6e, 0x6c, 0x75, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6e, 0x63, 0x65, 0x73,
+	0x74, 0x6f, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x75, 0x6e,
+	0x6c, 0x75, 0x72, 0x6b, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6e, 0x63, 0x65, 0x73, 0x74,
+	0x6f, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x3b, 0x5a, 0x39, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6a, 0x61, 0x73, 0x6f, 0x6e, 0x74,
+	0x68, 0x6f, 0x72, 0x73, 0x6e, 0x65, 0x73, 0x73, 0x2f, 0x75, 0x6e, 0x6c, 0x75, 0x72, 0x6b, 0x65,
+	0x72, 0x2d, 0x77, 0x65, 0x62, 0x2f, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2f, 0x75, 0x6e,
+	0x6c, 0x75, 0x72, 0x6b, 0x65, 0x72, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_unlurker_proto_rawDescOnce sync.Once
+	file_unlurker_proto_rawDescData = file_unlurker_proto_rawDesc
+)
+
+func file_unlurker_proto_rawDescGZIP() []byte {
+	file_unlurker_proto_rawDescOnce.Do(func() {
+		file_unlurker_proto_rawDescData = protoimpl.X.CompressGZIP(file_unlurker_proto_rawDescData)
+	})
+	return file_unlurker_proto_rawDescData
+}
+
+var file_unlurker_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_unlurker_proto_goTypes = []interface{}{
+	(*ActiveRequest)(nil),     // 0: unlurker.v1.ActiveRequest
+	(*ActiveResponse)(nil),    // 1: unlurker.v1.ActiveResponse
+	(*Truncation)(nil),        // 2: unlurker.v1.Truncation
+	(*ItemTreeRequest)(nil),   // 3: unlurker.v1.ItemTreeRequest
+	(*AncestorsRequest)(nil),  // 4: unlurker.v1.AncestorsRequest
+	(*AncestorsResponse)(nil), // 5: unlurker.v1.AncestorsResponse
+	(*Item)(nil),              // 6: unlurker.v1.Item
+	(*Story)(nil),             // 7: unlurker.v1.Story
+}
+var file_unlurker_proto_depIdxs = []int32{
+	6, // 0: unlurker.v1.ActiveResponse.items:type_name -> unlurker.v1.Item
+	2, // 1: unlurker.v1.ActiveResponse.truncation:type_name -> unlurker.v1.Truncation
+	6, // 2: unlurker.v1.AncestorsResponse.items:type_name -> unlurker.v1.Item
+	7, // 3: unlurker.v1.Item.story:type_name -> unlurker.v1.Story
+	0, // 4: unlurker.v1.Unlurker.Active:input_type -> unlurker.v1.ActiveRequest
+	3, // 5: unlurker.v1.Unlurker.ItemTree:input_type -> unlurker.v1.ItemTreeRequest
+	4, // 6: unlurker.v1.Unlurker.Ancestors:input_type -> unlurker.v1.AncestorsRequest
+	1, // 7: unlurker.v1.Unlurker.Active:output_type -> unlurker.v1.ActiveResponse
+	6, // 8: unlurker.v1.Unlurker.ItemTree:output_type -> unlurker.v1.Item
+	5, // 9: unlurker.v1.Unlurker.Ancestors:output_type -> unlurker.v1.AncestorsResponse
+	7, // [7:10] is the sub-list for method output_type
+	4, // [4:7] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_unlurker_proto_init() }
+func file_unlurker_proto_init() {
+	if File_unlurker_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_unlurker_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ActiveRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_unlurker_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ActiveResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_unlurker_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Truncation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_unlurker_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ItemTreeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_unlurker_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AncestorsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_unlurker_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AncestorsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_unlurker_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Item); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_unlurker_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Story); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_unlurker_proto_msgTypes[0].OneofWrappers = []interface{}{}
+	file_unlurker_proto_msgTypes[3].OneofWrappers = []interface{}{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_unlurker_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_unlurker_proto_goTypes,
+		DependencyIndexes: file_unlurker_proto_depIdxs,
+		MessageInfos:      file_unlurker_proto_msgTypes,
+	}.Build()
+	File_unlurker_proto = out.File
+	file_unlurker_proto_rawDesc = nil
+	file_unlurker_proto_goTypes = nil
+	file_unlurker_proto_depIdxs = nil
+}
@@ -0,0 +1,201 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.27.1
+// source: unlurker.proto
+
+package unlurkerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Unlurker_Active_FullMethodName    = "/unlurker.v1.Unlurker/Active"
+	Unlurker_ItemTree_FullMethodName  = "/unlurker.v1.Unlurker/ItemTree"
+	Unlurker_Ancestors_FullMethodName = "/unlurker.v1.Unlurker/Ancestors"
+)
+
+// UnlurkerClient is the client API for Unlurker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UnlurkerClient interface {
+	Active(ctx context.Context, in *ActiveRequest, opts ...grpc.CallOption) (*ActiveResponse, error)
+	ItemTree(ctx context.Context, in *ItemTreeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Item], error)
+	Ancestors(ctx context.Context, in *AncestorsRequest, opts ...grpc.CallOption) (*AncestorsResponse, error)
+}
+
+type unlurkerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUnlurkerClient(cc grpc.ClientConnInterface) UnlurkerClient {
+	return &unlurkerClient{cc}
+}
+
+func (c *unlurkerClient) Active(ctx context.Context, in *ActiveRequest, opts ...grpc.CallOption) (*ActiveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ActiveResponse)
+	err := c.cc.Invoke(ctx, Unlurker_Active_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *unlurkerClient) ItemTree(ctx context.Context, in *ItemTreeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Item], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Unlurker_ServiceDesc.Streams[0], Unlurker_ItemTree_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ItemTreeRequest, Item]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Unlurker_ItemTreeClient = grpc.ServerStreamingClient[Item]
+
+func (c *unlurkerClient) Ancestors(ctx context.Context, in *AncestorsRequest, opts ...grpc.CallOption) (*AncestorsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AncestorsResponse)
+	err := c.cc.Invoke(ctx, Unlurker_Ancestors_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnlurkerServer is the server API for Unlurker service.
+// All implementations must embed UnimplementedUnlurkerServer
+// for forward compatibility.
+type UnlurkerServer interface {
+	Active(context.Context, *ActiveRequest) (*ActiveResponse, error)
+	ItemTree(*ItemTreeRequest, grpc.ServerStreamingServer[Item]) error
+	Ancestors(context.Context, *AncestorsRequest) (*AncestorsResponse, error)
+	mustEmbedUnimplementedUnlurkerServer()
+}
+
+// UnimplementedUnlurkerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedUnlurkerServer struct{}
+
+func (UnimplementedUnlurkerServer) Active(context.Context, *ActiveRequest) (*ActiveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Active not implemented")
+}
+func (UnimplementedUnlurkerServer) ItemTree(*ItemTreeRequest, grpc.ServerStreamingServer[Item]) error {
+	return status.Errorf(codes.Unimplemented, "method ItemTree not implemented")
+}
+func (UnimplementedUnlurkerServer) Ancestors(context.Context, *AncestorsRequest) (*AncestorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ancestors not implemented")
+}
+func (UnimplementedUnlurkerServer) mustEmbedUnimplementedUnlurkerServer() {}
+func (UnimplementedUnlurkerServer) testEmbeddedByValue()                  {}
+
+// UnsafeUnlurkerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UnlurkerServer will
+// result in compilation errors.
+type UnsafeUnlurkerServer interface {
+	mustEmbedUnimplementedUnlurkerServer()
+}
+
+func RegisterUnlurkerServer(s grpc.ServiceRegistrar, srv UnlurkerServer) {
+	// If the following call pancis, it indicates UnimplementedUnlurkerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Unlurker_ServiceDesc, srv)
+}
+
+func _Unlurker_Active_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ActiveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UnlurkerServer).Active(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Unlurker_Active_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UnlurkerServer).Active(ctx, req.(*ActiveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Unlurker_ItemTree_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ItemTreeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UnlurkerServer).ItemTree(m, &grpc.GenericServerStream[ItemTreeRequest, Item]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Unlurker_ItemTreeServer = grpc.ServerStreamingServer[Item]
+
+func _Unlurker_Ancestors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AncestorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UnlurkerServer).Ancestors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Unlurker_Ancestors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UnlurkerServer).Ancestors(ctx, req.(*AncestorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Unlurker_ServiceDesc is the grpc.ServiceDesc for Unlurker service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Unlurker_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "unlurker.v1.Unlurker",
+	HandlerType: (*UnlurkerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Active",
+			Handler:    _Unlurker_Active_Handler,
+		},
+		{
+			MethodName: "Ancestors",
+			Handler:    _Unlurker_Ancestors_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ItemTree",
+			Handler:       _Unlurker_ItemTree_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "unlurker.proto",
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// updatedItemsKey is the key of the event broker the changed items of each poll of the HN updates
+// list are published on. The sets are shared by every listener and must not be modified.
+const updatedItemsKey = "items"
+
+type hnUpdates struct {
+	Items    []int    `json:"items"`
+	Profiles []string `json:"profiles"`
+}
+
+// updatesFollower polls the list of recently changed items and profiles of the HN API so changes
+// reach clients before the cached copies expire.
+type updatesFollower struct {
+	source     *activeSource
+	client     *itemClient
+	httpClient *http.Client
+	responses  *lruCache[string, cachedResponse]
+	updates    *eventBroker[map[int]bool]
+	items      map[int]bool
+	profiles   map[string]bool
+	cfg        config
+}
+
+// followUpdates polls the HN updates list every update interval until ctx is done. The changed
+// items are marked for the item client to fetch again and published to updates for the live
+// connections, the cached responses about the changed items and users are dropped, and the
+// precomputed /active snapshot is refreshed early if any of its items changed.
+func followUpdates(
+	ctx context.Context,
+	cfg config,
+	client *itemClient,
+	httpClient *http.Client,
+	source *activeSource,
+	responses *lruCache[string, cachedResponse],
+	updates *eventBroker[map[int]bool],
+) {
+	if cfg.UpdatesInterval <= 0 {
+		return
+	}
+
+	u := &updatesFollower{
+		source:     source,
+		client:     client,
+		httpClient: httpClient,
+		responses:  responses,
+		updates:    updates,
+		items:      nil,
+		profiles:   nil,
+		cfg:        cfg,
+	}
+
+	ticker := time.NewTicker(cfg.UpdatesInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		u.poll(ctx)
+	}
+}
+
+func (u *updatesFollower) poll(ctx context.Context) {
+	var updates hnUpdates
+
+	err := fetchHNJSON(ctx, u.httpClient, "updates.json", &updates)
+	if err != nil {
+		log.Printf("failed to poll HN updates: %v", err)
+		return
+	}
+
+	// the list holds what changed recently, so most of it was already there at the last poll
+	items, changedItems := newlyListed(updates.Items, u.items)
+	profiles, changedProfiles := newlyListed(updates.Profiles, u.profiles)
+	u.items, u.profiles = items, profiles
+
+	if len(changedItems) == 0 && len(changedProfiles) == 0 {
+		return
+	}
+
+	u.client.markChanged(changedItems)
+
+	if len(changedItems) > 0 {
+		u.updates.publish(updatedItemsKey, changedItems)
+	}
+
+	dropped := u.responses.DeleteFunc(func(key string) bool {
+		id, name := responseSubject(key)
+		return changedItems[id] || changedProfiles[name]
+	})
+
+	refreshed := u.refreshActive(changedItems)
+
+	slog.Debug("applied HN updates", "items", len(changedItems), "profiles", len(changedProfiles),
+		"dropped", dropped, "refreshed", refreshed)
+}
+
+// newlyListed returns the set of the listed values and the set of those not in previous.
+func newlyListed[T comparable](listed []T, previous map[T]bool) (map[T]bool, map[T]bool) {
+	all := make(map[T]bool, len(listed))
+	added := make(map[T]bool)
+
+	for _, v := range listed {
+		all[v] = true
+
+		if !previous[v] {
+			added[v] = true
+		}
+	}
+
+	return all, added
+}
+
+// refreshActive refreshes the precomputed snapshot if any of its items changed and it is at least
+// an update interval old, so a busy thread does not keep it recomputing, and reports whether it
+// did.
+func (u *updatesFollower) refreshActive(changed map[int]bool) bool {
+	snapshot := u.source.Current()
+	if snapshot == nil || time.Since(snapshot.Time) < u.cfg.UpdatesInterval {
+		return false
+	}
+
+	for _, root := range snapshot.Roots {
+		if changed[root.Item.ID] {
+			u.source.Refresh()
+			return true
+		}
+	}
+
+	for _, children := range snapshot.Tree {
+		for id := range children {
+			if changed[id] {
+				u.source.Refresh()
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// responseSubject returns the item ID of a cached /item/:id response or the user name of a cached
+// /user/:name response, or zero values for other responses.
+func responseSubject(key string) (int, string) {
+	path, _, _ := strings.Cut(key, "?")
+
+	if rest, ok := strings.CutPrefix(path, "/item/"); ok {
+		param, _, _ := strings.Cut(rest, "/")
+		id, _ := strconv.Atoi(param)
+
+		return id, ""
+	}
+
+	if rest, ok := strings.CutPrefix(path, "/user/"); ok {
+		name, err := url.PathUnescape(rest)
+		if err == nil {
+			return 0, name
+		}
+	}
+
+	return 0, ""
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultUserLimit = 30
+	maxUserLimit     = 100
+)
+
+type handleUserResponse struct {
+	ID      string                          `json:"id"`
+	About   string                          `json:"about,omitempty"`
+	Items   []handleItemDescendantsResponse `json:"items"`
+	Created int64                           `json:"created"`
+	Karma   int                             `json:"karma"`
+}
+
+//nolint:cyclop // need parsing helper
+func handleUser(c *gin.Context, client *itemClient, httpClient *http.Client, formatter *textFormatter) {
+	ctx := c.Request.Context()
+
+	name := c.Param("name")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultUserLimit)))
+	if err != nil || limit < 1 || limit > maxUserLimit {
+		respondParamError(c, codeInvalidLimit, "limit", "invalid limit")
+		return
+	}
+
+	user, err := fetchUser(ctx, httpClient, name)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeHNUpstreamError, "failed to retrieve user")
+		return
+	}
+
+	if user == nil {
+		respondError(c, http.StatusNotFound, codeUserNotFound, "user not found")
+		return
+	}
+
+	// submitted is ordered newest first and includes both stories and comments
+	ids := user.Submitted[:min(limit, len(user.Submitted))]
+
+	submitted, err := fetchItems(ctx, client, ids)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeHNUpstreamError, "failed to retrieve items")
+		return
+	}
+
+	items := make([]handleItemDescendantsResponse, 0, len(ids))
+
+	for _, id := range ids {
+		item, ok := submitted[id]
+		if !ok || item == nil || item.Deleted || item.Dead {
+			continue
+		}
+
+		var story *storyMetadata
+		if item.Type != "comment" {
+			story = newStoryMetadata(item)
+		}
+
+		items = append(items, handleItemDescendantsResponse{
+			storyMetadata:     story,
+			By:                item.By,
+			Text:              formatter.format(item),
+			Children:          nil,
+			AccountAgeDays:    nil,
+			Karma:             nil,
+			Time:              item.Time,
+			ID:                item.ID,
+			Parent:            item.Parent,
+			Root:              0,
+			Depth:             0,
+			TruncatedChildren: 0,
+			OP:                false,
+			Dead:              item.Dead,
+			Deleted:           item.Deleted,
+			IsNew:             false,
+			NewUser:           false,
+		})
+	}
+
+	respond(c, http.StatusOK, handleUserResponse{
+		ID:      user.ID,
+		About:   user.About,
+		Items:   items,
+		Created: user.Created,
+		Karma:   user.Karma,
+	})
+}
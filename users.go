@@ -0,0 +1,82 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+type activeUserComment struct {
+	URL   string `json:"url"`
+	ID    int    `json:"id"`
+	Story int    `json:"story"`
+	Time  int64  `json:"time"`
+}
+
+type activeUser struct {
+	By       string              `json:"by"`
+	Items    []activeUserComment `json:"items"`
+	Comments int                 `json:"comments"`
+}
+
+type handleActiveUsersResponse struct {
+	Users              []activeUser `json:"users"`
+	SecondChanceFailed bool         `json:"secondChanceFailed"`
+}
+
+// handleActiveUsers responds with the users who commented within the window on any of the active
+// roots, ranked by how many comments they made, with links to those comments newest first.
+func handleActiveUsers(c *gin.Context, source *activeSource, defaultBlock []string) {
+	snapshot, opts, ok := getActiveSnapshot(c, source, nil, defaultBlock, nil)
+	if !ok {
+		return
+	}
+
+	respond(c, http.StatusOK, handleActiveUsersResponse{
+		Users:              activeUsers(snapshot, snapshot.now().Add(-snapshot.Params.Window), opts.Blocked),
+		SecondChanceFailed: snapshot.SecondChanceFailed,
+	})
+}
+
+// activeUsers groups the live comments after activeAfter under the snapshot roots by author,
+// skipping blocked authors, and orders the users by comment count and then by name.
+func activeUsers(snapshot *activeSnapshot, activeAfter time.Time, blocked map[string]bool) []activeUser {
+	byUser := make(map[string][]activeUserComment)
+
+	for _, root := range snapshot.Roots {
+		for _, item := range unl.FlattenTree(root.Item, snapshot.Tree) {
+			if item.Depth == 0 || item.Dead || item.Deleted || item.By == "" || blocked[item.By] {
+				continue
+			}
+
+			if item.Time <= activeAfter.Unix() {
+				continue
+			}
+
+			byUser[item.By] = append(byUser[item.By], activeUserComment{
+				URL:   hnItemURL + strconv.Itoa(item.ID),
+				ID:    item.ID,
+				Story: root.Item.ID,
+				Time:  item.Time,
+			})
+		}
+	}
+
+	users := make([]activeUser, 0, len(byUser))
+
+	for by, comments := range byUser {
+		slices.SortFunc(comments, func(a, b activeUserComment) int { return cmp.Compare(b.Time, a.Time) })
+		users = append(users, activeUser{By: by, Items: comments, Comments: len(comments)})
+	}
+
+	slices.SortFunc(users, func(a, b activeUser) int {
+		return cmp.Or(cmp.Compare(b.Comments, a.Comments), cmp.Compare(a.By, b.By))
+	})
+
+	return users
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build information, set with -ldflags "-X main.version=..." by the Makefile.
+//
+//nolint:gochecknoglobals // set by the linker
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+type handleVersionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// buildInfo returns the version the binary was built with. When the linker did not set the commit
+// or build date, they come from the VCS information Go embeds when building from a checkout.
+func buildInfo() handleVersionResponse {
+	info := handleVersionResponse{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, setting := range bi.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && info.Commit == "":
+			info.Commit = setting.Value
+		case setting.Key == "vcs.time" && info.BuildDate == "":
+			info.BuildDate = setting.Value
+		}
+	}
+
+	return info
+}
+
+// addVersionHeader sets X-Unlurker-Version on every response so client reports can be matched
+// to the deployed build.
+func addVersionHeader(info handleVersionResponse) gin.HandlerFunc {
+	value := info.Version
+	if info.Commit != "" {
+		value += " (" + info.Commit + ")"
+	}
+
+	return func(c *gin.Context) {
+		c.Header("X-Unlurker-Version", value)
+		c.Next()
+	}
+}
+
+// handleVersion responds with the version, commit, and build date of the server.
+func handleVersion(info handleVersionResponse) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		respond(c, http.StatusOK, info)
+	}
+}
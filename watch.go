@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// activePollInterval is how often the precomputed /active snapshot is checked for threads that
+// entered it.
+const activePollInterval = 30 * time.Second
+
+// activeWatcher follows the precomputed /active snapshot to tell which threads entered it. The
+// threads of the first snapshot it sees count as already active so that restarting does not report
+// all of them again.
+type activeWatcher struct {
+	source *activeSource
+	active map[int]bool
+	last   time.Time
+}
+
+// watchActive calls fn with each new precomputed /active snapshot and the roots that entered it
+// since the one before, until ctx is done. Nothing is computed while precomputing is disabled, and
+// stale snapshots are skipped since they hold no news.
+func watchActive(
+	ctx context.Context, source *activeSource, fn func(context.Context, *activeSnapshot, []handleActiveRoot),
+) {
+	w := &activeWatcher{source: source, active: nil, last: time.Time{}}
+
+	ticker := time.NewTicker(activePollInterval)
+	defer ticker.Stop()
+
+	for {
+		snapshot, entered, ok := w.next(ctx)
+		if ok {
+			fn(ctx, snapshot, entered)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// next returns the precomputed snapshot and the roots that entered it, or false if there is no new
+// one.
+func (w *activeWatcher) next(ctx context.Context) (*activeSnapshot, []handleActiveRoot, bool) {
+	if w.source.Interval() <= 0 {
+		return nil, nil, false
+	}
+
+	snapshot, err := w.source.Get(ctx, w.source.Params())
+	if err != nil || snapshot.Stale || !snapshot.Time.After(w.last) {
+		return nil, nil, false
+	}
+
+	previous := w.active
+
+	w.last = snapshot.Time
+	w.active = make(map[int]bool, len(snapshot.Roots))
+
+	var entered []handleActiveRoot
+
+	for _, root := range snapshot.Roots {
+		w.active[root.Item.ID] = true
+
+		if previous != nil && !previous[root.Item.ID] {
+			entered = append(entered, root)
+		}
+	}
+
+	return snapshot, entered, true
+}
@@ -0,0 +1,628 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+)
+
+const (
+	watchKindKeyword = "keyword"
+	watchKindAuthor  = "author"
+	watchKindDomain  = "domain"
+
+	watchScopeTitles   = "titles"
+	watchScopeComments = "comments"
+
+	webhookEventWatchHit = "watch.hit"
+
+	defaultWatchHitsLimit = 100
+	maxWatchHitsLimit     = 1000
+)
+
+// watchRule matches the stories (scope titles) or comments (scope comments) of the default /active
+// set by keyword, author, or story domain. Its hits are kept in the store and, if it has a webhook
+// URL, posted there signed with the secret, which is only returned when the rule is created.
+type watchRule struct {
+	ID         string `json:"id"`
+	Kind       string `json:"kind"`
+	Value      string `json:"value"`
+	Scope      string `json:"scope"`
+	WebhookURL string `json:"webhookUrl,omitempty"`
+	Secret     string `json:"secret,omitempty"`
+	Created    int64  `json:"created"`
+}
+
+// watchHit is an item that matched a watch rule, with when it was first seen matching.
+type watchHit struct {
+	Watch   string `json:"watch"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	By      string `json:"by"`
+	ID      int    `json:"id"`
+	Story   int    `json:"story"`
+	Time    int64  `json:"time"`
+	Matched int64  `json:"matched"`
+}
+
+// saveWatch stores a new watch rule.
+func (s *store) saveWatch(ctx context.Context, rule watchRule) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO watches (id, kind, value, scope, webhook_url, secret, created)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		rule.ID, rule.Kind, rule.Value, rule.Scope, rule.WebhookURL, rule.Secret, rule.Created)
+	if err != nil {
+		return fmt.Errorf("failed to save watch: %w", err)
+	}
+
+	return nil
+}
+
+// updateWatch replaces what a watch rule matches and where its hits are posted, dropping the hits
+// of the old rule, and reports whether the rule existed.
+func (s *store) updateWatch(ctx context.Context, rule watchRule) (bool, error) {
+	return s.inWatchTx(ctx, rule.ID, `UPDATE watches SET kind = ?, value = ?, scope = ?, webhook_url = ? WHERE id = ?`,
+		rule.Kind, rule.Value, rule.Scope, rule.WebhookURL, rule.ID)
+}
+
+// deleteWatch removes a watch rule and its hits, reporting whether the rule existed.
+func (s *store) deleteWatch(ctx context.Context, id string) (bool, error) {
+	return s.inWatchTx(ctx, id, `DELETE FROM watches WHERE id = ?`, id)
+}
+
+// inWatchTx runs a statement on the watch rule with the given ID and drops its hits in one
+// transaction, reporting whether the statement changed the rule.
+func (s *store) inWatchTx(ctx context.Context, id string, query string, args ...any) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin changing watch %s: %w", id, err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to change watch %s: %w", id, err)
+	}
+
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM watch_hits WHERE watch = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to drop hits of watch %s: %w", id, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, fmt.Errorf("failed to commit watch %s: %w", id, err)
+	}
+
+	return true, nil
+}
+
+// watches returns every watch rule, oldest first, including their secrets.
+func (s *store) watches(ctx context.Context) ([]watchRule, error) {
+	return s.queryWatches(ctx, `SELECT id, kind, value, scope, webhook_url, secret, created
+		FROM watches ORDER BY created, id`)
+}
+
+// watch returns the watch rule with the given ID, reporting whether it exists.
+func (s *store) watch(ctx context.Context, id string) (watchRule, bool, error) {
+	var invalid watchRule
+
+	rules, err := s.queryWatches(ctx, `SELECT id, kind, value, scope, webhook_url, secret, created
+		FROM watches WHERE id = ?`, id)
+	if err != nil || len(rules) == 0 {
+		return invalid, false, err
+	}
+
+	return rules[0], true, nil
+}
+
+func (s *store) queryWatches(ctx context.Context, query string, args ...any) ([]watchRule, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watches: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	rules := make([]watchRule, 0)
+
+	for rows.Next() {
+		var rule watchRule
+
+		err = rows.Scan(&rule.ID, &rule.Kind, &rule.Value, &rule.Scope, &rule.WebhookURL, &rule.Secret, &rule.Created)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read watch: %w", err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watches: %w", err)
+	}
+
+	return rules, nil
+}
+
+// recordWatchHits stores the hits not already stored and returns them.
+func (s *store) recordWatchHits(ctx context.Context, hits []watchHit) ([]watchHit, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin recording hits: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	var added []watchHit
+
+	for _, hit := range hits {
+		var result sql.Result
+
+		result, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO watch_hits
+			(watch, id, story, title, by, time, matched) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			hit.Watch, hit.ID, hit.Story, hit.Title, hit.By, hit.Time, hit.Matched)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record hit of watch %s: %w", hit.Watch, err)
+		}
+
+		n, _ := result.RowsAffected()
+		if n > 0 {
+			added = append(added, hit)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit hits: %w", err)
+	}
+
+	return added, nil
+}
+
+// watchHits returns up to limit hits of a watch rule, most recently matched first.
+func (s *store) watchHits(ctx context.Context, id string, limit int) ([]watchHit, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT watch, id, story, title, by, time, matched FROM watch_hits
+		WHERE watch = ? ORDER BY matched DESC, time DESC, id DESC LIMIT ?`, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hits: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	hits := make([]watchHit, 0)
+
+	for rows.Next() {
+		var hit watchHit
+
+		err = rows.Scan(&hit.Watch, &hit.ID, &hit.Story, &hit.Title, &hit.By, &hit.Time, &hit.Matched)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read hit: %w", err)
+		}
+
+		hit.URL = hnItemURL + strconv.Itoa(hit.ID)
+		hits = append(hits, hit)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hits: %w", err)
+	}
+
+	return hits, nil
+}
+
+// watchTarget is an item of the active set being matched against the watch rules. The text rules
+// match is only built when a keyword rule needs it.
+type watchTarget struct {
+	item      *hn.Item
+	text      string
+	root      bool
+	converted bool
+}
+
+// lowerText returns the lowercase title and URL of a story or the plain text of a comment.
+func (t *watchTarget) lowerText() string {
+	if !t.converted {
+		if t.root {
+			t.text = strings.ToLower(t.item.Title + "\n" + t.item.URL)
+		} else {
+			t.text = strings.ToLower(convertText(t.item.Text, textModePlain))
+		}
+
+		t.converted = true
+	}
+
+	return t.text
+}
+
+// matches reports whether the rule matches the target.
+func (rule watchRule) matches(t *watchTarget) bool {
+	if t.root != (rule.Scope == watchScopeTitles) {
+		return false
+	}
+
+	switch rule.Kind {
+	case watchKindAuthor:
+		return strings.EqualFold(t.item.By, rule.Value)
+	case watchKindDomain:
+		return matchesDomain(storyDomain(t.item.URL), []string{rule.Value})
+	case watchKindKeyword:
+		return strings.Contains(t.lowerText(), rule.Value)
+	default:
+		return false
+	}
+}
+
+type watchWebhookPayload struct {
+	Event string   `json:"event"`
+	Watch string   `json:"watch"`
+	Hit   watchHit `json:"hit"`
+	Time  int64    `json:"time"`
+}
+
+type watchEvaluator struct {
+	client *http.Client
+	store  *store
+}
+
+// evaluateWatches matches the watch rules against each new precomputed /active snapshot, storing
+// the hits and posting the new ones to the webhooks of their rules, until ctx is done. It returns
+// immediately if there is no store.
+func evaluateWatches(ctx context.Context, source *activeSource, st *store) {
+	if st == nil {
+		return
+	}
+
+	e := &watchEvaluator{client: &http.Client{Timeout: webhookTimeout}, store: st}
+	watchActive(ctx, source, e.evaluate)
+}
+
+func (e *watchEvaluator) evaluate(ctx context.Context, snapshot *activeSnapshot, _ []handleActiveRoot) {
+	rules, err := e.store.watches(ctx)
+	if err != nil {
+		log.Printf("failed to evaluate watches: %v", err)
+		return
+	}
+
+	if len(rules) == 0 {
+		return
+	}
+
+	added, err := e.store.recordWatchHits(ctx, matchWatches(snapshot, rules))
+	if err != nil {
+		log.Printf("failed to record watch hits: %v", err)
+		return
+	}
+
+	byID := make(map[string]watchRule, len(rules))
+	for _, rule := range rules {
+		byID[rule.ID] = rule
+	}
+
+	for _, hit := range added {
+		rule := byID[hit.Watch]
+		if rule.WebhookURL != "" {
+			e.push(ctx, rule, hit, snapshot.Time)
+		}
+	}
+}
+
+// matchWatches returns a hit for each live item of the snapshot and each rule matching it.
+func matchWatches(snapshot *activeSnapshot, rules []watchRule) []watchHit {
+	var hits []watchHit
+
+	for _, root := range snapshot.Roots {
+		items, depths := flattenRoot(root.Item, snapshot.Tree)
+
+		for i, item := range items {
+			if item.Dead || item.Deleted {
+				continue
+			}
+
+			target := &watchTarget{item: item, text: "", root: depths[i] == 0, converted: false}
+
+			for _, rule := range rules {
+				if rule.matches(target) {
+					hits = append(hits, newWatchHit(rule, root.Item, item, snapshot.Time))
+				}
+			}
+		}
+	}
+
+	return hits
+}
+
+func newWatchHit(rule watchRule, root *hn.Item, item *hn.Item, at time.Time) watchHit {
+	return watchHit{
+		Watch:   rule.ID,
+		Title:   root.Title,
+		URL:     hnItemURL + strconv.Itoa(item.ID),
+		By:      item.By,
+		ID:      item.ID,
+		Story:   root.ID,
+		Time:    item.Time,
+		Matched: at.Unix(),
+	}
+}
+
+// push posts a new hit to the webhook of its rule.
+func (e *watchEvaluator) push(ctx context.Context, rule watchRule, hit watchHit, at time.Time) {
+	body, err := json.Marshal(watchWebhookPayload{Event: webhookEventWatchHit, Watch: rule.ID, Hit: hit, Time: at.Unix()})
+	if err != nil {
+		log.Printf("failed to encode watch webhook: %v", err)
+		return
+	}
+
+	err = postWebhook(ctx, e.client, rule.WebhookURL, rule.Secret, webhookEventWatchHit, body)
+	if err != nil {
+		log.Printf("failed to send webhook of watch %s: %v", rule.ID, err)
+	}
+}
+
+// registerWatches adds the endpoints managing watch rules, which need the admin token since the
+// server posts hits to whatever URL they name. Nothing is added without a token.
+func registerWatches(r *gin.Engine, cfg config, st *store) {
+	if cfg.AdminToken == "" {
+		return
+	}
+
+	watches := r.Group("/watches", requireAdminToken(cfg.AdminToken))
+	watches.POST("", func(c *gin.Context) { handleCreateWatch(c, st) })
+	watches.GET("", func(c *gin.Context) { handleListWatches(c, st) })
+	watches.GET("/:id", func(c *gin.Context) { handleGetWatch(c, st) })
+	watches.PUT("/:id", func(c *gin.Context) { handleUpdateWatch(c, st) })
+	watches.DELETE("/:id", func(c *gin.Context) { handleDeleteWatch(c, st) })
+	watches.GET("/:id/hits", func(c *gin.Context) { handleWatchHits(c, st) })
+}
+
+type watchRequest struct {
+	Kind       string `json:"kind"`
+	Value      string `json:"value"`
+	Scope      string `json:"scope"`
+	WebhookURL string `json:"webhookUrl"`
+}
+
+// parseWatchRequest reads a watch rule from the JSON body, responding with an error and returning
+// false if it is invalid. The scope defaults to titles, and domain rules only apply to titles since
+// comments have no URL.
+//
+//nolint:cyclop // one check per field
+func parseWatchRequest(c *gin.Context) (watchRequest, bool) {
+	var req watchRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, codeInvalidBody, "invalid request body")
+		return req, false
+	}
+
+	if req.Kind != watchKindKeyword && req.Kind != watchKindAuthor && req.Kind != watchKindDomain {
+		respondParamError(c, codeInvalidKind, "kind", "invalid kind")
+		return req, false
+	}
+
+	if req.Scope == "" {
+		req.Scope = watchScopeTitles
+	}
+
+	if (req.Scope != watchScopeTitles && req.Scope != watchScopeComments) ||
+		(req.Kind == watchKindDomain && req.Scope != watchScopeTitles) {
+		respondParamError(c, codeInvalidScope, "scope", "invalid scope")
+		return req, false
+	}
+
+	prefix := ""
+	if req.Kind == watchKindDomain {
+		prefix = "www."
+	}
+
+	values := normalizeTerms([]string{req.Value}, prefix)
+	if len(values) == 0 {
+		respondParamError(c, codeInvalidValue, "value", "missing value")
+		return req, false
+	}
+
+	req.Value = values[0]
+
+	if req.WebhookURL != "" {
+		u, err := url.Parse(req.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			respondParamError(c, codeInvalidURL, "webhookUrl", "invalid webhookUrl")
+			return req, false
+		}
+
+		req.WebhookURL = u.String()
+	}
+
+	return req, true
+}
+
+// handleCreateWatch adds a watch rule from a JSON body.
+func handleCreateWatch(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	req, ok := parseWatchRequest(c)
+	if !ok {
+		return
+	}
+
+	rule := watchRule{
+		ID:         newRequestID(),
+		Kind:       req.Kind,
+		Value:      req.Value,
+		Scope:      req.Scope,
+		WebhookURL: req.WebhookURL,
+		Secret:     newRequestID() + newRequestID(),
+		Created:    time.Now().Unix(),
+	}
+
+	err := st.saveWatch(c.Request.Context(), rule)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to save watch")
+		return
+	}
+
+	respond(c, http.StatusCreated, rule)
+}
+
+type handleListWatchesResponse struct {
+	Watches []watchRule `json:"watches"`
+}
+
+// handleListWatches responds with every watch rule without the secrets.
+func handleListWatches(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	rules, err := st.watches(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read watches")
+		return
+	}
+
+	for i := range rules {
+		rules[i].Secret = ""
+	}
+
+	respond(c, http.StatusOK, handleListWatchesResponse{Watches: rules})
+}
+
+// handleGetWatch responds with one watch rule without its secret.
+func handleGetWatch(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	rule, found, err := st.watch(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read watch")
+		return
+	}
+
+	if !found {
+		respondError(c, http.StatusNotFound, codeWatchNotFound, "watch not found")
+		return
+	}
+
+	rule.Secret = ""
+
+	respond(c, http.StatusOK, rule)
+}
+
+// handleUpdateWatch replaces a watch rule with the one in the JSON body, keeping its ID and secret
+// and dropping the hits of the old rule.
+func handleUpdateWatch(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	req, ok := parseWatchRequest(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	rule, found, err := st.watch(ctx, c.Param("id"))
+	if err == nil && found {
+		rule.Kind, rule.Value, rule.Scope, rule.WebhookURL = req.Kind, req.Value, req.Scope, req.WebhookURL
+		found, err = st.updateWatch(ctx, rule)
+	}
+
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to update watch")
+		return
+	}
+
+	if !found {
+		respondError(c, http.StatusNotFound, codeWatchNotFound, "watch not found")
+		return
+	}
+
+	rule.Secret = ""
+
+	respond(c, http.StatusOK, rule)
+}
+
+// handleDeleteWatch removes a watch rule and its hits.
+func handleDeleteWatch(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	found, err := st.deleteWatch(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to delete watch")
+		return
+	}
+
+	if !found {
+		respondError(c, http.StatusNotFound, codeWatchNotFound, "watch not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type handleWatchHitsResponse struct {
+	Hits []watchHit `json:"hits"`
+}
+
+// handleWatchHits responds with the most recent hits of a watch rule.
+func handleWatchHits(c *gin.Context, st *store) {
+	if st == nil {
+		respondStoreDisabled(c)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultWatchHitsLimit)))
+	if err != nil || limit < 1 || limit > maxWatchHitsLimit {
+		respondParamError(c, codeInvalidLimit, "limit", "invalid limit")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	_, found, err := st.watch(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read watch")
+		return
+	}
+
+	if !found {
+		respondError(c, http.StatusNotFound, codeWatchNotFound, "watch not found")
+		return
+	}
+
+	hits, err := st.watchHits(ctx, c.Param("id"), limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternalError, "failed to read hits")
+		return
+	}
+
+	respond(c, http.StatusOK, handleWatchHitsResponse{Hits: hits})
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	webhookTimeout     = 10 * time.Second
+	webhookAttempts    = 3
+	webhookEventActive = "thread.active"
+)
+
+type webhookThread struct {
+	Title            string  `json:"title"`
+	URL              string  `json:"url,omitempty"`
+	HNURL            string  `json:"hnUrl"`
+	By               string  `json:"by"`
+	ID               int     `json:"id"`
+	Time             int64   `json:"time"`
+	Score            int     `json:"score"`
+	Descendants      int     `json:"descendants"`
+	ActiveComments   int     `json:"activeComments"`
+	ActiveCommenters int     `json:"activeCommenters"`
+	CommentsPerHour  float64 `json:"commentsPerHour"`
+}
+
+type webhookPayload struct {
+	Event        string        `json:"event"`
+	Subscription string        `json:"subscription"`
+	Thread       webhookThread `json:"thread"`
+	Time         int64         `json:"time"`
+}
+
+type webhookDispatcher struct {
+	client *http.Client
+	store  *store
+}
+
+// dispatchWebhooks posts to the matching subscriptions each time a thread enters the precomputed
+// /active snapshot, until ctx is done. It returns immediately if there is no store.
+func dispatchWebhooks(ctx context.Context, source *activeSource, st *store) {
+	if st == nil {
+		return
+	}
+
+	d := &webhookDispatcher{client: &http.Client{Timeout: webhookTimeout}, store: st}
+	watchActive(ctx, source, d.dispatch)
+}
+
+// dispatch sends the webhooks for the threads that entered snapshot.
+func (d *webhookDispatcher) dispatch(ctx context.Context, snapshot *activeSnapshot, entered []handleActiveRoot) {
+	if len(entered) == 0 {
+		return
+	}
+
+	subs, err := d.store.subscriptions(ctx)
+	if err != nil {
+		log.Printf("failed to send webhooks: %v", err)
+		return
+	}
+
+	for _, root := range entered {
+		thread := newWebhookThread(snapshot, root)
+
+		for _, sub := range subs {
+			if sub.matches(thread) {
+				d.deliver(ctx, sub, thread, snapshot.Time)
+			}
+		}
+	}
+}
+
+func newWebhookThread(snapshot *activeSnapshot, root handleActiveRoot) webhookThread {
+	items, depths := flattenRoot(root.Item, snapshot.Tree)
+	metrics := newRootMetrics(items, depths, snapshot.Time.Add(-snapshot.Params.Window), snapshot.Time)
+
+	return webhookThread{
+		Title:            root.Item.Title,
+		URL:              root.Item.URL,
+		HNURL:            hnItemURL + strconv.Itoa(root.Item.ID),
+		By:               root.Item.By,
+		ID:               root.Item.ID,
+		Time:             root.Time,
+		Score:            root.Item.Score,
+		Descendants:      root.Item.Descendants,
+		ActiveComments:   metrics.ActiveComments,
+		ActiveCommenters: metrics.ActiveCommenters,
+		CommentsPerHour:  metrics.CommentsPerHour,
+	}
+}
+
+// matches reports whether thread passes every filter of the subscription.
+func (sub subscription) matches(thread webhookThread) bool {
+	if thread.ActiveComments < sub.MinActiveComments {
+		return false
+	}
+
+	if len(sub.Domains) > 0 && !matchesDomain(storyDomain(thread.URL), sub.Domains) {
+		return false
+	}
+
+	if len(sub.Keywords) == 0 {
+		return true
+	}
+
+	_, ok := matchingKeyword(thread, sub.Keywords)
+
+	return ok
+}
+
+// matchingKeyword returns the first of the lowercase keywords that the title or URL of thread
+// contains, ignoring case.
+func matchingKeyword(thread webhookThread, keywords []string) (string, bool) {
+	s := strings.ToLower(thread.Title + "\n" + thread.URL)
+
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return keyword, true
+		}
+	}
+
+	return "", false
+}
+
+// deliver posts the webhook for thread to the subscription and records the outcome.
+func (d *webhookDispatcher) deliver(ctx context.Context, sub subscription, thread webhookThread, at time.Time) {
+	body, err := json.Marshal(webhookPayload{
+		Event:        webhookEventActive,
+		Subscription: sub.ID,
+		Thread:       thread,
+		Time:         at.Unix(),
+	})
+	if err != nil {
+		log.Printf("failed to encode webhook: %v", err)
+		return
+	}
+
+	err = postWebhook(ctx, d.client, sub.URL, sub.Secret, webhookEventActive, body)
+	if err != nil {
+		log.Printf("failed to send webhook to subscription %s: %v", sub.ID, err)
+	}
+
+	err = d.store.recordDelivery(ctx, sub.ID, time.Now(), err)
+	if err != nil {
+		log.Printf("failed to record webhook delivery: %v", err)
+	}
+}
+
+// postWebhook posts a webhook body to rawURL, retrying failures with backoff.
+func postWebhook(
+	ctx context.Context, client *http.Client, rawURL string, secret string, event string, body []byte,
+) error {
+	var err error
+
+	for attempt := range webhookAttempts {
+		if attempt > 0 {
+			err = sleep(ctx, retryDelay(attempt))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = postWebhookOnce(ctx, client, rawURL, secret, event, body)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// postWebhookOnce sends one webhook, signed with an HMAC-SHA256 of the body keyed by secret in the
+// X-Unlurker-Signature header.
+func postWebhookOnce(
+	ctx context.Context, client *http.Client, rawURL string, secret string, event string, body []byte,
+) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "unlurker-webhooks")
+	req.Header.Set("X-Unlurker-Event", event)
+	req.Header.Set("X-Unlurker-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w posting webhook: %d", errUnexpectedStatus, resp.StatusCode)
+	}
+
+	return nil
+}
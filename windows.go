@@ -0,0 +1,73 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasonthorsness/unlurker/hn"
+	"github.com/jasonthorsness/unlurker/unl"
+)
+
+// maxWindows bounds the windows of one /active request, each of which is another pass over every
+// thread.
+const maxWindows = 5
+
+// activeWindow is one of the windows of a multi-window /active request, named as it was requested.
+type activeWindow struct {
+	Name     string
+	Duration time.Duration
+}
+
+// parseWindows reads the window query parameter, a duration or a comma-separated list of them such
+// as 15m,1h,6h, which defaults to def. The windows are returned shortest first without duplicates,
+// and an error is added to errs for each that is invalid or outside bounds.
+func parseWindows(c *gin.Context, errs *paramErrors, def time.Duration, bounds activeBounds) []activeWindow {
+	names := splitList(c.DefaultQuery("window", def.String()))
+
+	if len(names) == 0 || len(names) > maxWindows {
+		errs.add(codeInvalidWindow, "window", fmt.Sprintf("window must be 1 to %d durations", maxWindows))
+		return nil
+	}
+
+	windows := make([]activeWindow, 0, len(names))
+
+	for _, name := range names {
+		d, ok := parseDurationValue(errs, "window", name, codeInvalidWindow, "invalid window duration")
+		if !ok {
+			continue
+		}
+
+		errs.addRange(bounds.checkWindow(d))
+
+		windows = append(windows, activeWindow{Name: name, Duration: d})
+	}
+
+	slices.SortStableFunc(windows, func(a, b activeWindow) int { return cmp.Compare(a.Duration, b.Duration) })
+
+	return slices.CompactFunc(windows, func(a, b activeWindow) bool { return a.Duration == b.Duration })
+}
+
+// itemWindows returns the names of the windows of opts within which each comment of the flattened
+// thread under root is active, shortest first, or nil if the request has a single window.
+func itemWindows(flat []unl.FlatItem, root *hn.Item, now time.Time, opts activeItemOptions) map[int][]string {
+	if len(opts.Windows) < 2 {
+		return nil
+	}
+
+	windows := make(map[int][]string)
+
+	for _, w := range opts.Windows {
+		isActive := threadActivity(flat, root, w.Duration, now.Add(-w.Duration), opts)
+
+		for _, f := range flat {
+			if isActive(f.Item) {
+				windows[f.ID] = append(windows[f.ID], w.Name)
+			}
+		}
+	}
+
+	return windows
+}